@@ -0,0 +1,420 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"score/src/parser"
+	"score/src/store"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// default and maximum number of matches returned by GET /api/matches
+	DEFAULT_LIMIT = 20
+	MAX_LIMIT     = 100
+
+	STATUS_RUNNING  = "running"
+	STATUS_FINISHED = "finished"
+
+	// date format accepted by the from and to filters besides timestamps
+	DATE_FORMAT = "2006-01-02"
+)
+
+// Game as returned by the API, including derived statistics.
+type APIGame struct {
+	Points   []parser.TeamID   `json:"points"`
+	Rallies  []parser.Rally    `json:"rallies"`
+	Server   *parser.PlayerRef `json:"server,omitempty"`
+	Receiver *parser.PlayerRef `json:"receiver,omitempty"`
+	Winner   parser.TeamID     `json:"winner"`
+	// score after each rally
+	Scores []parser.Score `json:"scores"`
+	// serve of each rally and of the next one, only known if the server of
+	// the game is known
+	Serves    []parser.Serve `json:"serves"`
+	NextServe *parser.Serve  `json:"nextServe,omitempty"`
+	// points at which the interval and the change of ends take place
+	IntervalPoint   int  `json:"intervalPoint"`
+	ChangeEndsPoint int  `json:"changeEndsPoint"`
+	Interval        bool `json:"interval"`
+	AvgRallyGap     int  `json:"avgRallyGap"`
+	parser.Stats
+}
+
+// Match as returned by the API, including derived statistics.
+type APIMatch struct {
+	UUID       string           `json:"uuid"`
+	Modified   int64            `json:"modified"`
+	Info       parser.MatchInfo `json:"info"`
+	Games      []APIGame        `json:"games"`
+	Events     []parser.Event   `json:"events"`
+	Winner     parser.TeamID    `json:"winner"`
+	Finished   bool             `json:"finished"`
+	Interval   bool             `json:"interval"`
+	ChangeEnds bool             `json:"changeEnds"`
+	parser.Stats
+}
+
+type APIMatchList struct {
+	Matches []APIMatch `json:"matches"`
+	// number of matches matching the filters, regardless of pagination
+	Total  int `json:"total"`
+	Limit  int `json:"limit"`
+	Offset int `json:"offset"`
+}
+
+// Revision of a match as returned by the API. The match data is only
+// included if a single revision is requested.
+type APIRevision struct {
+	Match    string          `json:"match"`
+	Revision int             `json:"revision"`
+	Created  int64           `json:"created"`
+	Data     json.RawMessage `json:"data,omitempty"`
+}
+
+// MatchFilter selects matches of GET /api/matches. Zero values match all
+// matches.
+type MatchFilter struct {
+	Status string
+	// matches started in [From, To)
+	From   time.Time
+	To     time.Time
+	Player string
+	Mode   parser.Mode
+
+	// field to sort by, prefixed with "-" for descending order
+	Sort   string
+	Limit  int
+	Offset int
+}
+
+func newAPIMatch(uuid string, modified time.Time, m parser.Match) APIMatch {
+	match := APIMatch{
+		UUID:       uuid,
+		Modified:   modified.Unix(),
+		Info:       m.Info,
+		Games:      []APIGame{},
+		Events:     m.Events,
+		Winner:     m.Winner,
+		Finished:   m.Finished,
+		Interval:   m.Interval,
+		ChangeEnds: m.ChangeEnds,
+		Stats:      m.Stats,
+	}
+
+	if match.Events == nil {
+		match.Events = []parser.Event{}
+	}
+
+	// running matches have no end yet, the parser uses the current time
+	if !m.Finished {
+		match.Info.End = parser.UnixTime{}
+	}
+
+	for _, g := range m.Games {
+		if g.Serves == nil {
+			g.Serves = []parser.Serve{}
+		}
+
+		match.Games = append(match.Games, APIGame{
+			Points:          g.Points,
+			Rallies:         g.Rallies,
+			Server:          g.Server,
+			Receiver:        g.Receiver,
+			Winner:          g.Winner,
+			Scores:          g.Scores,
+			Serves:          g.Serves,
+			NextServe:       g.NextServe,
+			IntervalPoint:   g.IntervalPoint,
+			ChangeEndsPoint: g.ChangeEndsPoint,
+			Interval:        g.GameInterval,
+			AvgRallyGap:     g.AvgRallyGap,
+			Stats:           g.Stats,
+		})
+	}
+
+	return match
+}
+
+func newAPIRevision(revision store.Revision) APIRevision {
+	return APIRevision{
+		Match:    revision.UUID,
+		Revision: revision.Revision,
+		Created:  revision.Created.Unix(),
+	}
+}
+
+// Parses a Unix timestamp or a date, e.g. 2023-03-24.
+func parseTime(value string) (time.Time, error) {
+	if timestamp, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(timestamp, 0), nil
+	}
+
+	return time.ParseInLocation(DATE_FORMAT, value, time.Local)
+}
+
+func parseMatchFilter(query url.Values) (MatchFilter, error) {
+	filter := MatchFilter{
+		Status: query.Get("status"),
+		Player: strings.TrimSpace(query.Get("player")),
+		Sort:   "-" + store.SORT_MODIFIED,
+		Limit:  DEFAULT_LIMIT,
+	}
+
+	if filter.Status != "" && filter.Status != STATUS_RUNNING && filter.Status != STATUS_FINISHED {
+		return filter, errors.New("status must be running or finished")
+	}
+
+	if value := query.Get("from"); value != "" {
+		from, err := parseTime(value)
+		if err != nil {
+			return filter, errors.New("invalid from")
+		}
+
+		filter.From = from
+	}
+
+	if value := query.Get("to"); value != "" {
+		to, err := parseTime(value)
+		if err != nil {
+			return filter, errors.New("invalid to")
+		}
+
+		filter.To = to
+	}
+
+	if value := query.Get("mode"); value != "" {
+		mode, err := strconv.Atoi(value)
+		if err != nil {
+			return filter, errors.New("invalid mode")
+		}
+
+		filter.Mode = parser.Mode(mode)
+	}
+
+	if value := query.Get("sort"); value != "" {
+		switch strings.TrimPrefix(value, "-") {
+		case store.SORT_START, store.SORT_MODIFIED, store.SORT_DURATION:
+			filter.Sort = value
+		default:
+			return filter, errors.New("sort must be start, modified or duration")
+		}
+	}
+
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > MAX_LIMIT {
+			return filter, errors.New("invalid limit")
+		}
+
+		filter.Limit = limit
+	}
+
+	if value := query.Get("offset"); value != "" {
+		offset, err := strconv.Atoi(value)
+		if err != nil || offset < 0 {
+			return filter, errors.New("invalid offset")
+		}
+
+		filter.Offset = offset
+	}
+
+	return filter, nil
+}
+
+func (f MatchFilter) query() store.MatchQuery {
+	query := store.MatchQuery{
+		From:       f.From,
+		To:         f.To,
+		Mode:       int(f.Mode),
+		Player:     f.Player,
+		Sort:       strings.TrimPrefix(f.Sort, "-"),
+		Descending: strings.HasPrefix(f.Sort, "-"),
+		Limit:      f.Limit,
+		Offset:     f.Offset,
+	}
+
+	if f.Status != "" {
+		finished := f.Status == STATUS_FINISHED
+		query.Finished = &finished
+	}
+
+	return query
+}
+
+// Returns the matches selected by the filter. Matches that cannot be parsed
+// are skipped, like on the index page.
+func getMatches(filter MatchFilter) (APIMatchList, error) {
+	list := APIMatchList{
+		Matches: []APIMatch{},
+		Limit:   filter.Limit,
+		Offset:  filter.Offset,
+	}
+
+	records, total, err := matches.Query(filter.query())
+	if err != nil {
+		return list, err
+	}
+
+	list.Total = total
+
+	for _, record := range records {
+		m, err := parser.Parse(record.JSON)
+		if err != nil {
+			continue
+		}
+
+		list.Matches = append(list.Matches, newAPIMatch(record.UUID, record.Modified, m))
+	}
+
+	return list, nil
+}
+
+func getMatch(uuid string) (APIMatch, error) {
+	record, err := matches.Get(uuid)
+	if err != nil {
+		return APIMatch{}, err
+	}
+
+	if len(record.JSON) == 0 {
+		return APIMatch{}, store.ErrNotFound
+	}
+
+	m, err := parser.Parse(record.JSON)
+	if err != nil {
+		return APIMatch{}, err
+	}
+
+	return newAPIMatch(record.UUID, record.Modified, m), nil
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// Handles the read API:
+//
+//	GET /api/matches
+//	GET /api/matches/{uuid}
+//	GET /api/matches/{uuid}/events
+//	GET /api/matches/{uuid}/ws
+//	GET /api/matches/{uuid}/revisions
+//	GET /api/matches/{uuid}/revisions/{revision}
+func handleMatchesAPI(w http.ResponseWriter, r *http.Request) {
+	var path []string
+	if rest := strings.Trim(strings.TrimPrefix(r.URL.Path, PATH_API_MATCHES), "/"); rest != "" {
+		path = strings.Split(rest, "/")
+	}
+
+	switch {
+	case len(path) <= 1:
+	case len(path) == 2 && (path[1] == "events" || path[1] == "revisions" || path[1] == "ws"):
+	case len(path) == 3 && path[1] == "revisions":
+	case len(path) == 2 && path[1] == "handoff":
+		handleHandoff(w, r, path[0])
+		return
+	default:
+		writeProblem(w, http.StatusNotFound, nil)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		writeProblem(w, http.StatusMethodNotAllowed, nil)
+		return
+	}
+
+	switch len(path) {
+	case 0:
+		filter, err := parseMatchFilter(r.URL.Query())
+		if err != nil {
+			writeProblem(w, http.StatusBadRequest, err)
+			return
+		}
+
+		list, err := getMatches(filter)
+		if err != nil {
+			writeProblem(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		writeJSON(w, list)
+	case 1:
+		match, err := getMatch(path[0])
+		if errors.Is(err, store.ErrNotFound) {
+			writeProblem(w, http.StatusNotFound, err)
+			return
+		} else if err != nil {
+			writeProblem(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		writeJSON(w, match)
+	case 2:
+		if path[1] == "ws" {
+			handleScoring(w, r, path[0])
+			return
+		}
+
+		if path[1] == "events" {
+			// matches without updates are streamed from their first update
+			match, err := getMatch(path[0])
+			if errors.Is(err, store.ErrNotFound) {
+				if _, err := matches.Get(path[0]); err == nil {
+					serveEvents(w, r, path[0], nil)
+				} else {
+					writeProblem(w, http.StatusNotFound, err)
+				}
+
+				return
+			} else if err != nil {
+				writeProblem(w, http.StatusInternalServerError, err)
+				return
+			}
+
+			serveEvents(w, r, path[0], &match)
+			return
+		}
+
+		revisions, err := matches.Revisions(path[0])
+		if errors.Is(err, store.ErrNotFound) {
+			writeProblem(w, http.StatusNotFound, err)
+			return
+		} else if err != nil {
+			writeProblem(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		response := []APIRevision{}
+
+		for _, revision := range revisions {
+			response = append(response, newAPIRevision(revision))
+		}
+
+		writeJSON(w, response)
+	case 3:
+		n, err := strconv.Atoi(path[2])
+		if err != nil {
+			writeProblem(w, http.StatusNotFound, errors.New("invalid revision"))
+			return
+		}
+
+		revision, err := matches.Revision(path[0], n)
+		if errors.Is(err, store.ErrNotFound) {
+			writeProblem(w, http.StatusNotFound, errors.New("revision not found"))
+			return
+		} else if err != nil {
+			writeProblem(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		response := newAPIRevision(revision)
+		response.Data = json.RawMessage(revision.JSON)
+
+		writeJSON(w, response)
+	}
+}
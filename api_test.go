@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"score/src/parser"
+	"strings"
+	"testing"
+)
+
+// Returns a match between the given players, which is finished if Team1
+// won both games 21-0.
+func apiTestMatch(player1 string, player2 string, start int, finished bool) string {
+	points := "[" + strings.TrimSuffix(strings.Repeat("1,", 21), ",") + "]"
+	games := `{"points": [1, 2]}`
+	end := "null"
+
+	if finished {
+		games = fmt.Sprintf(`{"points": %s}, {"points": %s}`, points, points)
+		end = fmt.Sprint(start + 3600)
+	}
+
+	return fmt.Sprintf(`{
+		"info": {
+			"mode": 21,
+			"team1": [{ "country": "DK", "player": %q }],
+			"team2": [{ "country": "TW", "player": %q }],
+			"start": %d,
+			"end": %s
+		},
+		"games": [%s]
+	}`, player1, player2, start, end, games)
+}
+
+func getAPI(t *testing.T, target string, v any) int {
+	w := httptest.NewRecorder()
+	handleMatchesAPI(w, httptest.NewRequest(http.MethodGet, target, nil))
+
+	if w.Code == http.StatusOK {
+		if err := json.NewDecoder(w.Body).Decode(v); err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+
+	return w.Code
+}
+
+func TestMatchesAPI(t *testing.T) {
+	setup(t)
+
+	token := strings.Repeat("a", TOKEN_LENGTH)
+
+	data := []string{
+		apiTestMatch("Viktor AXELSEN", "CHOU Tien Chen", 1679684400, true),
+		apiTestMatch("Anders ANTONSEN", "CHOU Tien Chen", 1679688000, false),
+		apiTestMatch("Viktor AXELSEN", "LEE Zii Jia", 1679691600, false),
+	}
+
+	var uuids []string
+
+	for _, d := range data {
+		uuid, _ := createMatch(token)
+
+		if w := apiRequest(token, `{"action": "update", "match": "`+uuid+`", "data": `+d+`}`); w.Code != http.StatusOK {
+			t.Fatalf("Got status %d: %s", w.Code, w.Body.String())
+		}
+
+		uuids = append(uuids, uuid)
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+		total int
+	}{
+		{"?sort=start", uuids, 3},
+		{"?sort=-start", []string{uuids[2], uuids[1], uuids[0]}, 3},
+		{"?status=finished", uuids[:1], 1},
+		{"?status=running&sort=start", uuids[1:], 2},
+		{"?player=axelsen&sort=start", []string{uuids[0], uuids[2]}, 2},
+		{"?from=1679688000&to=1679691600", uuids[1:2], 1},
+		{"?mode=15", []string{}, 0},
+		{"?sort=start&limit=1&offset=1", uuids[1:2], 3},
+		{"?offset=5", []string{}, 3},
+	}
+
+	for _, test := range tests {
+		var list APIMatchList
+
+		if code := getAPI(t, PATH_API_MATCHES+test.query, &list); code != http.StatusOK {
+			t.Fatalf("%s: got status %d", test.query, code)
+		}
+
+		var got []string
+		for _, match := range list.Matches {
+			got = append(got, match.UUID)
+		}
+
+		if strings.Join(got, ",") != strings.Join(test.want, ",") || list.Total != test.total {
+			t.Errorf("%s: got %v (%d), want %v (%d)", test.query, got, list.Total, test.want, test.total)
+		}
+	}
+
+	for _, query := range []string{"?status=paused", "?from=yesterday", "?limit=0", "?sort=name"} {
+		if code := getAPI(t, PATH_API_MATCHES+query, nil); code != http.StatusBadRequest {
+			t.Errorf("%s: got status %d, want %d", query, code, http.StatusBadRequest)
+		}
+	}
+
+	var match APIMatch
+
+	if code := getAPI(t, PATH_API_MATCHES+"/"+uuids[0], &match); code != http.StatusOK {
+		t.Fatalf("Got status %d", code)
+	}
+
+	if !match.Finished || match.Winner != 1 || match.Team1PointsWon != 42 || match.Games[1].Team1PointsWon != 21 {
+		t.Errorf("Got %+v, want finished match won by Team1", match)
+	}
+
+	// derived statistics of each rally are included
+	if game := match.Games[0]; len(game.Scores) != 21 || game.Scores[20] != (parser.Score{Team1: 21}) || game.IntervalPoint != 10 || game.Serves == nil {
+		t.Errorf("Got %+v, want scores and interval of first game", game)
+	}
+
+	// running matches have no end
+	var running map[string]json.RawMessage
+
+	if code := getAPI(t, PATH_API_MATCHES+"/"+uuids[1], &running); code != http.StatusOK || !strings.Contains(string(running["info"]), `"end":null`) {
+		t.Errorf("Got status %d and %s, want running match without end", code, running["info"])
+	}
+
+	if code := getAPI(t, PATH_API_MATCHES+"/unknown", nil); code != http.StatusNotFound {
+		t.Errorf("Got status %d, want %d", code, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,572 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"path"
+	"score/src/auth"
+	"score/src/store"
+	"score/src/throttle"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/thanhpk/randstr"
+)
+
+const (
+	PATH_API_AUTH  = "/api/auth"
+	PATH_API_ADMIN = "/api/admin"
+	PATH_LOGIN     = "/login/"
+
+	// name of cookie that holds the session of a user
+	SESSION_COOKIE_NAME = "session"
+	// time after which users have to log in again
+	SESSION_TTL = 7 * 24 * time.Hour
+
+	// failed logins by an IP address within LOGIN_WINDOW, before further
+	// logins are refused until it has passed
+	LOGIN_FAILURES_PER_IP = 20
+	// failed logins for a username within LOGIN_WINDOW, before further
+	// logins are delayed by LOGIN_DELAY until it has passed. They are not
+	// refused, so that others cannot lock the user out.
+	LOGIN_FAILURES_PER_USER = 10
+	LOGIN_WINDOW            = 15 * time.Minute
+	LOGIN_DELAY             = 2 * time.Second
+
+	// everyone may score and organise, accounts are only needed to manage
+	// users
+	AUTH_ANONYMOUS = "anonymous"
+	// scoring and organising needs an account, following matches does not
+	AUTH_ACCOUNTS = "accounts"
+	// everything needs an account
+	AUTH_PRIVATE = "private"
+
+	ERR_LOGIN_FAILED = "username or password is wrong"
+	ERR_LOGIN_LIMIT  = "too many failed logins, please try again later"
+	ERR_FORBIDDEN    = "your role does not allow this"
+	ERR_UNAUTHORIZED = "please log in first"
+	ERR_SELF         = "admins cannot remove their own admin role"
+
+	// compared against if a user does not exist, so that logins take as long
+	// as for users that exist
+	DUMMY_HASH = "pbkdf2-sha256$600000$Y27q6JTQsZuUC7XL8D14JQ$e0diIY2lUQb+1rK9hCKKpk+sknz7c0HRK3XmbnWyRTc"
+)
+
+var (
+	users  store.UserStore
+	audits store.AuditStore
+	// one of AUTH_ANONYMOUS, AUTH_ACCOUNTS or AUTH_PRIVATE
+	authMode = AUTH_ANONYMOUS
+
+	// failed logins by IP address and by username, so that passwords
+	// cannot be guessed
+	loginIPThrottle   = throttle.New(LOGIN_FAILURES_PER_IP, LOGIN_WINDOW)
+	loginUserThrottle = throttle.New(LOGIN_FAILURES_PER_USER, LOGIN_WINDOW)
+	loginDelay        = LOGIN_DELAY
+)
+
+// User as returned by the API, without their password hash.
+type APIUser struct {
+	Username string    `json:"username"`
+	Role     auth.Role `json:"role"`
+	Created  int64     `json:"created"`
+}
+
+type APIAuditRecord struct {
+	ID       int    `json:"id"`
+	Created  int64  `json:"created"`
+	Username string `json:"username"`
+	Action   string `json:"action"`
+	Target   string `json:"target"`
+}
+
+type LoginPage struct {
+	// path to return to after logging in
+	Next string
+}
+
+func isAuthMode(mode string) bool {
+	return mode == AUTH_ANONYMOUS || mode == AUTH_ACCOUNTS || mode == AUTH_PRIVATE
+}
+
+func newAPIUser(user store.UserRecord) APIUser {
+	return APIUser{
+		Username: user.Username,
+		Role:     auth.Role(user.Role),
+		Created:  user.Created.Unix(),
+	}
+}
+
+// Returns the user of the session of the request, if any.
+func sessionUser(r *http.Request) (store.UserRecord, bool) {
+	cookie, err := r.Cookie(SESSION_COOKIE_NAME)
+	if err != nil || len(cookie.Value) != TOKEN_LENGTH {
+		return store.UserRecord{}, false
+	}
+
+	session, err := users.GetSession(store.HashToken(cookie.Value), time.Now())
+	if err != nil {
+		return store.UserRecord{}, false
+	}
+
+	user, err := users.GetUser(session.Username)
+	return user, err == nil
+}
+
+// Returns true if the user of the request has at least the given role.
+func hasRole(r *http.Request, role auth.Role) bool {
+	user, ok := sessionUser(r)
+	return ok && auth.Role(user.Role).Allows(role)
+}
+
+// Returns true if the request may do what the given role may do. Without
+// accounts, everyone may do everything but manage users.
+func allows(r *http.Request, role auth.Role) bool {
+	if authMode == AUTH_ANONYMOUS && role != auth.Admin {
+		return true
+	}
+
+	return hasRole(r, role)
+}
+
+// Writes a problem and returns false unless the request may do what the
+// given role may do, see allows.
+func authorize(w http.ResponseWriter, r *http.Request, role auth.Role) bool {
+	if allows(r, role) {
+		return true
+	}
+
+	if _, ok := sessionUser(r); ok {
+		writeProblem(w, http.StatusForbidden, errors.New(ERR_FORBIDDEN))
+	} else {
+		writeProblem(w, http.StatusUnauthorized, errors.New(ERR_UNAUTHORIZED))
+	}
+
+	return false
+}
+
+// Requires organiser rights for requests that change something, and records
+// them in the audit log.
+func authorizeWrite(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method == http.MethodGet {
+		return true
+	}
+
+	if !authorize(w, r, auth.Organiser) {
+		return false
+	}
+
+	recordAction(r, r.Method, r.URL.Path)
+
+	return true
+}
+
+// Records an action of the user of the request in the audit log. Anonymous
+// actions are not recorded.
+func recordAction(r *http.Request, action string, target string) {
+	user, ok := sessionUser(r)
+	if !ok {
+		return
+	}
+
+	if err := audits.Audit(user.Username, action, target); err != nil {
+		log.Printf("Could not record %s %s of %s: %s\n", action, target, user.Username, err)
+	}
+}
+
+// Returns the path to return to after logging in, which must be on this
+// server.
+func loginNext(next string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/\\") {
+		return PATH_INDEX
+	}
+
+	return next
+}
+
+func loginURL(r *http.Request) string {
+	return PATH_LOGIN + "?next=" + url.QueryEscape(r.URL.RequestURI())
+}
+
+// Requires a session of a viewer for everything but logging in if the server
+// is private.
+func requireViewer(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// e.g. /api/auth/../matches is not public
+		clean := path.Clean(r.URL.Path)
+		public := clean+"/" == PATH_LOGIN || clean == PATH_API_AUTH || strings.HasPrefix(clean, PATH_API_AUTH+"/")
+
+		if authMode != AUTH_PRIVATE || public || hasRole(r, auth.Viewer) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if strings.HasPrefix(clean, PATH_API) {
+			writeProblem(w, http.StatusUnauthorized, errors.New(ERR_UNAUTHORIZED))
+			return
+		}
+
+		http.Redirect(w, r, loginURL(r), http.StatusSeeOther)
+	})
+}
+
+// Creates a session for the user and returns its token.
+func login(username string, password string) (string, store.UserRecord, error) {
+	username, err := auth.NormalizeUsername(username)
+	if err != nil {
+		auth.CheckPassword(DUMMY_HASH, password)
+		return "", store.UserRecord{}, errors.New(ERR_LOGIN_FAILED)
+	}
+
+	user, err := users.GetUser(username)
+	if errors.Is(err, store.ErrNotFound) {
+		auth.CheckPassword(DUMMY_HASH, password)
+		return "", user, errors.New(ERR_LOGIN_FAILED)
+	} else if err != nil {
+		return "", user, err
+	}
+
+	if !auth.CheckPassword(user.PasswordHash, password) {
+		return "", user, errors.New(ERR_LOGIN_FAILED)
+	}
+
+	token := randstr.String(TOKEN_LENGTH)
+
+	if err := users.CreateSession(store.HashToken(token), user.Username, time.Now().Add(SESSION_TTL)); err != nil {
+		return "", user, err
+	}
+
+	return token, user, nil
+}
+
+// Creates a user with a hashed password.
+func createUser(username string, password string, role auth.Role) (store.UserRecord, error) {
+	username, err := auth.NormalizeUsername(username)
+	if err != nil {
+		return store.UserRecord{}, err
+	}
+
+	if !role.IsValid() {
+		return store.UserRecord{}, errors.New(auth.ERR_INVALID_ROLE)
+	}
+
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		return store.UserRecord{}, err
+	}
+
+	user := store.UserRecord{
+		Username:     username,
+		PasswordHash: hash,
+		Role:         string(role),
+	}
+
+	if err := users.CreateUser(user); err != nil {
+		return user, err
+	}
+
+	return users.GetUser(username)
+}
+
+// Changes the password and role of a user, unless they are empty.
+func updateUser(username string, password string, role auth.Role) (store.UserRecord, error) {
+	username, err := auth.NormalizeUsername(username)
+	if err != nil {
+		return store.UserRecord{}, store.ErrNotFound
+	}
+
+	user, err := users.GetUser(username)
+	if err != nil {
+		return user, err
+	}
+
+	if password != "" {
+		if user.PasswordHash, err = auth.HashPassword(password); err != nil {
+			return user, err
+		}
+	}
+
+	if role != "" {
+		if !role.IsValid() {
+			return user, errors.New(auth.ERR_INVALID_ROLE)
+		}
+
+		user.Role = string(role)
+	}
+
+	return user, users.UpdateUser(user)
+}
+
+// Handles the session API:
+//
+//	POST /api/auth/login
+//	POST /api/auth/logout
+//	GET  /api/auth/me
+func handleAuthAPI(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, PATH_API_AUTH), "/")
+
+	method := http.MethodPost
+	if path == "me" {
+		method = http.MethodGet
+	}
+
+	if path != "login" && path != "logout" && path != "me" {
+		writeProblem(w, http.StatusNotFound, nil)
+		return
+	}
+
+	if r.Method != method {
+		writeProblem(w, http.StatusMethodNotAllowed, nil)
+		return
+	}
+
+	switch path {
+	case "login":
+		var data struct {
+			Username string `json:"username"`
+			Password string `json:"password"`
+		}
+
+		if err := readJSON(r, &data); err != nil {
+			writeProblem(w, http.StatusBadRequest, err)
+			return
+		}
+
+		ip := clientIP(r)
+		username := strings.ToLower(strings.TrimSpace(data.Username))
+		now := time.Now()
+
+		if !loginIPThrottle.Allow(ip, now) {
+			writeProblem(w, http.StatusTooManyRequests, errors.New(ERR_LOGIN_LIMIT))
+			return
+		}
+
+		if !loginUserThrottle.Allow(username, now) {
+			time.Sleep(loginDelay)
+		}
+
+		token, user, err := login(data.Username, data.Password)
+		if err != nil {
+			loginIPThrottle.Fail(ip, now)
+			loginUserThrottle.Fail(username, now)
+			writeProblem(w, http.StatusUnauthorized, err)
+			return
+		}
+
+		loginUserThrottle.Reset(username)
+
+		if err := audits.Audit(user.Username, "login", ""); err != nil {
+			log.Printf("Could not record login of %s: %s\n", user.Username, err)
+		}
+
+		http.SetCookie(w, &http.Cookie{
+			Name:     SESSION_COOKIE_NAME,
+			Value:    token,
+			Path:     "/",
+			Expires:  time.Now().Add(SESSION_TTL),
+			HttpOnly: true,
+			// only sent over HTTPS if it is served over HTTPS
+			Secure:   r.TLS != nil,
+			SameSite: http.SameSiteLaxMode,
+		})
+
+		writeJSON(w, newAPIUser(user))
+	case "logout":
+		if cookie, err := r.Cookie(SESSION_COOKIE_NAME); err == nil {
+			users.DeleteSession(store.HashToken(cookie.Value))
+		}
+
+		http.SetCookie(w, &http.Cookie{
+			Name:     SESSION_COOKIE_NAME,
+			Path:     "/",
+			MaxAge:   -1,
+			HttpOnly: true,
+			Secure:   r.TLS != nil,
+			SameSite: http.SameSiteLaxMode,
+		})
+
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		user, ok := sessionUser(r)
+		if !ok {
+			writeProblem(w, http.StatusUnauthorized, errors.New(ERR_UNAUTHORIZED))
+			return
+		}
+
+		writeJSON(w, newAPIUser(user))
+	}
+}
+
+// Handles the admin API, which is only allowed for admins:
+//
+//	GET    /api/admin/users
+//	POST   /api/admin/users
+//	PATCH  /api/admin/users/{username}
+//	DELETE /api/admin/users/{username}
+//	GET    /api/admin/audit
+func handleAdminAPI(w http.ResponseWriter, r *http.Request) {
+	var path []string
+	if rest := strings.Trim(strings.TrimPrefix(r.URL.Path, PATH_API_ADMIN), "/"); rest != "" {
+		path = strings.Split(rest, "/")
+	}
+
+	var methods []string
+
+	switch {
+	case len(path) == 1 && path[0] == "users":
+		methods = []string{http.MethodGet, http.MethodPost}
+	case len(path) == 2 && path[0] == "users":
+		methods = []string{http.MethodPatch, http.MethodDelete}
+	case len(path) == 1 && path[0] == "audit":
+		methods = []string{http.MethodGet}
+	default:
+		writeProblem(w, http.StatusNotFound, nil)
+		return
+	}
+
+	allowed := false
+	for _, method := range methods {
+		allowed = allowed || r.Method == method
+	}
+
+	if !allowed {
+		writeProblem(w, http.StatusMethodNotAllowed, nil)
+		return
+	}
+
+	if !authorize(w, r, auth.Admin) {
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		recordAction(r, r.Method, r.URL.Path)
+	}
+
+	if path[0] == "audit" {
+		limit := DEFAULT_LIMIT
+		if value := r.URL.Query().Get("limit"); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 || n > MAX_LIMIT {
+				writeProblem(w, http.StatusBadRequest, errors.New("limit must be between 1 and "+strconv.Itoa(MAX_LIMIT)))
+				return
+			}
+
+			limit = n
+		}
+
+		records, err := audits.ListAudit(limit)
+		if err != nil {
+			writeProblem(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		list := []APIAuditRecord{}
+
+		for _, record := range records {
+			list = append(list, APIAuditRecord{
+				ID:       record.ID,
+				Created:  record.Created.Unix(),
+				Username: record.Username,
+				Action:   record.Action,
+				Target:   record.Target,
+			})
+		}
+
+		writeJSON(w, list)
+		return
+	}
+
+	var data struct {
+		Username string    `json:"username"`
+		Password string    `json:"password"`
+		Role     auth.Role `json:"role"`
+	}
+
+	if r.Method == http.MethodPost || r.Method == http.MethodPatch {
+		if err := readJSON(r, &data); err != nil {
+			writeProblem(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	self, _ := sessionUser(r)
+
+	switch r.Method {
+	case http.MethodGet:
+		records, err := users.ListUsers()
+		if err != nil {
+			writeProblem(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		list := []APIUser{}
+
+		for _, user := range records {
+			list = append(list, newAPIUser(user))
+		}
+
+		writeJSON(w, list)
+	case http.MethodPost:
+		user, err := createUser(data.Username, data.Password, data.Role)
+		if errors.Is(err, store.ErrExists) {
+			writeProblem(w, http.StatusConflict, err)
+			return
+		} else if err != nil {
+			writeProblem(w, http.StatusBadRequest, err)
+			return
+		}
+
+		writeCreated(w, newAPIUser(user))
+	case http.MethodPatch:
+		// an admin is always left to manage users
+		if path[1] == self.Username && data.Role != "" && data.Role != auth.Admin {
+			writeProblem(w, http.StatusConflict, errors.New(ERR_SELF))
+			return
+		}
+
+		user, err := updateUser(path[1], data.Password, data.Role)
+		if errors.Is(err, store.ErrNotFound) {
+			writeProblem(w, http.StatusNotFound, err)
+			return
+		} else if err != nil {
+			writeProblem(w, http.StatusBadRequest, err)
+			return
+		}
+
+		writeJSON(w, newAPIUser(user))
+	case http.MethodDelete:
+		if path[1] == self.Username {
+			writeProblem(w, http.StatusConflict, errors.New(ERR_SELF))
+			return
+		}
+
+		if err := users.DeleteUser(path[1]); err != nil {
+			writeStoreProblem(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func handleLogin(w http.ResponseWriter, r *http.Request) {
+	if strings.TrimSpace(r.URL.EscapedPath()) != PATH_LOGIN {
+		http.NotFound(w, r)
+		return
+	}
+
+	t, ok := templates["login.html"]
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	if err := t.Execute(w, LoginPage{Next: loginNext(r.URL.Query().Get("next"))}); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
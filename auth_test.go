@@ -0,0 +1,369 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"score/src/auth"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Logs in and returns the session cookie.
+func loginRequest(t *testing.T, username string, password string) *http.Cookie {
+	r := httptest.NewRequest(http.MethodPost, PATH_API_AUTH+"/login", strings.NewReader(`{"username": "`+username+`", "password": "`+password+`"}`))
+
+	w := httptest.NewRecorder()
+	handleAuthAPI(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Got status %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == SESSION_COOKIE_NAME {
+			return cookie
+		}
+	}
+
+	t.Fatal("Expected session cookie")
+	return nil
+}
+
+func adminRequest(method string, path string, body string, session *http.Cookie) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, PATH_API_ADMIN+path, strings.NewReader(body))
+	if session != nil {
+		r.AddCookie(session)
+	}
+
+	w := httptest.NewRecorder()
+	handleAdminAPI(w, r)
+
+	return w
+}
+
+func TestLogin(t *testing.T) {
+	setup(t)
+
+	if _, err := createUser("Desk", "correct horse", auth.Organiser); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	for _, password := range []string{"wrong password", ""} {
+		w := httptest.NewRecorder()
+		handleAuthAPI(w, httptest.NewRequest(http.MethodPost, PATH_API_AUTH+"/login", strings.NewReader(`{"username": "desk", "password": "`+password+`"}`)))
+
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("Got status %d, want %d", w.Code, http.StatusUnauthorized)
+		}
+	}
+
+	session := loginRequest(t, "DESK", "correct horse")
+
+	if !session.HttpOnly || session.Secure {
+		t.Error("Expected HttpOnly session cookie that is not restricted to HTTPS")
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "https://example.com"+PATH_API_AUTH+"/login", strings.NewReader(`{"username": "desk", "password": "correct horse"}`))
+
+	w := httptest.NewRecorder()
+	handleAuthAPI(w, r)
+
+	if cookies := w.Result().Cookies(); len(cookies) != 1 || !cookies[0].Secure {
+		t.Errorf("Got %+v, want secure session cookie over HTTPS", cookies)
+	}
+
+	r = httptest.NewRequest(http.MethodGet, PATH_API_AUTH+"/me", nil)
+	r.AddCookie(session)
+
+	w = httptest.NewRecorder()
+	handleAuthAPI(w, r)
+
+	var user APIUser
+	if err := json.NewDecoder(w.Body).Decode(&user); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if user.Username != "desk" || user.Role != auth.Organiser {
+		t.Errorf("Got %+v, want desk as organiser", user)
+	}
+
+	r = httptest.NewRequest(http.MethodPost, PATH_API_AUTH+"/logout", nil)
+	r.AddCookie(session)
+
+	w = httptest.NewRecorder()
+	handleAuthAPI(w, r)
+
+	if w.Code != http.StatusNoContent {
+		t.Errorf("Got status %d, want %d", w.Code, http.StatusNoContent)
+	}
+
+	r = httptest.NewRequest(http.MethodGet, PATH_API_AUTH+"/me", nil)
+	r.AddCookie(session)
+
+	w = httptest.NewRecorder()
+	handleAuthAPI(w, r)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Got status %d, want %d after logout", w.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestLoginThrottle(t *testing.T) {
+	setup(t)
+
+	if _, err := createUser("desk", "correct horse", auth.Organiser); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	loginStatus := func(username string, password string, ip string) int {
+		r := httptest.NewRequest(http.MethodPost, PATH_API_AUTH+"/login", strings.NewReader(`{"username": "`+username+`", "password": "`+password+`"}`))
+		r.RemoteAddr = ip + ":1234"
+
+		w := httptest.NewRecorder()
+		handleAuthAPI(w, r)
+
+		return w.Code
+	}
+
+	loginDelay = 50 * time.Millisecond
+	defer func() { loginDelay = LOGIN_DELAY }()
+
+	// failures from different addresses still count for the username
+	for i := 0; i < LOGIN_FAILURES_PER_USER; i++ {
+		loginStatus("desk", "wrong password", fmt.Sprintf("192.0.2.%d", i))
+	}
+
+	start := time.Now()
+
+	if code := loginStatus("desk", "wrong password", "198.51.100.1"); code != http.StatusUnauthorized {
+		t.Errorf("Got status %d, want %d", code, http.StatusUnauthorized)
+	}
+
+	if time.Since(start) < loginDelay {
+		t.Error("Expected login to be delayed")
+	}
+
+	// the user is not locked out
+	if code := loginStatus("Desk", "correct horse", "198.51.100.1"); code != http.StatusOK {
+		t.Errorf("Got status %d, want %d", code, http.StatusOK)
+	}
+
+	// failures for different usernames still count for the address
+	for i := 0; i < LOGIN_FAILURES_PER_IP; i++ {
+		loginStatus(fmt.Sprintf("user%d", i), "wrong password", "198.51.100.2")
+	}
+
+	if code := loginStatus("other", "wrong password", "198.51.100.2"); code != http.StatusTooManyRequests {
+		t.Errorf("Got status %d, want %d", code, http.StatusTooManyRequests)
+	}
+
+	if code := loginStatus("other", "wrong password", "198.51.100.3"); code != http.StatusUnauthorized {
+		t.Errorf("Got status %d, want %d", code, http.StatusUnauthorized)
+	}
+
+	// clients behind a trusted proxy do not share its address
+	trustedProxies, _ = parseTrustedProxies("198.51.100.2")
+	defer func() { trustedProxies = nil }()
+
+	r := httptest.NewRequest(http.MethodPost, PATH_API_AUTH+"/login", strings.NewReader(`{"username": "other", "password": "wrong password"}`))
+	r.RemoteAddr = "198.51.100.2:1234"
+	r.Header.Set("X-Forwarded-For", "203.0.113.1")
+
+	w := httptest.NewRecorder()
+	handleAuthAPI(w, r)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Got status %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestRoles(t *testing.T) {
+	setup(t)
+
+	authMode = AUTH_ACCOUNTS
+	defer func() { authMode = AUTH_ANONYMOUS }()
+
+	for username, role := range map[string]auth.Role{"viewer": auth.Viewer, "umpire": auth.Scorer} {
+		if _, err := createUser(username, "correct horse", role); err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+
+	token := strings.Repeat("a", TOKEN_LENGTH)
+
+	apiSessionRequest := func(session *http.Cookie) int {
+		r := httptest.NewRequest(http.MethodPost, PATH_API, strings.NewReader(`{"action": "new"}`))
+		r.AddCookie(&http.Cookie{Name: COOKIE_NAME, Value: token})
+		if session != nil {
+			r.AddCookie(session)
+		}
+
+		w := httptest.NewRecorder()
+		handleAPI(w, r)
+
+		return w.Code
+	}
+
+	viewer := loginRequest(t, "viewer", "correct horse")
+	umpire := loginRequest(t, "umpire", "correct horse")
+
+	for _, test := range []struct {
+		session *http.Cookie
+		want    int
+	}{
+		{nil, http.StatusUnauthorized},
+		{viewer, http.StatusForbidden},
+		{umpire, http.StatusCreated},
+	} {
+		if code := apiSessionRequest(test.session); code != test.want {
+			t.Errorf("Got status %d, want %d", code, test.want)
+		}
+	}
+
+	// scorers may not organise tournaments, but everyone may follow them
+	r := httptest.NewRequest(http.MethodPost, PATH_API_TOURNAMENTS, strings.NewReader(`{"name": "Open"}`))
+	r.AddCookie(umpire)
+
+	w := httptest.NewRecorder()
+	handleTournamentsAPI(w, r)
+
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Got status %d, want %d", w.Code, http.StatusForbidden)
+	}
+
+	w = httptest.NewRecorder()
+	handleTournamentsAPI(w, httptest.NewRequest(http.MethodGet, PATH_API_TOURNAMENTS, nil))
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Got status %d, want %d", w.Code, http.StatusOK)
+	}
+
+	w = httptest.NewRecorder()
+	handleClient(w, httptest.NewRequest(http.MethodGet, PATH_CLIENT, nil))
+
+	if location := w.Header().Get("Location"); !strings.HasPrefix(location, PATH_LOGIN) {
+		t.Errorf("Got redirect to %q, want login page", location)
+	}
+
+	records, err := audits.ListAudit(DEFAULT_LIMIT)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if len(records) == 0 || records[0].Username != "umpire" || records[0].Action != ACTION_NEW {
+		t.Errorf("Got %+v, want new match of umpire in audit log", records)
+	}
+}
+
+func TestAnonymousMode(t *testing.T) {
+	setup(t)
+
+	if w := apiRequest(strings.Repeat("a", TOKEN_LENGTH), `{"action": "new"}`); w.Code != http.StatusCreated {
+		t.Errorf("Got status %d, want %d", w.Code, http.StatusCreated)
+	}
+
+	// users are only managed by admins
+	if w := adminRequest(http.MethodGet, "/users", "", nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("Got status %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestPrivateMode(t *testing.T) {
+	setup(t)
+
+	authMode = AUTH_PRIVATE
+	defer func() { authMode = AUTH_ANONYMOUS }()
+
+	handler := requireViewer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for path, want := range map[string]int{
+		PATH_INDEX:                 http.StatusSeeOther,
+		PATH_API_MATCHES:           http.StatusUnauthorized,
+		PATH_LOGIN:                 http.StatusOK,
+		PATH_API_AUTH + "/login":   http.StatusOK,
+		PATH_API_AUTH + "/../../x": http.StatusSeeOther,
+	} {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+
+		if w.Code != want {
+			t.Errorf("%s: got status %d, want %d", path, w.Code, want)
+		}
+	}
+}
+
+func TestAdminAPI(t *testing.T) {
+	setup(t)
+
+	if _, err := createUser("root", "correct horse", auth.Admin); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	session := loginRequest(t, "root", "correct horse")
+
+	w := adminRequest(http.MethodPost, "/users", `{"username": "Umpire", "password": "battery staple", "role": "scorer"}`, session)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Got status %d, want %d: %s", w.Code, http.StatusCreated, w.Body.String())
+	}
+
+	for _, body := range []string{
+		`{"username": "umpire", "password": "battery staple", "role": "scorer"}`,
+		`{"username": "other", "password": "short", "role": "scorer"}`,
+		`{"username": "other", "password": "battery staple", "role": "king"}`,
+	} {
+		if w := adminRequest(http.MethodPost, "/users", body, session); w.Code != http.StatusConflict && w.Code != http.StatusBadRequest {
+			t.Errorf("%s: got status %d, want error", body, w.Code)
+		}
+	}
+
+	if w := adminRequest(http.MethodPatch, "/users/umpire", `{"role": "organiser"}`, session); w.Code != http.StatusOK {
+		t.Errorf("Got status %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+
+	// the last admin must not lock themselves out
+	if w := adminRequest(http.MethodPatch, "/users/root", `{"role": "viewer"}`, session); w.Code != http.StatusConflict {
+		t.Errorf("Got status %d, want %d", w.Code, http.StatusConflict)
+	}
+
+	if w := adminRequest(http.MethodDelete, "/users/root", "", session); w.Code != http.StatusConflict {
+		t.Errorf("Got status %d, want %d", w.Code, http.StatusConflict)
+	}
+
+	w = adminRequest(http.MethodGet, "/users", "", session)
+
+	var list []APIUser
+	if err := json.NewDecoder(w.Body).Decode(&list); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if len(list) != 2 || list[1].Username != "umpire" || list[1].Role != auth.Organiser {
+		t.Errorf("Got %+v, want root and umpire as organiser", list)
+	}
+
+	// organisers may not manage users
+	umpire := loginRequest(t, "umpire", "battery staple")
+
+	if w := adminRequest(http.MethodGet, "/users", "", umpire); w.Code != http.StatusForbidden {
+		t.Errorf("Got status %d, want %d", w.Code, http.StatusForbidden)
+	}
+
+	if w := adminRequest(http.MethodDelete, "/users/umpire", "", session); w.Code != http.StatusNoContent {
+		t.Errorf("Got status %d, want %d", w.Code, http.StatusNoContent)
+	}
+
+	w = adminRequest(http.MethodGet, "/audit?limit=2", "", session)
+
+	var records []APIAuditRecord
+	if err := json.NewDecoder(w.Body).Decode(&records); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if len(records) != 2 || records[0].Action != http.MethodDelete || records[0].Target != PATH_API_ADMIN+"/users/umpire" {
+		t.Errorf("Got %+v, want deletion of umpire in audit log", records)
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"score/src/hub"
+	"time"
+)
+
+const (
+	PATH_API_EVENTS = "/api/events"
+
+	// interval of comments sent to keep idle connections open
+	KEEPALIVE_INTERVAL = 15 * time.Second
+
+	// names of the events
+	EVENT_MATCH = "match"
+	EVENT_LINK  = "link"
+)
+
+var events = hub.New()
+
+// APILink is sent as "link" event once a match has been linked to or
+// unlinked from a position of a draw, a rubber of a tie or a scheduled
+// match, so that pages do not have to reload on every change of any match.
+type APILink struct {
+	Match     string `json:"match"`
+	Draw      string `json:"draw,omitempty"`
+	Tie       string `json:"tie,omitempty"`
+	Scheduled int    `json:"scheduled,omitempty"`
+	Linked    bool   `json:"linked"`
+}
+
+// Publishes the updated match to subscribers of the event streams.
+func publishMatch(match APIMatch) {
+	data, err := json.Marshal(match)
+	if err != nil {
+		return
+	}
+
+	events.Publish(hub.Message{
+		Match: match.UUID,
+		Type:  EVENT_MATCH,
+		Data:  data,
+	})
+}
+
+// Publishes a changed link to subscribers of all matches.
+func publishLink(link APILink) {
+	data, err := json.Marshal(link)
+	if err != nil {
+		return
+	}
+
+	events.Publish(hub.Message{
+		Type: EVENT_LINK,
+		Data: data,
+	})
+}
+
+// Streams updates of the match with the given uuid, or of all matches if the
+// uuid is empty, as Server-Sent Events. Every update is a "match" event that
+// contains the match as returned by GET /api/matches/{uuid}. The stream of
+// all matches also contains "link" events, see APILink. If initial is given,
+// it is sent first.
+func serveEvents(w http.ResponseWriter, r *http.Request, uuid string, initial *APIMatch) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeProblem(w, http.StatusInternalServerError, errors.New("streaming is not supported"))
+		return
+	}
+
+	messages, cancel := events.Subscribe(uuid)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	// disable buffering of reverse proxies, e.g. nginx
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if initial != nil {
+		data, _ := json.Marshal(initial)
+		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", EVENT_MATCH, data)
+	}
+
+	flusher.Flush()
+
+	keepalive := time.NewTicker(KEEPALIVE_INTERVAL)
+	defer keepalive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case message := <-messages:
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", message.Type, message.Data)
+		case <-keepalive.C:
+			fmt.Fprint(w, ": keepalive\n\n")
+		}
+
+		flusher.Flush()
+	}
+}
+
+// Handles GET /api/events.
+func handleEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeProblem(w, http.StatusMethodNotAllowed, nil)
+		return
+	}
+
+	serveEvents(w, r, "", nil)
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Returns the data of the next event of a stream.
+func nextEvent(t *testing.T, scanner *bufio.Scanner) APIMatch {
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+
+		var match APIMatch
+		if err := json.Unmarshal([]byte(data), &match); err != nil {
+			t.Fatal(err.Error())
+		}
+
+		return match
+	}
+
+	t.Fatal("Expected event")
+	return APIMatch{}
+}
+
+func TestEvents(t *testing.T) {
+	setup(t)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(PATH_API_EVENTS, handleEvents)
+	mux.HandleFunc(PATH_API_MATCHES+"/", handleMatchesAPI)
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	token := strings.Repeat("a", TOKEN_LENGTH)
+	uuid, _ := createMatch(token)
+	other, _ := createMatch(token)
+
+	apiRequest(token, `{"action": "update", "match": "`+uuid+`", "data": `+testMatch+`}`)
+
+	all, err := http.Get(server.URL + PATH_API_EVENTS)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	defer all.Body.Close()
+
+	one, err := http.Get(server.URL + PATH_API_MATCHES + "/" + uuid + "/events")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	defer one.Body.Close()
+
+	if ct := one.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Got content type %s", ct)
+	}
+
+	allEvents := bufio.NewScanner(all.Body)
+	oneEvents := bufio.NewScanner(one.Body)
+
+	// the stream of a single match starts with its current state
+	if match := nextEvent(t, oneEvents); match.UUID != uuid || match.PointsPlayed != 3 {
+		t.Errorf("Got %+v, want initial state", match)
+	}
+
+	apiRequest(token, `{"action": "update", "match": "`+other+`", "data": `+testMatch+`}`)
+	apiRequest(token, `{"action": "update", "match": "`+uuid+`", "data": `+strings.Replace(testMatch, "[1, 2, 1]", "[1, 2, 1, 1]", 1)+`}`)
+
+	if match := nextEvent(t, allEvents); match.UUID != other {
+		t.Errorf("Got %s, want %s", match.UUID, other)
+	}
+
+	if match := nextEvent(t, allEvents); match.UUID != uuid {
+		t.Errorf("Got %s, want %s", match.UUID, uuid)
+	}
+
+	if match := nextEvent(t, oneEvents); match.UUID != uuid || match.PointsPlayed != 4 {
+		t.Errorf("Got %+v, want updated match", match)
+	}
+}
+
+func TestLinkEvents(t *testing.T) {
+	setup(t)
+
+	var tie APITie
+	if code := jsonRequest(t, handleTiesAPI, http.MethodPost, PATH_API_TIES, `{"team1": "BC Berlin", "team2": "SG Hamburg", "mode": 21, "rubbers": ["MS"]}`, &tie); code != http.StatusCreated {
+		t.Fatalf("Got status %d, want %d", code, http.StatusCreated)
+	}
+
+	all, cancel := events.Subscribe("")
+	defer cancel()
+
+	uuid, err := createTieMatch(strings.Repeat("a", TOKEN_LENGTH), TieLink{Tie: tie.ID, Rubber: 0})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// pages of the tie learn about the new match before it is started
+	message := <-all
+
+	var link APILink
+	if err := json.Unmarshal(message.Data, &link); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if message.Type != EVENT_LINK || link.Match != uuid || link.Tie != tie.ID || !link.Linked {
+		t.Errorf("Got %s %+v, want link of %s", message.Type, link, uuid)
+	}
+}
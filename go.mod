@@ -4,7 +4,12 @@ go 1.20
 
 require (
 	github.com/biter777/countries v1.6.4
+	github.com/coder/websocket v1.8.12
 	github.com/google/uuid v1.3.0
 	github.com/mattn/go-sqlite3 v1.14.16
 	github.com/thanhpk/randstr v1.0.5
+	golang.org/x/crypto v0.31.0
+	golang.org/x/term v0.27.0
 )
+
+require golang.org/x/sys v0.28.0 // indirect
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"score/src/auth"
+	"score/src/store"
+	"score/src/throttle"
+	"strings"
+	"time"
+
+	"github.com/thanhpk/randstr"
+)
+
+const (
+	ACTION_HANDOFF = "handoff"
+
+	// time in which a handoff code must be used
+	HANDOFF_TTL = 5 * time.Minute
+	// length of handoff codes, which are typed on the other device
+	HANDOFF_CODE_LENGTH = 6
+	// characters of handoff codes, without ones that are easily confused
+	HANDOFF_CODE_CHARS = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	// codes are random, but may collide with an unused code
+	HANDOFF_ATTEMPTS = 3
+	// invalid codes a client may send within HANDOFF_TTL, so that codes
+	// cannot be guessed
+	HANDOFF_FAILURES = 10
+
+	ERR_NOT_STARTED = "match has not been started"
+)
+
+var (
+	handoffs store.HandoffStore
+	// token that allows handing off any match, e.g. by the tournament desk,
+	// sent as "Authorization: Bearer {token}". Handoffs by the desk are
+	// disabled if it is empty.
+	adminToken string
+	// failed handoffs by IP address
+	handoffThrottle = throttle.New(HANDOFF_FAILURES, HANDOFF_TTL)
+	// reverse proxies whose X-Forwarded-For and X-Real-IP headers are
+	// trusted, e.g. the one in front of docker-compose. The headers are
+	// ignored if it is empty.
+	trustedProxies []*net.IPNet
+)
+
+type APIHandoff struct {
+	Code    string `json:"code"`
+	URL     string `json:"url"`
+	Expires int64  `json:"expires"`
+}
+
+// Match that was handed off to the client, with its JSON as it was last
+// sent by the previous client.
+type APIHandoffResponse struct {
+	Match string          `json:"match"`
+	Data  json.RawMessage `json:"data"`
+}
+
+// Returns true if the request is sent by the tournament desk, i.e. with
+// adminToken or by a user that is at least an organiser.
+func isAdmin(r *http.Request) bool {
+	if hasRole(r, auth.Organiser) {
+		return true
+	}
+
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+	return ok && len(adminToken) != 0 && subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) == 1
+}
+
+// Returns true if the request is sent by the client that scores the match.
+func isScorer(r *http.Request, record store.Record) bool {
+	token, err := r.Cookie(COOKIE_NAME)
+
+	return err == nil && len(token.Value) == TOKEN_LENGTH && subtle.ConstantTimeCompare([]byte(token.Value), []byte(record.Token)) == 1
+}
+
+// Parses a comma separated list of IP addresses and networks in CIDR
+// notation, e.g. "127.0.0.1,172.16.0.0/12".
+func parseTrustedProxies(list string) ([]*net.IPNet, error) {
+	var proxies []*net.IPNet
+
+	for _, s := range strings.Split(list, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+
+		if !strings.Contains(s, "/") {
+			ip := net.ParseIP(s)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IP address %s", s)
+			}
+
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip = ip.To4()
+				bits = 8 * net.IPv4len
+			}
+
+			proxies = append(proxies, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, err
+		}
+
+		proxies = append(proxies, network)
+	}
+
+	return proxies, nil
+}
+
+func isTrustedProxy(addr string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+
+	for _, network := range trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Returns the IP address of the client that sent the request. Behind a
+// trusted proxy, it is the last address in X-Forwarded-For that is not a
+// trusted proxy itself, or X-Real-IP if the proxy does not forward the
+// former.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	if !isTrustedProxy(host) {
+		return host
+	}
+
+	forwarded := r.Header.Values("X-Forwarded-For")
+	if len(forwarded) == 0 {
+		if addr := strings.TrimSpace(r.Header.Get("X-Real-IP")); net.ParseIP(addr) != nil {
+			return addr
+		}
+
+		return host
+	}
+
+	// proxies append the address they received the request from, so only
+	// the addresses at the end were added by trusted proxies
+	addrs := strings.Split(strings.Join(forwarded, ","), ",")
+	for i := len(addrs) - 1; i >= 0; i-- {
+		addr := strings.TrimSpace(addrs[i])
+		if net.ParseIP(addr) == nil {
+			break
+		}
+
+		host = addr
+		if !isTrustedProxy(addr) {
+			break
+		}
+	}
+
+	return host
+}
+
+// Creates a handoff code for a match that has not been finished.
+func createHandoff(uuid string, now time.Time) (APIHandoff, error) {
+	expires := now.Add(HANDOFF_TTL)
+
+	for attempt := 1; ; attempt++ {
+		code := randstr.String(HANDOFF_CODE_LENGTH, HANDOFF_CODE_CHARS)
+
+		err := handoffs.CreateHandoff(code, uuid, expires)
+		if err == nil {
+			query := url.Values{}
+			query.Set("handoff", code)
+
+			return APIHandoff{
+				Code:    code,
+				URL:     PATH_CLIENT + "?" + query.Encode(),
+				Expires: expires.Unix(),
+			}, nil
+		}
+
+		if errors.Is(err, store.ErrNotFound) || attempt == HANDOFF_ATTEMPTS {
+			return APIHandoff{}, err
+		}
+	}
+}
+
+// Moves the match of a handoff code to the client with the given token.
+func redeemHandoff(code string, token string) (APIHandoffResponse, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+
+	uuid, err := handoffs.Handoff(code, token, time.Now())
+	if err != nil {
+		return APIHandoffResponse{}, err
+	}
+
+	record, err := matches.Get(uuid)
+	if err != nil {
+		return APIHandoffResponse{}, err
+	}
+
+	return APIHandoffResponse{
+		Match: uuid,
+		Data:  json.RawMessage(record.JSON),
+	}, nil
+}
+
+// Handles POST /api/matches/{uuid}/handoff, which is allowed for the client
+// that scores the match and for the tournament desk.
+func handleHandoff(w http.ResponseWriter, r *http.Request, uuid string) {
+	if r.Method != http.MethodPost {
+		writeProblem(w, http.StatusMethodNotAllowed, nil)
+		return
+	}
+
+	record, err := matches.Get(uuid)
+	if err != nil {
+		writeStoreProblem(w, err)
+		return
+	}
+
+	if len(record.Token) == 0 || !(isScorer(r, record) || isAdmin(r)) {
+		writeProblem(w, http.StatusForbidden, errors.New("match cannot be handed off with this token"))
+		return
+	}
+
+	// the client that continues the match needs its state
+	if len(record.JSON) == 0 {
+		writeProblem(w, http.StatusConflict, errors.New(ERR_NOT_STARTED))
+		return
+	}
+
+	handoff, err := createHandoff(uuid, time.Now())
+	if errors.Is(err, store.ErrNotFound) {
+		// the match has been finished in the meantime
+		writeProblem(w, http.StatusConflict, err)
+		return
+	} else if err != nil {
+		writeProblem(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeCreated(w, handoff)
+}
+
+// Prepares the client to continue a match, e.g. /c/?handoff={code}. The code
+// is only used once the client sends it.
+func prefillHandoffClient(data *ClientData, query url.Values) error {
+	data.Handoff = strings.ToUpper(strings.TrimSpace(query.Get("handoff")))
+
+	return nil
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func handoffRequest(t *testing.T, uuid string, token string, admin string, v any) int {
+	r := httptest.NewRequest(http.MethodPost, PATH_API_MATCHES+"/"+uuid+"/handoff", nil)
+	r.AddCookie(&http.Cookie{Name: COOKIE_NAME, Value: token})
+
+	if admin != "" {
+		r.Header.Set("Authorization", "Bearer "+admin)
+	}
+
+	w := httptest.NewRecorder()
+	handleMatchesAPI(w, r)
+
+	if w.Code == http.StatusCreated {
+		if err := json.NewDecoder(w.Body).Decode(v); err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+
+	return w.Code
+}
+
+func TestHandoff(t *testing.T) {
+	setup(t)
+
+	adminToken = "desk"
+	defer func() { adminToken = "" }()
+
+	token := strings.Repeat("a", TOKEN_LENGTH)
+	other := strings.Repeat("b", TOKEN_LENGTH)
+	third := strings.Repeat("c", TOKEN_LENGTH)
+
+	w := apiRequest(token, `{"action": "new"}`)
+
+	var response APIResponseData
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	var handoff APIHandoff
+
+	// the client needs the state of the match to continue it
+	if code := handoffRequest(t, response.Match, token, "", &handoff); code != http.StatusConflict {
+		t.Errorf("Got status %d, want %d", code, http.StatusConflict)
+	}
+
+	data := apiTestMatch("Viktor AXELSEN", "CHOU Tien Chen", 1679684400, false)
+	if w := apiRequest(token, `{"action": "update", "match": "`+response.Match+`", "data": `+data+`}`); w.Code != http.StatusOK {
+		t.Fatalf("Got status %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+
+	for _, admin := range []string{"", "wrong"} {
+		if code := handoffRequest(t, response.Match, other, admin, &handoff); code != http.StatusForbidden {
+			t.Errorf("Got status %d, want %d", code, http.StatusForbidden)
+		}
+	}
+
+	if code := handoffRequest(t, response.Match, token, "", &handoff); code != http.StatusCreated || len(handoff.Code) != HANDOFF_CODE_LENGTH || !strings.Contains(handoff.URL, handoff.Code) {
+		t.Fatalf("Got status %d and %+v, want handoff code", code, handoff)
+	}
+
+	var unused APIHandoff
+	if code := handoffRequest(t, response.Match, "", "desk", &unused); code != http.StatusCreated {
+		t.Fatalf("Got status %d, want %d", code, http.StatusCreated)
+	}
+
+	if w := apiRequest(other, `{"action": "handoff", "code": "unknown"}`); w.Code != http.StatusNotFound {
+		t.Errorf("Got status %d, want %d", w.Code, http.StatusNotFound)
+	}
+
+	// codes are not case sensitive
+	w = apiRequest(other, `{"action": "handoff", "code": "`+strings.ToLower(handoff.Code)+`"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Got status %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+
+	var continued APIHandoffResponse
+	if err := json.NewDecoder(w.Body).Decode(&continued); err != nil || continued.Match != response.Match || !strings.Contains(string(continued.Data), "Viktor AXELSEN") {
+		t.Fatalf("Got %+v (%v), want handed off match", continued, err)
+	}
+
+	if w := apiRequest(token, `{"action": "update", "match": "`+response.Match+`", "data": `+data+`}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Got status %d, want %d", w.Code, http.StatusBadRequest)
+	}
+
+	if w := apiRequest(other, `{"action": "update", "match": "`+response.Match+`", "data": `+data+`}`); w.Code != http.StatusOK {
+		t.Errorf("Got status %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+
+	// the code of the desk was created for the previous client
+	if w := apiRequest(third, `{"action": "handoff", "code": "`+unused.Code+`"}`); w.Code != http.StatusConflict {
+		t.Errorf("Got status %d, want %d", w.Code, http.StatusConflict)
+	}
+
+	w = httptest.NewRecorder()
+	handleClient(w, httptest.NewRequest(http.MethodGet, PATH_CLIENT+"?handoff="+handoff.Code, nil))
+
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `const HANDOFF = "`+handoff.Code+`"`) {
+		t.Errorf("Got status %d, want client for handoff", w.Code)
+	}
+
+	// codes cannot be guessed
+	for i := 1; i < HANDOFF_FAILURES; i++ {
+		apiRequest(third, `{"action": "handoff", "code": "unknown"}`)
+	}
+
+	if w := apiRequest(third, `{"action": "handoff", "code": "unknown"}`); w.Code != http.StatusTooManyRequests {
+		t.Errorf("Got status %d, want %d", w.Code, http.StatusTooManyRequests)
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	proxies, err := parseTrustedProxies("192.0.2.1, 198.51.100.0/24")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	trustedProxies = proxies
+	defer func() { trustedProxies = nil }()
+
+	if _, err := parseTrustedProxies("192.0.2"); err == nil {
+		t.Error("Expected invalid address to fail")
+	}
+
+	for _, test := range []struct {
+		remote    string
+		forwarded string
+		real      string
+		want      string
+	}{
+		{"203.0.113.1:1234", "", "", "203.0.113.1"},
+		// headers of untrusted clients are ignored
+		{"203.0.113.1:1234", "203.0.113.2", "203.0.113.3", "203.0.113.1"},
+		{"192.0.2.1:1234", "", "", "192.0.2.1"},
+		{"192.0.2.1:1234", "203.0.113.2", "", "203.0.113.2"},
+		{"192.0.2.1:1234", "", "203.0.113.3", "203.0.113.3"},
+		// addresses sent by the client itself are skipped
+		{"192.0.2.1:1234", "203.0.113.9, 203.0.113.2", "", "203.0.113.2"},
+		{"192.0.2.1:1234", "203.0.113.2, 198.51.100.7", "", "203.0.113.2"},
+		{"192.0.2.1:1234", "198.51.100.7", "203.0.113.3", "198.51.100.7"},
+		{"192.0.2.1:1234", "invalid", "", "192.0.2.1"},
+	} {
+		r := httptest.NewRequest(http.MethodPost, PATH_API_MATCHES, nil)
+		r.RemoteAddr = test.remote
+
+		if test.forwarded != "" {
+			r.Header.Set("X-Forwarded-For", test.forwarded)
+		}
+
+		if test.real != "" {
+			r.Header.Set("X-Real-IP", test.real)
+		}
+
+		if ip := clientIP(r); ip != test.want {
+			t.Errorf("Got client IP %s for %s via %s, want %s", ip, test.forwarded, test.remote, test.want)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"score/src/store"
+)
+
+const MIGRATE_USAGE = "usage: score migrate [status|up|dry-run]"
+
+// Runs the migrate command on the database at the given path:
+//
+//	status   prints the schema version and pending migrations
+//	up       applies all pending migrations
+//	dry-run  prints the SQL of pending migrations without applying them
+func runMigrate(w io.Writer, path string, args []string) error {
+	command := "status"
+	if len(args) > 0 {
+		command = args[0]
+	}
+
+	if len(args) > 1 {
+		return errors.New(MIGRATE_USAGE)
+	}
+
+	var migrator *store.Migrator
+	var err error
+
+	// only up may modify the database
+	switch command {
+	case "up":
+		migrator, err = store.NewMigrator(path)
+	case "status", "dry-run":
+		migrator, err = store.NewReadOnlyMigrator(path)
+	default:
+		return errors.New(MIGRATE_USAGE)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	defer migrator.Close()
+
+	switch command {
+	case "status":
+		version, err := migrator.Version()
+		if err != nil {
+			return err
+		}
+
+		pending, err := migrator.Pending()
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(w, "%s: schema version %d, %d pending migration(s)\n", path, version, len(pending))
+
+		for _, migration := range pending {
+			fmt.Fprintf(w, "  %s\n", migration.Name)
+		}
+
+	case "up":
+		applied, err := migrator.Up()
+
+		for _, migration := range applied {
+			fmt.Fprintf(w, "applied %s\n", migration.Name)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if len(applied) == 0 {
+			fmt.Fprintln(w, "database is up to date")
+		}
+
+	case "dry-run":
+		pending, err := migrator.Pending()
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range pending {
+			fmt.Fprintf(w, "-- %s\n%s\n", migration.Name, migration.SQL)
+		}
+
+		if len(pending) == 0 {
+			fmt.Fprintln(w, "database is up to date")
+		}
+
+	default:
+		return errors.New(MIGRATE_USAGE)
+	}
+
+	return nil
+}
@@ -0,0 +1,340 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"score/src/parser"
+	"score/src/schedule"
+	"score/src/store"
+	"sort"
+	"strings"
+)
+
+const (
+	PATH_API_PLANNED = "/api/planned"
+	PATH_UPCOMING    = "/upcoming/"
+
+	ERR_INVALID_PLAN   = "planned match needs a mode, a start and two teams of one or two players"
+	ERR_PLAN_MISMATCH  = "teams or mode do not match the planned match"
+	ERR_PLAN_UNCLAIMED = "planned match has not been claimed"
+)
+
+var planned store.PlannedStore
+
+// MatchPlan is what is known about a match before it is started.
+type MatchPlan struct {
+	Mode  parser.Mode `json:"mode"`
+	Team1 parser.Team `json:"team1"`
+	Team2 parser.Team `json:"team2"`
+	// id of the court of the schedule the match is played on, 0 if it is
+	// not known yet
+	Court int             `json:"court,omitempty"`
+	Start parser.UnixTime `json:"start"`
+}
+
+// Planned match as returned by the API.
+type APIPlannedMatch struct {
+	MatchPlan
+	UUID string `json:"uuid"`
+	// name of the court, if known
+	CourtName string `json:"court_name,omitempty"`
+	// true once a client scores the match
+	Claimed bool `json:"claimed"`
+	Started bool `json:"started"`
+}
+
+type UpcomingPage struct {
+	Matches []APIPlannedMatch
+}
+
+func (p MatchPlan) validate(s schedule.Schedule) error {
+	players := len(p.Team1)
+
+	if (players != 1 && players != 2) || len(p.Team2) != players || !p.Team1.IsValid() || !p.Team2.IsValid() || p.Start.IsZero() {
+		return errors.New(ERR_INVALID_PLAN)
+	}
+
+	if _, ok := parser.Rules(p.Mode); !ok {
+		return errors.New(parser.ERR_INVALID_MODE)
+	}
+
+	if p.Court != 0 {
+		if _, err := s.Court(p.Court); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// The name of its court is taken from the schedule.
+func newAPIPlannedMatch(record store.PlannedRecord, s schedule.Schedule) (APIPlannedMatch, error) {
+	match := APIPlannedMatch{
+		UUID:    record.UUID,
+		Claimed: record.Claimed,
+		Started: record.Started,
+	}
+
+	if err := json.Unmarshal([]byte(record.Plan), &match.MatchPlan); err != nil {
+		return match, err
+	}
+
+	if court, err := s.Court(match.Court); err == nil {
+		match.CourtName = court.Name
+	}
+
+	return match, nil
+}
+
+func getPlannedMatch(uuid string) (APIPlannedMatch, error) {
+	record, err := planned.GetPlanned(uuid)
+	if err != nil {
+		return APIPlannedMatch{}, err
+	}
+
+	s, err := loadSchedule()
+	if err != nil {
+		return APIPlannedMatch{}, err
+	}
+
+	return newAPIPlannedMatch(record, s)
+}
+
+// Returns the planned matches that have not been started, by planned start.
+func getUpcomingMatches() ([]APIPlannedMatch, error) {
+	records, err := planned.ListPlanned()
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := loadSchedule()
+	if err != nil {
+		return nil, err
+	}
+
+	upcoming := []APIPlannedMatch{}
+
+	for _, record := range records {
+		match, err := newAPIPlannedMatch(record, s)
+		if err != nil {
+			return nil, err
+		}
+
+		upcoming = append(upcoming, match)
+	}
+
+	sort.SliceStable(upcoming, func(i, j int) bool {
+		return upcoming[i].Start.Before(upcoming[j].Start.Time)
+	})
+
+	return upcoming, nil
+}
+
+// Checks that a planned match is played by its teams in its mode. Returns
+// nil if the match was not planned.
+func checkPlannedMatch(uuid string, m parser.Match) error {
+	record, err := planned.GetPlanned(uuid)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	var plan MatchPlan
+
+	if err := json.Unmarshal([]byte(record.Plan), &plan); err != nil {
+		return err
+	}
+
+	if m.Info.Mode != plan.Mode || !sameTeam(m.Info.Team1, plan.Team1) || !sameTeam(m.Info.Team2, plan.Team2) {
+		return errors.New(ERR_PLAN_MISMATCH)
+	}
+
+	return nil
+}
+
+// Hands a planned match to the client with the given token, see createMatch.
+func claimPlannedMatch(token string, uuid string) (string, error) {
+	if err := planned.Claim(uuid, token); err != nil {
+		return "", err
+	}
+
+	return uuid, nil
+}
+
+// Removes the client of a planned match that has not been started, e.g. if
+// it was claimed on the wrong device, so that another client can claim it.
+// drawMutex is held, as matches are only updated while it is held.
+func unclaimPlannedMatch(uuid string) (APIPlannedMatch, error) {
+	drawMutex.Lock()
+	defer drawMutex.Unlock()
+
+	match, err := getPlannedMatch(uuid)
+	if err != nil {
+		return match, err
+	}
+
+	if match.Started {
+		return match, errors.New(ERR_MATCH_STARTED)
+	}
+
+	if !match.Claimed {
+		return match, errors.New(ERR_PLAN_UNCLAIMED)
+	}
+
+	if err := planned.Unclaim(uuid); err != nil {
+		return match, err
+	}
+
+	match.Claimed = false
+
+	return match, nil
+}
+
+// Fills in the teams of a planned match for the client, e.g.
+// /c/?planned={uuid}
+func prefillPlannedClient(data *ClientData, query url.Values) error {
+	match, err := getPlannedMatch(query.Get("planned"))
+	if err != nil {
+		return err
+	}
+
+	data.Planned = match.UUID
+	data.Team1 = match.Team1
+	data.Team2 = match.Team2
+	data.Doubles = len(match.Team1) == 2
+	data.DefaultMode = match.Mode
+
+	return nil
+}
+
+// Handles the planned match API:
+//
+//	GET  /api/planned
+//	POST /api/planned
+//	GET  /api/planned/{uuid}
+//	POST /api/planned/{uuid}/unclaim
+func handlePlannedAPI(w http.ResponseWriter, r *http.Request) {
+	var path []string
+	if rest := strings.Trim(strings.TrimPrefix(r.URL.Path, PATH_API_PLANNED), "/"); rest != "" {
+		path = strings.Split(rest, "/")
+	}
+
+	if len(path) > 2 || (len(path) == 2 && path[1] != "unclaim") {
+		writeProblem(w, http.StatusNotFound, nil)
+		return
+	}
+
+	// matches are planned and unclaimed by POST, everything else is
+	// read-only
+	method := http.MethodGet
+	if len(path) == 2 || (len(path) == 0 && r.Method == http.MethodPost) {
+		method = http.MethodPost
+	}
+
+	if r.Method != method {
+		writeProblem(w, http.StatusMethodNotAllowed, nil)
+		return
+	}
+
+	if !authorizeWrite(w, r) {
+		return
+	}
+
+	if len(path) == 2 {
+		match, err := unclaimPlannedMatch(path[0])
+		if errors.Is(err, store.ErrNotFound) {
+			writeStoreProblem(w, err)
+			return
+		} else if err != nil {
+			writeProblem(w, http.StatusBadRequest, err)
+			return
+		}
+
+		writeJSON(w, match)
+		return
+	}
+
+	if len(path) == 1 {
+		match, err := getPlannedMatch(path[0])
+		if err != nil {
+			writeStoreProblem(w, err)
+			return
+		}
+
+		writeJSON(w, match)
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		var plan MatchPlan
+
+		if err := readJSON(r, &plan); err != nil {
+			writeProblem(w, http.StatusBadRequest, err)
+			return
+		}
+
+		s, err := loadSchedule()
+		if err != nil {
+			writeProblem(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		if err := plan.validate(s); err != nil {
+			writeProblem(w, http.StatusBadRequest, err)
+			return
+		}
+
+		raw, _ := json.Marshal(plan)
+
+		uuid, err := planned.CreatePlanned(string(raw))
+		if err != nil {
+			writeProblem(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		created, err := getPlannedMatch(uuid)
+		if err != nil {
+			writeStoreProblem(w, err)
+			return
+		}
+
+		writeCreated(w, created)
+		return
+	}
+
+	upcoming, err := getUpcomingMatches()
+	if err != nil {
+		writeProblem(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, upcoming)
+}
+
+func handleUpcoming(w http.ResponseWriter, r *http.Request) {
+	if strings.TrimSpace(r.URL.EscapedPath()) != PATH_UPCOMING {
+		http.NotFound(w, r)
+		return
+	}
+
+	t, ok := templates["upcoming.html"]
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	upcoming, err := getUpcomingMatches()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	if err := t.Execute(w, UpcomingPage{Matches: upcoming}); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"score/src/schedule"
+	"strings"
+	"testing"
+)
+
+func TestPlanned(t *testing.T) {
+	setup(t)
+
+	token := strings.Repeat("a", TOKEN_LENGTH)
+
+	if _, err := updateSchedule(func(s *schedule.Schedule) error {
+		s.AddCourt("Court 1")
+		_, err := s.AddCourt("Court 2")
+		return err
+	}); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	var later, first APIPlannedMatch
+	body := `{"mode": 21, "court": 2, "start": 1679691600, "team1": [{"player": "Viktor AXELSEN", "country": "DK"}], "team2": [{"player": "LEE Zii Jia", "country": "MY"}]}`
+	if code := jsonRequest(t, handlePlannedAPI, http.MethodPost, PATH_API_PLANNED, body, &later); code != http.StatusCreated || later.Claimed || later.Court != 2 || later.CourtName != "Court 2" {
+		t.Fatalf("Got status %d and %+v, want %d", code, later, http.StatusCreated)
+	}
+
+	body = `{"mode": 21, "court": 1, "start": 1679684400, "team1": [{"player": "Viktor AXELSEN", "country": "DK"}], "team2": [{"player": "CHOU Tien Chen", "country": "TW"}]}`
+	if code := jsonRequest(t, handlePlannedAPI, http.MethodPost, PATH_API_PLANNED, body, &first); code != http.StatusCreated {
+		t.Fatalf("Got status %d, want %d", code, http.StatusCreated)
+	}
+
+	for _, body := range []string{
+		// no start
+		`{"mode": 21, "team1": [{"player": "Viktor AXELSEN", "country": "DK"}], "team2": [{"player": "CHOU Tien Chen", "country": "TW"}]}`,
+		// singles against doubles
+		`{"mode": 21, "start": 1679684400, "team1": [{"player": "Viktor AXELSEN", "country": "DK"}], "team2": [{"player": "CHOU Tien Chen", "country": "TW"}, {"player": "LEE Zii Jia", "country": "MY"}]}`,
+		// unknown mode
+		`{"mode": 13, "start": 1679684400, "team1": [{"player": "Viktor AXELSEN", "country": "DK"}], "team2": [{"player": "CHOU Tien Chen", "country": "TW"}]}`,
+		// unknown court
+		`{"mode": 21, "court": 3, "start": 1679684400, "team1": [{"player": "Viktor AXELSEN", "country": "DK"}], "team2": [{"player": "CHOU Tien Chen", "country": "TW"}]}`,
+	} {
+		var match APIPlannedMatch
+		if code := jsonRequest(t, handlePlannedAPI, http.MethodPost, PATH_API_PLANNED, body, &match); code != http.StatusBadRequest {
+			t.Errorf("Got status %d for %s, want %d", code, body, http.StatusBadRequest)
+		}
+	}
+
+	var upcoming []APIPlannedMatch
+	if code := jsonRequest(t, handlePlannedAPI, http.MethodGet, PATH_API_PLANNED, "", &upcoming); code != http.StatusOK || len(upcoming) != 2 || upcoming[0].UUID != first.UUID {
+		t.Fatalf("Got status %d and %+v, want earliest match first", code, upcoming)
+	}
+
+	w := httptest.NewRecorder()
+	handleUpcoming(w, httptest.NewRequest(http.MethodGet, PATH_UPCOMING, nil))
+
+	for _, want := range []string{"Court 1", "LEE Zii Jia", "/c/?planned=" + first.UUID} {
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("Expected %q on upcoming page", want)
+		}
+	}
+
+	w = httptest.NewRecorder()
+	handleClient(w, httptest.NewRequest(http.MethodGet, PATH_CLIENT+"?planned="+first.UUID, nil))
+
+	if w.Code != http.StatusOK || !regexp.MustCompile(`const PLANNED = \s*"`+first.UUID+`"`).MatchString(w.Body.String()) {
+		t.Errorf("Got status %d, want client for planned match", w.Code)
+	}
+
+	w = apiRequest(token, `{"action": "new", "planned": "`+first.UUID+`"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Got status %d, want %d: %s", w.Code, http.StatusCreated, w.Body.String())
+	}
+
+	var response APIResponseData
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil || response.Match != first.UUID {
+		t.Fatalf("Got %+v (%v), want planned match", response, err)
+	}
+
+	// a planned match is only scored by one client
+	if w := apiRequest(strings.Repeat("b", TOKEN_LENGTH), `{"action": "new", "planned": "`+first.UUID+`"}`); w.Code != http.StatusConflict {
+		t.Errorf("Got status %d, want %d", w.Code, http.StatusConflict)
+	}
+
+	if w := apiRequest(token, `{"action": "new", "planned": "unknown"}`); w.Code != http.StatusNotFound {
+		t.Errorf("Got status %d, want %d", w.Code, http.StatusNotFound)
+	}
+
+	if code := jsonRequest(t, handlePlannedAPI, http.MethodGet, PATH_API_PLANNED+"/"+first.UUID, "", &first); code != http.StatusOK || !first.Claimed || first.Started {
+		t.Errorf("Got status %d and %+v, want claimed match", code, first)
+	}
+
+	// the match is unclaimed, e.g. if it was claimed on the wrong device
+	if code := jsonRequest(t, handlePlannedAPI, http.MethodPost, PATH_API_PLANNED+"/"+first.UUID+"/unclaim", "", &first); code != http.StatusOK || first.Claimed {
+		t.Fatalf("Got status %d and %+v, want unclaimed match", code, first)
+	}
+
+	if code := jsonRequest(t, handlePlannedAPI, http.MethodPost, PATH_API_PLANNED+"/"+first.UUID+"/unclaim", "", &first); code != http.StatusBadRequest {
+		t.Errorf("Got status %d, want %d", code, http.StatusBadRequest)
+	}
+
+	if code := jsonRequest(t, handlePlannedAPI, http.MethodPost, PATH_API_PLANNED+"/unknown/unclaim", "", &first); code != http.StatusNotFound {
+		t.Errorf("Got status %d, want %d", code, http.StatusNotFound)
+	}
+
+	if w := apiRequest(token, `{"action": "new", "planned": "`+first.UUID+`"}`); w.Code != http.StatusCreated {
+		t.Fatalf("Got status %d, want %d", w.Code, http.StatusCreated)
+	}
+
+	// the planned teams play the match
+	w = apiRequest(token, `{"action": "update", "match": "`+first.UUID+`", "data": `+apiTestMatch("Viktor AXELSEN", "LEE Zii Jia", 1679684400, false)+`}`)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Got status %d, want %d", w.Code, http.StatusBadRequest)
+	}
+
+	w = apiRequest(token, `{"action": "update", "match": "`+first.UUID+`", "data": `+apiTestMatch("Viktor AXELSEN", "CHOU Tien Chen", 1679684400, false)+`}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Got status %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+
+	// started matches cannot be unclaimed
+	if code := jsonRequest(t, handlePlannedAPI, http.MethodPost, PATH_API_PLANNED+"/"+first.UUID+"/unclaim", "", &first); code != http.StatusBadRequest {
+		t.Errorf("Got status %d, want %d", code, http.StatusBadRequest)
+	}
+
+	// started matches are not upcoming anymore
+	upcoming = nil
+	if code := jsonRequest(t, handlePlannedAPI, http.MethodGet, PATH_API_PLANNED, "", &upcoming); code != http.StatusOK || len(upcoming) != 1 || upcoming[0].UUID != later.UUID {
+		t.Errorf("Got status %d and %+v, want only the later match", code, upcoming)
+	}
+}
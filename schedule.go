@@ -0,0 +1,510 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"score/src/parser"
+	"score/src/schedule"
+	"score/src/store"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	PATH_API_SCHEDULE = "/api/schedule"
+	PATH_COURTS       = "/courts/"
+
+	// interval in which queued matches are called to free courts, e.g. once
+	// their players have rested
+	SCHEDULER_INTERVAL = time.Minute
+
+	ERR_SCHEDULED_TEAMS_MISMATCH = "teams do not match the teams of the scheduled match"
+)
+
+var (
+	schedules store.ScheduleStore
+	// the schedule is read, changed and written as a whole
+	scheduleMutex sync.Mutex
+)
+
+// Scheduled match as returned by the API.
+type APIScheduledMatch struct {
+	schedule.Item
+	// expected start of queued matches
+	Expected int64 `json:"expected,omitempty"`
+}
+
+type APISchedule struct {
+	Courts  []schedule.Court    `json:"courts"`
+	Matches []APIScheduledMatch `json:"matches"`
+	Rest    int                 `json:"rest"`
+}
+
+// Court as shown on the court call page.
+type CourtCall struct {
+	schedule.Court
+	// nil if the court is free
+	Item *schedule.Item
+	// false until the first update of the scoring match
+	Started bool
+	Live    parser.Match
+	// client that scores the match, empty once it is scored
+	ClientURL string
+}
+
+type QueuedMatch struct {
+	schedule.Item
+	Expected time.Time
+}
+
+type CourtsPage struct {
+	Courts []CourtCall
+	Queue  []QueuedMatch
+}
+
+func loadSchedule() (schedule.Schedule, error) {
+	var s schedule.Schedule
+
+	data, err := schedules.GetSchedule()
+	if errors.Is(err, store.ErrNotFound) {
+		return schedule.New(), nil
+	} else if err != nil {
+		return s, err
+	}
+
+	err = json.Unmarshal([]byte(data), &s)
+	return s, err
+}
+
+func saveSchedule(s schedule.Schedule) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	return schedules.SaveSchedule(string(data))
+}
+
+// Changes the schedule, calls queued matches to free courts and saves it.
+func updateSchedule(change func(s *schedule.Schedule) error) (schedule.Schedule, error) {
+	scheduleMutex.Lock()
+	defer scheduleMutex.Unlock()
+
+	s, err := loadSchedule()
+	if err != nil {
+		return s, err
+	}
+
+	if err := change(&s); err != nil {
+		return s, err
+	}
+
+	s.Assign(time.Now())
+
+	return s, saveSchedule(s)
+}
+
+// Calls queued matches to free courts. The schedule is only saved if a
+// match was called.
+func assignCourts(now time.Time) error {
+	scheduleMutex.Lock()
+	defer scheduleMutex.Unlock()
+
+	s, err := loadSchedule()
+	if err != nil {
+		return err
+	}
+
+	if called := s.Assign(now); len(called) == 0 {
+		return nil
+	}
+
+	return saveSchedule(s)
+}
+
+func runScheduler() {
+	for range time.Tick(SCHEDULER_INTERVAL) {
+		if err := assignCourts(time.Now()); err != nil {
+			log.Printf("Could not assign courts: %s\n", err)
+		}
+	}
+}
+
+// Checks that a match linked to a scheduled match is played by its teams.
+// Returns nil if the match is not linked.
+func checkScheduledMatch(uuid string, m parser.Match) error {
+	scheduleMutex.Lock()
+	defer scheduleMutex.Unlock()
+
+	s, err := loadSchedule()
+	if err != nil {
+		return err
+	}
+
+	item, ok := s.ItemOf(uuid)
+	if !ok {
+		return nil
+	}
+
+	if !sameTeam(m.Info.Team1, item.Team1) || !sameTeam(m.Info.Team2, item.Team2) {
+		return errors.New(ERR_SCHEDULED_TEAMS_MISMATCH)
+	}
+
+	return nil
+}
+
+// Frees the court of the scheduled match that is scored as the given match,
+// once it has a winner, and calls the next match.
+func finishScheduledMatch(uuid string, m parser.Match) error {
+	if m.Winner == parser.Unknown {
+		return nil
+	}
+
+	scheduleMutex.Lock()
+	defer scheduleMutex.Unlock()
+
+	s, err := loadSchedule()
+	if err != nil {
+		return err
+	}
+
+	item, ok := s.ItemOf(uuid)
+	if !ok || !item.Finished.IsZero() {
+		return nil
+	}
+
+	now := time.Now()
+
+	if err := s.Finish(item.ID, now); err != nil {
+		return err
+	}
+
+	s.Assign(now)
+
+	return saveSchedule(s)
+}
+
+// Returns the estimated duration of a match of the given mode, from the
+// duration of finished matches.
+func estimateDuration(mode parser.Mode) int {
+	average, err := matches.AverageDuration(int(mode))
+	if err != nil {
+		return schedule.DEFAULT_ESTIMATE
+	}
+
+	return schedule.Estimate(average)
+}
+
+func newAPISchedule(s schedule.Schedule, now time.Time) APISchedule {
+	forecast := s.Forecast(now)
+
+	api := APISchedule{
+		Courts:  s.Courts,
+		Matches: []APIScheduledMatch{},
+		Rest:    s.Rest,
+	}
+
+	for _, item := range s.Items {
+		match := APIScheduledMatch{Item: item}
+
+		if expected, ok := forecast[item.ID]; ok {
+			match.Expected = expected.Unix()
+		}
+
+		api.Matches = append(api.Matches, match)
+	}
+
+	return api
+}
+
+// Creates a match for a scheduled match that has been called, see
+// createMatch.
+func createScheduledMatch(token string, id int) (string, error) {
+	scheduleMutex.Lock()
+	defer scheduleMutex.Unlock()
+
+	s, err := loadSchedule()
+	if err != nil {
+		return "", err
+	}
+
+	uuid, err := createMatch(token)
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.Link(id, uuid); err != nil {
+		matches.Delete(uuid)
+		return "", err
+	}
+
+	if err := saveSchedule(s); err != nil {
+		return "", err
+	}
+
+	publishLink(APILink{Match: uuid, Scheduled: id, Linked: true})
+
+	return uuid, nil
+}
+
+// Unlinks the match of a called match that has not been started, see
+// unlinkMatch.
+func unlinkScheduledMatch(id int) (schedule.Schedule, error) {
+	drawMutex.Lock()
+	defer drawMutex.Unlock()
+
+	scheduleMutex.Lock()
+	defer scheduleMutex.Unlock()
+
+	s, err := loadSchedule()
+	if err != nil {
+		return s, err
+	}
+
+	uuid, err := s.Unlink(id)
+	if err != nil {
+		return s, err
+	}
+
+	if err := unlinkMatch(uuid, func() error { return saveSchedule(s) }); err != nil {
+		return s, err
+	}
+
+	publishLink(APILink{Match: uuid, Scheduled: id})
+
+	return s, nil
+}
+
+func getCourtsPage(now time.Time) (CourtsPage, error) {
+	s, err := loadSchedule()
+	if err != nil {
+		return CourtsPage{}, err
+	}
+
+	var page CourtsPage
+
+	for _, court := range s.Courts {
+		call := CourtCall{Court: court}
+
+		if court.Item != 0 {
+			item, _ := s.Item(court.Item)
+			call.Item = item
+
+			if item.Match == "" {
+				call.ClientURL = PATH_CLIENT + "?scheduled=" + strconv.Itoa(item.ID)
+			} else if m, ok := getStartedMatches([]string{item.Match})[item.Match]; ok {
+				call.Started = true
+				call.Live = m
+			}
+		}
+
+		page.Courts = append(page.Courts, call)
+	}
+
+	forecast := s.Forecast(now)
+
+	for _, item := range s.Items {
+		if item.Queued() {
+			page.Queue = append(page.Queue, QueuedMatch{
+				Item:     item,
+				Expected: forecast[item.ID],
+			})
+		}
+	}
+
+	return page, nil
+}
+
+// Fills in the teams of a scheduled match for the client, e.g.
+// /c/?scheduled=1
+func prefillScheduledClient(data *ClientData, query url.Values) error {
+	id, err := strconv.Atoi(query.Get("scheduled"))
+	if err != nil {
+		return errors.New(schedule.ERR_UNKNOWN_MATCH)
+	}
+
+	s, err := loadSchedule()
+	if err != nil {
+		return err
+	}
+
+	item, err := s.Item(id)
+	if err != nil {
+		return err
+	}
+
+	data.Scheduled = &item.ID
+	data.Team1 = item.Team1
+	data.Team2 = item.Team2
+	data.Doubles = item.Category.Players() == 2
+	data.DefaultMode = item.Mode
+
+	return nil
+}
+
+// Handles the schedule API:
+//
+//	GET  /api/schedule
+//	POST /api/schedule/courts
+//	POST /api/schedule/matches
+//	POST /api/schedule/matches/{id}/finish
+//	POST /api/schedule/matches/{id}/unlink
+//	POST /api/schedule/rest
+func handleScheduleAPI(w http.ResponseWriter, r *http.Request) {
+	var path []string
+	if rest := strings.Trim(strings.TrimPrefix(r.URL.Path, PATH_API_SCHEDULE), "/"); rest != "" {
+		path = strings.Split(rest, "/")
+	}
+
+	switch {
+	case len(path) == 0:
+	case len(path) == 1 && (path[0] == "courts" || path[0] == "matches" || path[0] == "rest"):
+	case len(path) == 3 && path[0] == "matches" && (path[2] == "finish" || path[2] == "unlink"):
+	default:
+		writeProblem(w, http.StatusNotFound, nil)
+		return
+	}
+
+	// the schedule is changed by POST, everything else is read-only
+	method := http.MethodPost
+	if len(path) == 0 {
+		method = http.MethodGet
+	}
+
+	if r.Method != method {
+		writeProblem(w, http.StatusMethodNotAllowed, nil)
+		return
+	}
+
+	if !authorizeWrite(w, r) {
+		return
+	}
+
+	var created any
+
+	change := func(s *schedule.Schedule) error {
+		switch {
+		case len(path) == 0:
+			return nil
+		case path[0] == "courts":
+			var data struct {
+				Name string `json:"name"`
+			}
+
+			if err := readJSON(r, &data); err != nil {
+				return err
+			}
+
+			court, err := s.AddCourt(data.Name)
+			created = court
+
+			return err
+		case path[0] == "rest":
+			var data struct {
+				Minutes int `json:"minutes"`
+			}
+
+			if err := readJSON(r, &data); err != nil || data.Minutes < 0 {
+				return errors.New("rest must be a number of minutes")
+			}
+
+			s.Rest = data.Minutes
+
+			return nil
+		case len(path) == 3:
+			id, err := strconv.Atoi(path[1])
+			if err != nil {
+				return errors.New(schedule.ERR_UNKNOWN_MATCH)
+			}
+
+			return s.Finish(id, time.Now())
+		default:
+			var item schedule.Item
+
+			if err := readJSON(r, &item); err != nil {
+				return err
+			}
+
+			if item.Estimate <= 0 {
+				item.Estimate = estimateDuration(item.Mode)
+			}
+
+			item, err := s.Add(item)
+			created = item
+
+			return err
+		}
+	}
+
+	var s schedule.Schedule
+	var err error
+
+	// errors of the request are told apart from errors of the store
+	status := http.StatusInternalServerError
+
+	if len(path) == 0 {
+		s, err = loadSchedule()
+	} else if len(path) == 3 && path[2] == "unlink" {
+		id, _ := strconv.Atoi(path[1])
+		s, err = unlinkScheduledMatch(id)
+		status = http.StatusBadRequest
+	} else {
+		s, err = updateSchedule(func(s *schedule.Schedule) error {
+			if err := change(s); err != nil {
+				status = http.StatusBadRequest
+				return err
+			}
+
+			return nil
+		})
+	}
+
+	if err != nil {
+		writeProblem(w, status, err)
+		return
+	}
+
+	// a new match may have been called right away
+	if item, ok := created.(schedule.Item); ok {
+		if called, err := s.Item(item.ID); err == nil {
+			created = *called
+		}
+	}
+
+	if created != nil {
+		writeCreated(w, created)
+		return
+	}
+
+	writeJSON(w, newAPISchedule(s, time.Now()))
+}
+
+func handleCourts(w http.ResponseWriter, r *http.Request) {
+	if strings.TrimSpace(r.URL.EscapedPath()) != PATH_COURTS {
+		http.NotFound(w, r)
+		return
+	}
+
+	t, ok := templates["courts.html"]
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	page, err := getCourtsPage(time.Now())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	if err := t.Execute(w, page); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
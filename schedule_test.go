@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestSchedule(t *testing.T) {
+	setup(t)
+
+	token := strings.Repeat("a", TOKEN_LENGTH)
+
+	var s APISchedule
+	if code := jsonRequest(t, handleScheduleAPI, http.MethodGet, PATH_API_SCHEDULE, "", &s); code != http.StatusOK || len(s.Courts) != 0 || s.Rest == 0 {
+		t.Fatalf("Got status %d and %+v, want empty schedule", code, s)
+	}
+
+	var court struct {
+		ID int `json:"id"`
+	}
+	if code := jsonRequest(t, handleScheduleAPI, http.MethodPost, PATH_API_SCHEDULE+"/courts", `{"name": "Court 1"}`, &court); code != http.StatusCreated || court.ID != 1 {
+		t.Fatalf("Got status %d and court %d, want %d", code, court.ID, http.StatusCreated)
+	}
+
+	if code := jsonRequest(t, handleScheduleAPI, http.MethodPost, PATH_API_SCHEDULE+"/courts", `{"name": " "}`, &court); code != http.StatusBadRequest {
+		t.Errorf("Got status %d, want %d", code, http.StatusBadRequest)
+	}
+
+	var item struct {
+		ID       int `json:"id"`
+		Court    int `json:"court"`
+		Estimate int `json:"estimate"`
+	}
+	body := `{"category": "MS", "mode": 21, "team1": [{"player": "Viktor AXELSEN", "country": "DK"}], "team2": [{"player": "CHOU Tien Chen", "country": "TW"}]}`
+	if code := jsonRequest(t, handleScheduleAPI, http.MethodPost, PATH_API_SCHEDULE+"/matches", body, &item); code != http.StatusCreated || item.Estimate == 0 {
+		t.Fatalf("Got status %d and %+v, want %d", code, item, http.StatusCreated)
+	}
+
+	body = `{"category": "MS", "mode": 21, "team1": [{"player": "Viktor AXELSEN", "country": "DK"}], "team2": [{"player": "LEE Zii Jia", "country": "MY"}]}`
+	if code := jsonRequest(t, handleScheduleAPI, http.MethodPost, PATH_API_SCHEDULE+"/matches", body, &item); code != http.StatusCreated {
+		t.Fatalf("Got status %d, want %d", code, http.StatusCreated)
+	}
+
+	if code := jsonRequest(t, handleScheduleAPI, http.MethodPost, PATH_API_SCHEDULE+"/matches", `{"category": "MD", "mode": 21}`, &item); code != http.StatusBadRequest {
+		t.Errorf("Got status %d, want %d", code, http.StatusBadRequest)
+	}
+
+	// the first match is called right away, the second has to wait
+	jsonRequest(t, handleScheduleAPI, http.MethodGet, PATH_API_SCHEDULE, "", &s)
+	if len(s.Matches) != 2 || s.Matches[0].Court != 1 || s.Matches[1].Court != 0 || s.Matches[1].Expected == 0 {
+		t.Fatalf("Got %+v, want first match on court 1", s.Matches)
+	}
+
+	w := httptest.NewRecorder()
+	handleClient(w, httptest.NewRequest(http.MethodGet, PATH_CLIENT+"?scheduled=1", nil))
+
+	if w.Code != http.StatusOK || !regexp.MustCompile(`const SCHEDULED = \s*1`).MatchString(w.Body.String()) {
+		t.Errorf("Got status %d, want client for scheduled match", w.Code)
+	}
+
+	// only called matches are scored
+	if w := apiRequest(token, `{"action": "new", "scheduled": 2}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Got status %d, want %d", w.Code, http.StatusBadRequest)
+	}
+
+	w = apiRequest(token, `{"action": "new", "scheduled": 1}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Got status %d, want %d: %s", w.Code, http.StatusCreated, w.Body.String())
+	}
+
+	var response APIResponseData
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// the match is unlinked, e.g. if it was started on the wrong device
+	if code := jsonRequest(t, handleScheduleAPI, http.MethodPost, PATH_API_SCHEDULE+"/matches/1/unlink", "", &s); code != http.StatusOK || s.Matches[0].Match != "" || s.Matches[0].Court != 1 {
+		t.Fatalf("Got status %d and %+v, want unlinked match on court 1", code, s.Matches[0])
+	}
+
+	if code := jsonRequest(t, handleScheduleAPI, http.MethodPost, PATH_API_SCHEDULE+"/matches/1/unlink", "", &s); code != http.StatusBadRequest {
+		t.Errorf("Got status %d, want %d", code, http.StatusBadRequest)
+	}
+
+	w = apiRequest(token, `{"action": "new", "scheduled": 1}`)
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	w = httptest.NewRecorder()
+	handleCourts(w, httptest.NewRequest(http.MethodGet, PATH_COURTS, nil))
+
+	for _, want := range []string{"Court 1", "/m/" + response.Match, "Order of play", "LEE Zii Jia"} {
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("Expected %q on courts page", want)
+		}
+	}
+
+	// the match is played by the teams of the scheduled match
+	w = apiRequest(token, `{"action": "update", "match": "`+response.Match+`", "data": `+apiTestMatch("Viktor AXELSEN", "LEE Zii Jia", 1679684400, false)+`}`)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Got status %d, want %d", w.Code, http.StatusBadRequest)
+	}
+
+	w = apiRequest(token, `{"action": "update", "match": "`+response.Match+`", "data": `+apiTestMatch("Viktor AXELSEN", "CHOU Tien Chen", 1679684400, true)+`}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Got status %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+
+	// the court is free, but Viktor AXELSEN has to rest first
+	s = APISchedule{}
+	jsonRequest(t, handleScheduleAPI, http.MethodGet, PATH_API_SCHEDULE, "", &s)
+	if s.Matches[0].Finished.IsZero() || s.Courts[0].Item != 0 || s.Matches[1].Court != 0 {
+		t.Fatalf("Got %+v, want first match finished and court free", s)
+	}
+
+	s = APISchedule{}
+	if code := jsonRequest(t, handleScheduleAPI, http.MethodPost, PATH_API_SCHEDULE+"/rest", `{"minutes": 0}`, &s); code != http.StatusOK || s.Rest != 0 || s.Matches[1].Court != 1 {
+		t.Errorf("Got status %d and %+v, want second match on court 1", code, s)
+	}
+
+	if code := jsonRequest(t, handleScheduleAPI, http.MethodPost, PATH_API_SCHEDULE+"/matches/2/finish", "", &s); code != http.StatusOK || s.Matches[1].Finished.IsZero() {
+		t.Errorf("Got status %d and %+v, want second match finished", code, s)
+	}
+
+	if code := jsonRequest(t, handleScheduleAPI, http.MethodPost, PATH_API_SCHEDULE+"/matches/2/finish", "", &s); code != http.StatusBadRequest {
+		t.Errorf("Got status %d, want %d", code, http.StatusBadRequest)
+	}
+
+	if code := jsonRequest(t, handleScheduleAPI, http.MethodDelete, PATH_API_SCHEDULE, "", &s); code != http.StatusMethodNotAllowed {
+		t.Errorf("Got status %d, want %d", code, http.StatusMethodNotAllowed)
+	}
+	// a schedule that cannot be loaded is not the fault of the request
+	if err := schedules.SaveSchedule("{"); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if code := jsonRequest(t, handleScheduleAPI, http.MethodPost, PATH_API_SCHEDULE+"/rest", `{"minutes": 10}`, &s); code != http.StatusInternalServerError {
+		t.Errorf("Got status %d, want %d", code, http.StatusInternalServerError)
+	}
+}
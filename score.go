@@ -1,29 +1,38 @@
 package main
 
 import (
-	"database/sql"
 	"embed"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"html/template"
 	"io"
 	"io/fs"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
+	"regexp"
+	"score/src/auth"
 	"score/src/parser"
+	"score/src/store"
 	"strings"
+	"time"
 
 	"github.com/biter777/countries"
-	"github.com/google/uuid"
-	_ "github.com/mattn/go-sqlite3"
 	"github.com/thanhpk/randstr"
 )
 
 const (
-	PATH_API    = "/api/"
-	PATH_CLIENT = "/c/"
-	PATH_INDEX  = "/"
+	PATH_API         = "/api/"
+	PATH_API_MATCHES = "/api/matches"
+	PATH_CLIENT      = "/c/"
+	PATH_MATCH       = "/m/"
+	PATH_OVERLAY     = "/overlay/"
+	PATH_REPLAY      = "/r/"
+	PATH_INDEX       = "/"
+	// scripts shared by the templates, see static/
+	PATH_STATIC = "/static/"
 
 	ACTION_NEW    = "new"
 	ACTION_UPDATE = "update"
@@ -31,6 +40,9 @@ const (
 	// name of SQLite database
 	DEFAULT_DB_PATH = "score.sqlite"
 
+	// default mode selected in the client
+	DEFAULT_MODE = parser.Mode21
+
 	// path to templates
 	TEMPLATES = "tpl"
 
@@ -38,18 +50,32 @@ const (
 	COOKIE_NAME = "token"
 	// length of cookie value
 	TOKEN_LENGTH = 64
+
+	ERR_MATCH_STARTED = "match has already been started"
 )
 
 var (
-	//go:embed tpl/*
+	//go:embed tpl/* static/*
 	files     embed.FS
 	templates map[string]*template.Template
-	database  string
+	matches   store.MatchStore
+	// public URL of the server, e.g. https://score.example.com, which links
+	// shared on other sites start with
+	baseURL string
 )
 
 type APIRequestData struct {
 	Action string `json:"action"`
 	Match  string `json:"match"`
+	// position of a draw, rubber of a tie or scheduled match that a new
+	// match is played at, if any
+	Draw      *DrawLink `json:"draw,omitempty"`
+	Tie       *TieLink  `json:"tie,omitempty"`
+	Scheduled *int      `json:"scheduled,omitempty"`
+	// uuid of a planned match that is claimed instead of creating a match
+	Planned string `json:"planned,omitempty"`
+	// handoff code of a match that is continued, see ACTION_HANDOFF
+	Code string `json:"code,omitempty"`
 	// match data is nested JSON, but must not be decoded automatically
 	Data map[string]any `json:"data"`
 }
@@ -58,6 +84,90 @@ type APIResponseData struct {
 	Match string `json:"match"`
 }
 
+// Problem details for HTTP APIs, see RFC 7807. If the problem was caused by
+// an invalid match, the location of the error is included.
+type APIProblem struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail,omitempty"`
+	*parser.ValidationError
+}
+
+// Match as shown in templates, together with its uuid.
+type MatchData struct {
+	UUID string
+	parser.Match
+}
+
+type MatchPage struct {
+	UUID string
+	// absolute URL of the page, e.g. for OpenGraph tags
+	URL         string
+	Description string
+	// false until the first update of the match
+	Started bool
+	Match   parser.Match
+}
+
+// OverlayOptions are set by query parameters of the overlay, e.g.
+// /overlay/{uuid}?layout=corner&team1=ffa824&background=00000000
+type OverlayOptions struct {
+	// lower-third or corner
+	Layout string
+	// colours as hex RGB or RGBA values without "#"
+	Team1      string
+	Team2      string
+	Background string
+	Text       string
+}
+
+type OverlayPage struct {
+	UUID    string
+	Options OverlayOptions
+	Started bool
+	Match   parser.Match
+	// score of the current game and games won
+	ScoreTeam1 int
+	ScoreTeam2 int
+	GamesTeam1 int
+	GamesTeam2 int
+	// team serving the next rally, Unknown if not known
+	Serving parser.TeamID
+}
+
+type ReplayRevision struct {
+	store.Revision
+	Match parser.Match
+	Err   error
+	// true if points were removed since the previous revision
+	Undo bool
+}
+
+type ReplayData struct {
+	UUID      string
+	Revisions []ReplayRevision
+}
+
+type ClientData struct {
+	Rules       []parser.RuleSet
+	DefaultMode parser.Mode
+	// set if the client scores a match of a draw, whose teams are known,
+	// a rubber of a tie, a scheduled match or a planned match
+	Draw      *DrawLink
+	Tie       *TieLink
+	Scheduled *int
+	Planned   string
+	// handoff code of a match that the client continues
+	Handoff string
+	Team1   parser.Team
+	Team2   parser.Team
+	Doubles bool
+	// clubs of a tie, which play as team 1 and team 2 of the rubber
+	Club1 string
+	Club2 string
+}
+
 func initTemplates() error {
 	if templates == nil {
 		templates = make(map[string]*template.Template)
@@ -75,6 +185,45 @@ func initTemplates() error {
 		"flag": func(country parser.Country) string {
 			return countries.ByName(string(country)).Emoji()
 		},
+		// names of the players of a team, without flags
+		"players": func(team parser.Team) string {
+			var names []string
+
+			for _, player := range team {
+				names = append(names, string(player.Player))
+			}
+
+			return strings.Join(names, " / ")
+		},
+		// points of a polyline showing the win probability of Team1 after each
+		// rally, starting at 50% and scaled to the given size
+		"probability": func(m parser.Match, width int, height int) string {
+			probabilities := parser.WinProbability(m)
+			points := []string{fmt.Sprintf("0,%.1f", float64(height)/2)}
+
+			for i, p := range probabilities {
+				x := float64(width) * float64(i+1) / float64(len(probabilities))
+				y := float64(height) * (1 - p.Team1Match)
+
+				points = append(points, fmt.Sprintf("%.1f,%.1f", x, y))
+			}
+
+			return strings.Join(points, " ")
+		},
+		"event": func(t parser.EventType) string {
+			switch t {
+			case parser.EventYellowCard:
+				return "🟨"
+			case parser.EventRedCard:
+				return "🟥"
+			case parser.EventBlackCard:
+				return "⬛"
+			case parser.EventInjury, parser.EventMedical:
+				return "⛑️"
+			default:
+				return ""
+			}
+		},
 	}
 
 	for _, tpl := range entries {
@@ -93,114 +242,343 @@ func initTemplates() error {
 	return nil
 }
 
-func initDatabase() error {
-	db, err := sql.Open("sqlite3", database)
+// Registers custom rule sets from a JSON file containing a list of rule sets,
+// e.g. [{"mode": 1021, "name": "21 Pts., 1 game", "winPoints": 21, ...}]
+func initRules(path string) error {
+	data, err := os.ReadFile(path)
 	if err != nil {
 		return err
 	}
 
-	defer db.Close()
+	var rules []parser.RuleSet
 
-	stmt := `
-		CREATE TABLE IF NOT EXISTS matches (
-			uuid     TEXT NOT NULL PRIMARY KEY,
-			token    TEXT,
-			json     TEXT,
-			modified DATETIME DEFAULT CURRENT_TIMESTAMP
-		);
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return err
+	}
+
+	for _, r := range rules {
+		if err := parser.Register(r); err != nil {
+			return fmt.Errorf("mode %d: %s", r.Mode, err)
+		}
+	}
 
-		CREATE TRIGGER IF NOT EXISTS update_modified AFTER UPDATE ON matches
-		BEGIN
-			UPDATE matches SET modified = datetime('now') WHERE json = NEW.json;
-		END;
-	`
+	return nil
+}
 
-	if _, err := db.Exec(stmt); err != nil {
+func initDatabase(path string) error {
+	s, err := store.NewSQLite(path)
+	if err != nil {
 		return err
 	}
 
+	matches = s
+	tournaments = s
+	ties = s
+	schedules = s
+	planned = s
+	handoffs = s
+	users = s
+	audits = s
+
 	return nil
 }
 
 func createMatch(token string) (string, error) {
-	if len(token) == 0 {
-		return "", errors.New("empty token")
-	}
+	return matches.Create(token)
+}
 
-	uuid, err := uuid.NewRandom()
-	if err != nil {
-		return "", errors.New("cannot generate match uuid")
+// Unlinks a match that has not been started yet from its draw, tie or
+// scheduled match. unlink removes and saves the link, then the match is
+// deleted, so that its scorer cannot start it anymore. drawMutex must be
+// held, as matches are only updated while it is held.
+func unlinkMatch(uuid string, unlink func() error) error {
+	record, err := matches.Get(uuid)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		return err
 	}
 
-	db, err := sql.Open("sqlite3", database)
-	if err != nil {
-		return "", errors.New("cannot open database")
+	if len(record.JSON) != 0 {
+		return errors.New(ERR_MATCH_STARTED)
 	}
 
-	defer db.Close()
+	if err := unlink(); err != nil {
+		return err
+	}
 
-	if _, err := db.Exec("INSERT INTO matches (uuid, token) VALUES (?, ?)", uuid.String(), token); err != nil {
-		return "", errors.New("cannot create match")
+	if err := matches.Delete(uuid); err != nil && !errors.Is(err, store.ErrNotFound) {
+		return err
 	}
 
-	return uuid.String(), nil
+	return nil
 }
 
 func updateMatch(raw string, m parser.Match, uuid string, token string) error {
-	db, err := sql.Open("sqlite3", database)
+	drawMutex.Lock()
+	defer drawMutex.Unlock()
+
+	// the winner of a match of a draw advances to the next round
+	draw, err := advanceDraw(uuid, m)
 	if err != nil {
-		return errors.New("cannot open database")
+		return err
+	}
+
+	// the players of a rubber must fit its category
+	if err := checkRubber(uuid, m); err != nil {
+		return err
+	}
+
+	// as must the teams of a scheduled match
+	if err := checkScheduledMatch(uuid, m); err != nil {
+		return err
 	}
 
-	defer db.Close()
+	// and the teams and mode of a planned match
+	if err := checkPlannedMatch(uuid, m); err != nil {
+		return err
+	}
 
-	newToken := sql.NullString{
-		String: token,
-		Valid:  true,
+	// a finished match cannot be updated anymore
+	if err := matches.Update(uuid, token, raw, m.Finished); err != nil {
+		return err
 	}
 
-	if m.Winner != parser.Unknown {
-		// match is finished, delete token
-		newToken.Valid = false
+	if draw != nil {
+		if err := saveDraw(*draw); err != nil {
+			return err
+		}
 	}
 
-	if _, err := db.Exec("UPDATE matches SET json = ?, token = ? WHERE uuid = ? AND token = ?",
-		raw, newToken, uuid, token); err != nil {
-		return errors.New("cannot update match")
+	publishMatch(newAPIMatch(uuid, time.Now(), m))
+
+	// the court of a finished scheduled match is free for the next one
+	if err := finishScheduledMatch(uuid, m); err != nil {
+		log.Printf("Could not finish scheduled match %s: %s\n", uuid, err)
 	}
 
 	return nil
 }
 
-func getRecentMatches() ([]parser.Match, error) {
-	var matches []parser.Match
-
-	db, err := sql.Open("sqlite3", database)
+// Returns a match that has been started.
+func getMatchData(uuid string) (MatchData, error) {
+	record, err := matches.Get(uuid)
 	if err != nil {
-		return matches, err
+		return MatchData{}, err
 	}
 
-	defer db.Close()
+	if len(record.JSON) == 0 {
+		return MatchData{}, store.ErrNotFound
+	}
 
-	rows, err := db.Query("SELECT json FROM matches WHERE modified >= datetime('now', '-1 day') ORDER BY modified DESC")
+	match, err := parser.Parse(record.JSON)
 	if err != nil {
-		return matches, err
+		return MatchData{}, err
 	}
 
-	for rows.Next() {
-		var json string
+	return MatchData{UUID: uuid, Match: match}, nil
+}
 
-		rows.Scan(&json)
+func getRecentMatches() ([]MatchData, error) {
+	var recent []MatchData
 
-		match, err := parser.Parse(json)
+	records, err := matches.List(time.Now().Add(-24 * time.Hour))
+	if err != nil {
+		return recent, err
+	}
+
+	for _, record := range records {
+		match, err := parser.Parse(record.JSON)
 		if err != nil {
 			continue
 		}
 
-		matches = append(matches, match)
+		recent = append(recent, MatchData{
+			UUID:  record.UUID,
+			Match: match,
+		})
+	}
+
+	return recent, nil
+}
+
+// Returns a short summary of a match, e.g. "21-15, 10-8 · live".
+func describeMatch(m parser.Match) string {
+	var games []string
+
+	for _, game := range m.Games {
+		if game.PointsPlayed != 0 {
+			games = append(games, fmt.Sprintf("%d-%d", game.Team1PointsWon, game.Team2PointsWon))
+		}
+	}
+
+	status := "live"
+	if m.Info.Result.IsEarly() {
+		status = string(m.Info.Result)
+	} else if m.Finished {
+		status = "finished"
+	}
+
+	if len(games) == 0 {
+		return status
+	}
+
+	return strings.Join(games, ", ") + " · " + status
+}
+
+// Returns the URL of a request. It is absolute if BASE_URL is configured,
+// as the host and scheme of requests are sent by the client.
+func absoluteURL(r *http.Request) string {
+	return baseURL + r.URL.Path
+}
+
+func getMatchPage(uuid string) (MatchPage, error) {
+	page := MatchPage{UUID: uuid}
+
+	record, err := matches.Get(uuid)
+	if err != nil || len(record.JSON) == 0 {
+		return page, err
+	}
+
+	page.Match, err = parser.Parse(record.JSON)
+	if err != nil {
+		return page, err
+	}
+
+	page.Started = true
+	page.Description = describeMatch(page.Match)
+
+	return page, nil
+}
+
+var hexColor = regexp.MustCompile("^([0-9a-fA-F]{3}|[0-9a-fA-F]{6}|[0-9a-fA-F]{8})$")
+
+func parseOverlayOptions(query url.Values) (OverlayOptions, error) {
+	options := OverlayOptions{
+		Layout:     "lower-third",
+		Team1:      "ffa824",
+		Team2:      "00fe49",
+		Background: "000000cc",
+		Text:       "ffffff",
+	}
+
+	if layout := query.Get("layout"); layout != "" {
+		if layout != "lower-third" && layout != "corner" {
+			return options, errors.New("layout must be lower-third or corner")
+		}
+
+		options.Layout = layout
+	}
+
+	colors := map[string]*string{
+		"team1":      &options.Team1,
+		"team2":      &options.Team2,
+		"background": &options.Background,
+		"text":       &options.Text,
+	}
+
+	for name, color := range colors {
+		if value := query.Get(name); value != "" {
+			if !hexColor.MatchString(value) {
+				return options, fmt.Errorf("%s must be a hex colour, e.g. ffa824", name)
+			}
+
+			*color = value
+		}
+	}
+
+	return options, nil
+}
+
+func getOverlayPage(uuid string, options OverlayOptions) (OverlayPage, error) {
+	page := OverlayPage{
+		UUID:    uuid,
+		Options: options,
+	}
+
+	record, err := matches.Get(uuid)
+	if err != nil || len(record.JSON) == 0 {
+		return page, err
+	}
+
+	page.Match, err = parser.Parse(record.JSON)
+	if err != nil {
+		return page, err
+	}
+
+	page.Started = true
+
+	for i, game := range page.Match.Games {
+		if game.Winner == parser.Team1 {
+			page.GamesTeam1++
+		} else if game.Winner == parser.Team2 {
+			page.GamesTeam2++
+		}
+
+		// the current game, or the last game of a finished match
+		if i == len(page.Match.Games)-1 {
+			page.ScoreTeam1 = game.Team1PointsWon
+			page.ScoreTeam2 = game.Team2PointsWon
+		}
+	}
+
+	if serve := page.Match.NextServe(); serve != nil {
+		page.Serving = serve.Team
+	}
+
+	// between games, the next game starts at 0-0 and is served by the winner
+	// of the previous game
+	if n := len(page.Match.Games); n > 0 && !page.Match.Finished {
+		if winner := page.Match.Games[n-1].Winner; winner != parser.Unknown {
+			page.ScoreTeam1 = 0
+			page.ScoreTeam2 = 0
+			page.Serving = winner
+		}
+	}
+
+	return page, nil
+}
+
+// Returns all revisions of a match, parsed for replaying.
+func getReplay(uuid string) (ReplayData, error) {
+	replay := ReplayData{UUID: uuid}
+
+	revisions, err := matches.Revisions(uuid)
+	if err != nil {
+		return replay, err
+	}
+
+	for i, revision := range revisions {
+		r := ReplayRevision{Revision: revision}
+		r.Match, r.Err = parser.Parse(revision.JSON)
+
+		if i > 0 && r.Err == nil && replay.Revisions[i-1].Err == nil {
+			r.Undo = r.Match.PointsPlayed < replay.Revisions[i-1].Match.PointsPlayed
+		}
+
+		replay.Revisions = append(replay.Revisions, r)
+	}
+
+	return replay, nil
+}
+
+func newProblem(status int, err error) *APIProblem {
+	problem := &APIProblem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+	}
+
+	if err != nil {
+		problem.Detail = err.Error()
+		errors.As(err, &problem.ValidationError)
 	}
 
-	return matches, nil
+	return problem
+}
+
+func writeProblem(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(newProblem(status, err))
 }
 
 func handleAPI(w http.ResponseWriter, r *http.Request) {
@@ -210,42 +588,97 @@ func handleAPI(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if r.Method != http.MethodPost {
-		w.WriteHeader(http.StatusMethodNotAllowed)
+		writeProblem(w, http.StatusMethodNotAllowed, nil)
 		return
 	}
 
 	token, err := r.Cookie(COOKIE_NAME)
 	if err != nil || len(token.Value) != TOKEN_LENGTH {
-		w.WriteHeader(http.StatusBadRequest)
+		writeProblem(w, http.StatusBadRequest, errors.New("missing or invalid token"))
+		return
+	}
+
+	if !authorize(w, r, auth.Scorer) {
 		return
 	}
 
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		writeProblem(w, http.StatusBadRequest, err)
 		return
 	}
 
 	var requestData APIRequestData
 
 	if err := json.Unmarshal(body, &requestData); err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		writeProblem(w, http.StatusBadRequest, err)
 		return
 	}
 
 	switch requestData.Action {
 	case ACTION_NEW:
-		uuid, err := createMatch(token.Value)
-		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
+		var uuid string
+
+		switch {
+		case requestData.Draw != nil:
+			uuid, err = createDrawMatch(token.Value, *requestData.Draw)
+		case requestData.Tie != nil:
+			uuid, err = createTieMatch(token.Value, *requestData.Tie)
+		case requestData.Scheduled != nil:
+			uuid, err = createScheduledMatch(token.Value, *requestData.Scheduled)
+		case requestData.Planned != "":
+			uuid, err = claimPlannedMatch(token.Value, requestData.Planned)
+		default:
+			uuid, err = createMatch(token.Value)
+		}
+
+		if errors.Is(err, store.ErrNotFound) {
+			writeProblem(w, http.StatusNotFound, err)
+			return
+		} else if errors.Is(err, store.ErrClaimed) {
+			writeProblem(w, http.StatusConflict, err)
+			return
+		} else if err != nil {
+			status := http.StatusInternalServerError
+			if requestData.Draw != nil || requestData.Tie != nil || requestData.Scheduled != nil {
+				status = http.StatusBadRequest
+			}
+
+			writeProblem(w, status, err)
 			return
 		}
 
-		w.WriteHeader(http.StatusCreated)
+		recordAction(r, ACTION_NEW, uuid)
+
 		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
 		json.NewEncoder(w).Encode(APIResponseData{
 			Match: uuid,
 		})
+	case ACTION_HANDOFF:
+		ip := clientIP(r)
+
+		if !handoffThrottle.Allow(ip, time.Now()) {
+			writeProblem(w, http.StatusTooManyRequests, errors.New("too many invalid handoff codes, try again later"))
+			return
+		}
+
+		handoff, err := redeemHandoff(requestData.Code, token.Value)
+		if errors.Is(err, store.ErrNotFound) {
+			handoffThrottle.Fail(ip, time.Now())
+			writeProblem(w, http.StatusNotFound, errors.New("handoff code is invalid or has expired"))
+			return
+		} else if errors.Is(err, store.ErrInvalidToken) {
+			writeProblem(w, http.StatusConflict, errors.New("match has been handed off to another client"))
+			return
+		} else if err != nil {
+			writeProblem(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		recordAction(r, ACTION_HANDOFF, handoff.Match)
+
+		writeJSON(w, handoff)
 	case ACTION_UPDATE:
 		// .Data is intentionally not un-marshalled into a struct yet,
 		// but a map[string]any instead.
@@ -254,15 +687,39 @@ func handleAPI(w http.ResponseWriter, r *http.Request) {
 
 		match, err := parser.Parse(string(data))
 		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
+			writeProblem(w, http.StatusBadRequest, err)
 			return
 		}
 
-		if updateMatch(string(data), match, requestData.Match, token.Value) != nil {
-			w.WriteHeader(http.StatusBadRequest)
+		scoringMutex.Lock()
+		err = updateMatch(string(data), match, requestData.Match, token.Value)
+		scoringMutex.Unlock()
+
+		if err != nil {
+			writeProblem(w, http.StatusBadRequest, err)
 		}
 	default:
-		w.WriteHeader(http.StatusBadRequest)
+		writeProblem(w, http.StatusBadRequest, errors.New("unknown action"))
+	}
+}
+
+// Fills in the position of a draw, the rubber of a tie, the scheduled match,
+// the planned match or the handoff code that the client is opened for, if
+// any.
+func prefillClient(data *ClientData, query url.Values) error {
+	switch {
+	case query.Has("draw"):
+		return prefillDrawClient(data, query)
+	case query.Has("tie"):
+		return prefillTieClient(data, query)
+	case query.Has("scheduled"):
+		return prefillScheduledClient(data, query)
+	case query.Has("planned"):
+		return prefillPlannedClient(data, query)
+	case query.Has("handoff"):
+		return prefillHandoffClient(data, query)
+	default:
+		return nil
 	}
 }
 
@@ -272,14 +729,21 @@ func handleClient(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// scoring needs an account unless the server is anonymous
+	if !allows(r, auth.Scorer) {
+		http.Redirect(w, r, loginURL(r), http.StatusSeeOther)
+		return
+	}
+
 	token, err := r.Cookie(COOKIE_NAME)
 
 	// set cookie if it does not exist yet
 	if err != nil || len(token.Value) != TOKEN_LENGTH {
 		http.SetCookie(w, &http.Cookie{
-			Name:  COOKIE_NAME,
-			Value: randstr.String(TOKEN_LENGTH),
-			Path:  "/",
+			Name:     COOKIE_NAME,
+			Value:    randstr.String(TOKEN_LENGTH),
+			Path:     "/",
+			SameSite: http.SameSiteLaxMode,
 		})
 	}
 
@@ -292,7 +756,17 @@ func handleClient(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	w.Header().Set("Access-Control-Allow-Credentials", "true")
 
-	if err := t.Execute(w, nil); err != nil {
+	data := ClientData{
+		Rules:       parser.AllRules(),
+		DefaultMode: DEFAULT_MODE,
+	}
+
+	if err := prefillClient(&data, r.URL.Query()); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if err := t.Execute(w, data); err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 	}
 }
@@ -309,15 +783,116 @@ func handleIndex(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	// a single match, e.g. to update the page once it has changed
+	if uuid := r.URL.Query().Get("match"); uuid != "" {
+		match, err := getMatchData(uuid)
+		if errors.Is(err, store.ErrNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		} else if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if err := t.ExecuteTemplate(w, "match", match); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+
+		return
+	}
+
 	matches, err := getRecentMatches()
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
+	if err := t.Execute(w, matches); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+func handleMatch(w http.ResponseWriter, r *http.Request) {
+	uuid := strings.Trim(strings.TrimPrefix(r.URL.Path, PATH_MATCH), "/")
+
+	t, ok := templates["match.html"]
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	page, err := getMatchPage(uuid)
+	if errors.Is(err, store.ErrNotFound) {
+		http.NotFound(w, r)
+		return
+	} else if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	page.URL = absoluteURL(r)
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	if err := t.Execute(w, page); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+func handleOverlay(w http.ResponseWriter, r *http.Request) {
+	uuid := strings.Trim(strings.TrimPrefix(r.URL.Path, PATH_OVERLAY), "/")
+
+	t, ok := templates["overlay.html"]
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	options, err := parseOverlayOptions(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	page, err := getOverlayPage(uuid, options)
+	if errors.Is(err, store.ErrNotFound) {
+		http.NotFound(w, r)
+		return
+	} else if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 
-	if err := t.Execute(w, matches); err != nil {
+	if err := t.Execute(w, page); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+func handleReplay(w http.ResponseWriter, r *http.Request) {
+	uuid := strings.Trim(strings.TrimPrefix(r.URL.Path, PATH_REPLAY), "/")
+
+	t, ok := templates["replay.html"]
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	replay, err := getReplay(uuid)
+	if errors.Is(err, store.ErrNotFound) {
+		http.NotFound(w, r)
+		return
+	} else if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	if err := t.Execute(w, replay); err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 	}
 }
@@ -325,27 +900,92 @@ func handleIndex(w http.ResponseWriter, r *http.Request) {
 func main() {
 	args := os.Args[1:]
 	if len(args) == 0 {
-		log.Fatalln("Please provide the host/ip and port to listen on, e.g.\n\t$ score localhost:8080")
+		log.Fatalln("Please provide the host/ip and port to listen on, e.g.\n\t$ score localhost:8080\nor migrate the database with\n\t$ score migrate [status|up|dry-run]\nor manage users with\n\t$ score user [list|add NAME ROLE|passwd NAME]")
 	}
 
+	database := DEFAULT_DB_PATH
 	if path := os.Getenv("DB_PATH"); path != "" {
 		database = path
-	} else {
-		database = DEFAULT_DB_PATH
+	}
+
+	if args[0] == "migrate" {
+		if err := runMigrate(os.Stdout, database, args[1:]); err != nil {
+			log.Fatalf("Could not migrate database: %s\n", err)
+		}
+
+		return
+	}
+
+	if args[0] == "user" {
+		if err := runUser(os.Stdin, os.Stdout, database, args[1:]); err != nil {
+			log.Fatalf("Could not manage users: %s\n", err)
+		}
+
+		return
 	}
 
 	if err := initTemplates(); err != nil {
 		log.Fatalf("Could not load templates: %s\n", err)
 	}
 
-	if err := initDatabase(); err != nil {
+	if path := os.Getenv("RULES_PATH"); path != "" {
+		if err := initRules(path); err != nil {
+			log.Fatalf("Could not load rules: %s\n", err)
+		}
+	}
+
+	if err := initDatabase(database); err != nil {
 		log.Fatalf("Could not load database: %s\n", err)
 	}
 
+	adminToken = os.Getenv("ADMIN_TOKEN")
+	baseURL = strings.TrimSuffix(os.Getenv("BASE_URL"), "/")
+
+	if list := os.Getenv("TRUSTED_PROXIES"); list != "" {
+		proxies, err := parseTrustedProxies(list)
+		if err != nil {
+			log.Fatalf("Invalid TRUSTED_PROXIES: %s\n", err)
+		}
+
+		trustedProxies = proxies
+	}
+
+	if mode := os.Getenv("AUTH_MODE"); mode != "" {
+		if !isAuthMode(mode) {
+			log.Fatalf("Unknown AUTH_MODE %s, please use %s, %s or %s\n", mode, AUTH_ANONYMOUS, AUTH_ACCOUNTS, AUTH_PRIVATE)
+		}
+
+		authMode = mode
+	}
+
 	http.HandleFunc(PATH_API, handleAPI)
+	http.HandleFunc(PATH_API_EVENTS, handleEvents)
+	http.HandleFunc(PATH_API_MATCHES, handleMatchesAPI)
+	http.HandleFunc(PATH_API_MATCHES+"/", handleMatchesAPI)
+	http.HandleFunc(PATH_API_TOURNAMENTS, handleTournamentsAPI)
+	http.HandleFunc(PATH_API_TOURNAMENTS+"/", handleTournamentsAPI)
+	http.HandleFunc(PATH_API_TIES, handleTiesAPI)
+	http.HandleFunc(PATH_API_TIES+"/", handleTiesAPI)
+	http.HandleFunc(PATH_API_SCHEDULE, handleScheduleAPI)
+	http.HandleFunc(PATH_API_SCHEDULE+"/", handleScheduleAPI)
+	http.HandleFunc(PATH_API_PLANNED, handlePlannedAPI)
+	http.HandleFunc(PATH_API_PLANNED+"/", handlePlannedAPI)
 	http.HandleFunc(PATH_CLIENT, handleClient)
+	http.HandleFunc(PATH_MATCH, handleMatch)
+	http.HandleFunc(PATH_OVERLAY, handleOverlay)
+	http.HandleFunc(PATH_REPLAY, handleReplay)
+	http.HandleFunc(PATH_TOURNAMENT, handleTournament)
+	http.HandleFunc(PATH_TIE, handleTie)
+	http.HandleFunc(PATH_COURTS, handleCourts)
+	http.HandleFunc(PATH_UPCOMING, handleUpcoming)
+	http.HandleFunc(PATH_API_AUTH+"/", handleAuthAPI)
+	http.HandleFunc(PATH_API_ADMIN+"/", handleAdminAPI)
+	http.HandleFunc(PATH_LOGIN, handleLogin)
+	http.Handle(PATH_STATIC, http.FileServer(http.FS(files)))
 	http.HandleFunc(PATH_INDEX, handleIndex)
 
+	go runScheduler()
+
 	log.Printf("Listening on http://%s\n", args[0])
-	log.Fatal(http.ListenAndServe(args[0], nil))
+	log.Fatal(http.ListenAndServe(args[0], requireViewer(http.DefaultServeMux)))
 }
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"score/src/parser"
+	"score/src/store"
+	"score/src/throttle"
+	"strings"
+	"testing"
+)
+
+const testMatch = `{
+	"info": {
+		"mode": 21,
+		"team1": [{ "country": "DK", "player": "Viktor AXELSEN" }],
+		"team2": [{ "country": "TW", "player": "CHOU Tien Chen" }],
+		"start": 1679684400,
+		"end": null
+	},
+	"games": [{ "points": [1, 2, 1] }]
+}`
+
+func setup(t *testing.T) {
+	if err := initTemplates(); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	s := store.NewMemory()
+	matches = s
+	tournaments = s
+	ties = s
+	schedules = s
+	planned = s
+	handoffs = s
+	users = s
+	audits = s
+
+	handoffThrottle = throttle.New(HANDOFF_FAILURES, HANDOFF_TTL)
+	loginIPThrottle = throttle.New(LOGIN_FAILURES_PER_IP, LOGIN_WINDOW)
+	loginUserThrottle = throttle.New(LOGIN_FAILURES_PER_USER, LOGIN_WINDOW)
+}
+
+func apiRequest(token string, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, PATH_API, strings.NewReader(body))
+	r.AddCookie(&http.Cookie{Name: COOKIE_NAME, Value: token})
+
+	w := httptest.NewRecorder()
+	handleAPI(w, r)
+
+	return w
+}
+
+// Sends a request to a JSON API and decodes its response into v if it
+// succeeded. Returns the status code.
+func jsonRequest(t *testing.T, handler http.HandlerFunc, method string, target string, body string, v any) int {
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(method, target, strings.NewReader(body)))
+
+	if w.Code == http.StatusOK || w.Code == http.StatusCreated {
+		if err := json.NewDecoder(w.Body).Decode(v); err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+
+	return w.Code
+}
+
+func TestHandleAPI(t *testing.T) {
+	setup(t)
+
+	token := strings.Repeat("a", TOKEN_LENGTH)
+
+	w := apiRequest(token, `{"action": "new"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Got status %d, want %d", w.Code, http.StatusCreated)
+	}
+
+	var response APIResponseData
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	w = apiRequest(token, `{"action": "update", "match": "`+response.Match+`", "data": `+testMatch+`}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Got status %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+
+	// other clients must not update the match
+	w = apiRequest(strings.Repeat("b", TOKEN_LENGTH), `{"action": "update", "match": "`+response.Match+`", "data": `+testMatch+`}`)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Got status %d, want %d", w.Code, http.StatusBadRequest)
+	}
+
+	w = httptest.NewRecorder()
+	handleIndex(w, httptest.NewRequest(http.MethodGet, PATH_INDEX, nil))
+
+	if !strings.Contains(w.Body.String(), "Viktor AXELSEN") {
+		t.Error("Expected match on index page")
+	}
+
+	// only the table of a single match is fetched once it has changed
+	w = httptest.NewRecorder()
+	handleIndex(w, httptest.NewRequest(http.MethodGet, PATH_INDEX+"?match="+response.Match, nil))
+
+	if body := w.Body.String(); !strings.HasPrefix(strings.TrimSpace(body), `<table id="match-`+response.Match+`">`) || strings.Contains(body, "<main") {
+		t.Errorf("Got %s, want table of match", body)
+	}
+
+	w = httptest.NewRecorder()
+	handleIndex(w, httptest.NewRequest(http.MethodGet, PATH_INDEX+"?match=unknown", nil))
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Got status %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestHandleAPIInvalidMatch(t *testing.T) {
+	setup(t)
+
+	token := strings.Repeat("a", TOKEN_LENGTH)
+
+	w := apiRequest(token, `{"action": "update", "match": "unknown", "data": {"info": {"mode": 7}}}`)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Got status %d, want %d", w.Code, http.StatusBadRequest)
+	}
+
+	var problem APIProblem
+	if err := json.NewDecoder(w.Body).Decode(&problem); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if problem.ValidationError == nil || problem.Field != "info.mode" {
+		t.Errorf("Got %+v, want error for info.mode", problem)
+	}
+}
+
+func TestRevisions(t *testing.T) {
+	setup(t)
+
+	token := strings.Repeat("a", TOKEN_LENGTH)
+	uuid, _ := createMatch(token)
+
+	for i := 0; i < 2; i++ {
+		w := apiRequest(token, `{"action": "update", "match": "`+uuid+`", "data": `+testMatch+`}`)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Got status %d, want %d", w.Code, http.StatusOK)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	handleMatchesAPI(w, httptest.NewRequest(http.MethodGet, PATH_API_MATCHES+uuid+"/revisions", nil))
+
+	var revisions []APIRevision
+	if err := json.NewDecoder(w.Body).Decode(&revisions); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if len(revisions) != 2 || revisions[1].Revision != 2 {
+		t.Errorf("Got %+v, want 2 revisions", revisions)
+	}
+
+	w = httptest.NewRecorder()
+	handleMatchesAPI(w, httptest.NewRequest(http.MethodGet, PATH_API_MATCHES+uuid+"/revisions/1", nil))
+
+	var revision APIRevision
+	if err := json.NewDecoder(w.Body).Decode(&revision); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if _, err := parser.Parse(string(revision.Data)); err != nil {
+		t.Errorf("Got invalid revision data: %s", err)
+	}
+
+	w = httptest.NewRecorder()
+	handleMatchesAPI(w, httptest.NewRequest(http.MethodGet, PATH_API_MATCHES+uuid+"/revisions/3", nil))
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Got status %d, want %d", w.Code, http.StatusNotFound)
+	}
+
+	w = httptest.NewRecorder()
+	handleReplay(w, httptest.NewRequest(http.MethodGet, PATH_REPLAY+uuid, nil))
+
+	if !strings.Contains(w.Body.String(), `id="revision-2"`) {
+		t.Error("Expected revision 2 on replay page")
+	}
+}
+
+func TestMatchPage(t *testing.T) {
+	setup(t)
+
+	token := strings.Repeat("a", TOKEN_LENGTH)
+	uuid, _ := createMatch(token)
+
+	w := httptest.NewRecorder()
+	handleMatch(w, httptest.NewRequest(http.MethodGet, PATH_MATCH+uuid, nil))
+
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "not started yet") {
+		t.Errorf("Got status %d, want page of match that has not started", w.Code)
+	}
+
+	apiRequest(token, `{"action": "update", "match": "`+uuid+`", "data": `+testMatch+`}`)
+
+	// the host of requests must not end up in shared links
+	r := httptest.NewRequest(http.MethodGet, PATH_MATCH+uuid, nil)
+	r.Host = "attacker.example"
+
+	w = httptest.NewRecorder()
+	handleMatch(w, r)
+
+	for _, want := range []string{
+		`<meta property="og:title" content="Viktor AXELSEN vs CHOU Tien Chen">`,
+		`<meta property="og:description" content="2-1 · live">`,
+		`<meta property="og:url" content="/m/` + uuid + `">`,
+	} {
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("Expected %s on match page", want)
+		}
+	}
+
+	baseURL = "https://score.example.com"
+	defer func() { baseURL = "" }()
+
+	w = httptest.NewRecorder()
+	handleMatch(w, r)
+
+	if want := `<meta property="og:url" content="https://score.example.com/m/` + uuid + `">`; !strings.Contains(w.Body.String(), want) {
+		t.Errorf("Expected %s on match page", want)
+	}
+
+	w = httptest.NewRecorder()
+	handleMatch(w, httptest.NewRequest(http.MethodGet, PATH_MATCH+"unknown", nil))
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Got status %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestOverlay(t *testing.T) {
+	setup(t)
+
+	token := strings.Repeat("a", TOKEN_LENGTH)
+	uuid, _ := createMatch(token)
+
+	data := strings.Replace(testMatch, `"games": [{ "points": [1, 2, 1] }]`, `"games": [{ "points": [1, 2, 1], "server": { "team": 1, "player": 0 } }]`, 1)
+	apiRequest(token, `{"action": "update", "match": "`+uuid+`", "data": `+data+`}`)
+
+	w := httptest.NewRecorder()
+	handleOverlay(w, httptest.NewRequest(http.MethodGet, PATH_OVERLAY+uuid+"?layout=corner&team1=ff0000", nil))
+
+	body := w.Body.String()
+
+	for _, want := range []string{`<body class="corner">`, `--color-team1: #ff0000;`, `<td class="score team1">2</td>`, `<td class="serve team1"><span class="dot">`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected %s in overlay", want)
+		}
+	}
+
+	// the winner of the first game serves first in the second game
+	data = strings.Replace(testMatch, `"games": [{ "points": [1, 2, 1] }]`, `"games": [{ "points": [`+strings.Repeat("2, ", 20)+`2] }]`, 1)
+	apiRequest(token, `{"action": "update", "match": "`+uuid+`", "data": `+data+`}`)
+
+	w = httptest.NewRecorder()
+	handleOverlay(w, httptest.NewRequest(http.MethodGet, PATH_OVERLAY+uuid, nil))
+
+	body = w.Body.String()
+
+	for _, want := range []string{`<td class="score team1">0</td>`, `<td class="score team2">0</td>`, `<td class="serve team2"><span class="dot">`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected %s in overlay between games", want)
+		}
+	}
+
+	for _, query := range []string{"?layout=fullscreen", "?team2=red", "?text=fff%3Bx"} {
+		w := httptest.NewRecorder()
+		handleOverlay(w, httptest.NewRequest(http.MethodGet, PATH_OVERLAY+uuid+query, nil))
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: got status %d, want %d", query, w.Code, http.StatusBadRequest)
+		}
+	}
+}
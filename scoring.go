@@ -0,0 +1,301 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"score/src/auth"
+	"score/src/parser"
+	"score/src/store"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/coder/websocket"
+)
+
+const (
+	// messages sent by the scoring client
+	MESSAGE_MATCH = "match"
+	MESSAGE_RALLY = "rally"
+	MESSAGE_UNDO  = "undo"
+	MESSAGE_INFO  = "info"
+	MESSAGE_EVENT = "event"
+
+	// messages sent by the server, besides MESSAGE_MATCH
+	MESSAGE_ACK   = "ack"
+	MESSAGE_ERROR = "error"
+
+	// interval of pings sent to keep idle connections open
+	PING_INTERVAL = 30 * time.Second
+	// connections are closed if a ping is not answered or a message cannot
+	// be written within this time
+	WRITE_TIMEOUT = 10 * time.Second
+	// maximum size of a message, e.g. a whole match
+	MAX_MESSAGE_SIZE = 1 << 20
+)
+
+// Changes are applied to the stored match one at a time, over HTTP as well as
+// over WebSockets, so that a change is never applied to a stale match and
+// concurrent clients of the same scorer cannot overwrite each other.
+var scoringMutex sync.Mutex
+
+// ScoringMessage is a change of a match sent by the scoring client over the
+// WebSocket at /api/matches/{uuid}/ws. Messages are numbered by Seq, starting
+// at 1 for every connection, and every message is answered with an ack or an
+// error with the same Seq. Rejected messages are not applied, but their Seq
+// is used up as well. The last message may be sent again, e.g. if its
+// response was lost, and is answered with the same response again. Messages
+// with an earlier Seq are rejected.
+//
+//	{"seq": 1, "type": "match", "data": {...}}      replaces the whole match
+//	{"seq": 2, "type": "rally", "winner": 1}        adds a rally
+//	{"seq": 3, "type": "undo"}                      removes the last rally
+//	{"seq": 4, "type": "info", "info": {...}}       replaces the match info
+//	{"seq": 5, "type": "event", "event": {...}}     adds an official event
+//
+// Rallies and events are timestamped by the server.
+type ScoringMessage struct {
+	Seq    int               `json:"seq"`
+	Type   string            `json:"type"`
+	Winner parser.TeamID     `json:"winner,omitempty"`
+	Shots  int               `json:"shots,omitempty"`
+	Note   string            `json:"note,omitempty"`
+	Info   *parser.MatchInfo `json:"info,omitempty"`
+	Event  *parser.Event     `json:"event,omitempty"`
+	Data   json.RawMessage   `json:"data,omitempty"`
+}
+
+// ScoringResponse is sent by the server. Every connection also receives the
+// state of the match after each change as a "match" message, like the
+// events of GET /api/matches/{uuid}/events.
+type ScoringResponse struct {
+	Type    string          `json:"type"`
+	Seq     int             `json:"seq,omitempty"`
+	Match   json.RawMessage `json:"match,omitempty"`
+	Problem *APIProblem     `json:"problem,omitempty"`
+}
+
+// Applies a scoring message to the match with the given uuid and returns
+// the raw JSON and the parsed match, which are then stored.
+func applyMessage(record store.Record, message ScoringMessage) (string, parser.Match, error) {
+	if message.Type == MESSAGE_MATCH {
+		m, err := parser.Parse(string(message.Data))
+		return string(message.Data), m, err
+	}
+
+	if len(record.JSON) == 0 {
+		return "", parser.Match{}, errors.New("match has not been started")
+	}
+
+	m, err := parser.Parse(record.JSON)
+	if err != nil {
+		return "", m, err
+	}
+
+	switch message.Type {
+	case MESSAGE_INFO:
+		if message.Info == nil {
+			return "", m, errors.New("missing info")
+		}
+
+		m.Info = *message.Info
+	case MESSAGE_RALLY, MESSAGE_UNDO, MESSAGE_EVENT:
+		state, err := parser.NewStateFromMatch(m)
+		if err != nil {
+			return "", m, err
+		}
+
+		switch message.Type {
+		case MESSAGE_RALLY:
+			err = state.ApplyRally(parser.Rally{
+				Winner: message.Winner,
+				Time:   &parser.UnixTime{Time: time.Now()},
+				Shots:  message.Shots,
+				Note:   message.Note,
+			})
+		case MESSAGE_UNDO:
+			state.Undo()
+		case MESSAGE_EVENT:
+			if message.Event == nil {
+				return "", m, errors.New("missing event")
+			}
+
+			event := *message.Event
+			event.Time = parser.UnixTime{Time: time.Now()}
+			err = state.AddEvent(event)
+		}
+
+		if err != nil {
+			return "", m, err
+		}
+
+		if m, err = state.Match(); err != nil {
+			return "", m, err
+		}
+	default:
+		return "", m, errors.New("unknown message type")
+	}
+
+	// the parser sets the end of running matches to now, which must not be
+	// stored
+	if !m.Finished {
+		m.Info.End = parser.UnixTime{}
+	}
+
+	data, err := json.Marshal(m)
+	if err != nil {
+		return "", m, err
+	}
+
+	// validate the match again, e.g. changed teams or events
+	m, err = parser.Parse(string(data))
+
+	return string(data), m, err
+}
+
+func handleScoringMessage(uuid string, token string, message ScoringMessage) error {
+	scoringMutex.Lock()
+	defer scoringMutex.Unlock()
+
+	record, err := matches.Get(uuid)
+	if err != nil {
+		return err
+	}
+
+	if len(token) != TOKEN_LENGTH || record.Token != token {
+		return errors.New("match cannot be updated with this token")
+	}
+
+	raw, m, err := applyMessage(record, message)
+	if err != nil {
+		return err
+	}
+
+	return updateMatch(raw, m, uuid, token)
+}
+
+// Handles the WebSocket at /api/matches/{uuid}/ws. Viewers may connect
+// without a token to receive updates only.
+func handleScoring(w http.ResponseWriter, r *http.Request, uuid string) {
+	if _, err := matches.Get(uuid); errors.Is(err, store.ErrNotFound) {
+		writeProblem(w, http.StatusNotFound, err)
+		return
+	} else if err != nil {
+		writeProblem(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// users that may not score only receive updates
+	var token string
+	if cookie, err := r.Cookie(COOKIE_NAME); err == nil && allows(r, auth.Scorer) {
+		token = cookie.Value
+	}
+
+	// pages of other origins must not score with the cookies of the scorer,
+	// so the origin has to match the host
+	conn, err := websocket.Accept(w, r, nil)
+	if err != nil {
+		return
+	}
+
+	defer conn.CloseNow()
+
+	conn.SetReadLimit(MAX_MESSAGE_SIZE)
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	updates, unsubscribe := events.Subscribe(uuid)
+	defer unsubscribe()
+
+	// connections may be written to concurrently
+	send := func(response ScoringResponse) error {
+		data, _ := json.Marshal(response)
+
+		ctx, cancel := context.WithTimeout(ctx, WRITE_TIMEOUT)
+		defer cancel()
+
+		return conn.Write(ctx, websocket.MessageText, data)
+	}
+
+	// the current state is sent first, then every update
+	if match, err := getMatch(uuid); err == nil {
+		data, _ := json.Marshal(match)
+		send(ScoringResponse{Type: MESSAGE_MATCH, Match: data})
+	}
+
+	go func() {
+		// the connection is closed once a write or a ping fails
+		defer conn.CloseNow()
+
+		ping := time.NewTicker(PING_INTERVAL)
+		defer ping.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case update, ok := <-updates:
+				if !ok {
+					return
+				}
+
+				if send(ScoringResponse{Type: MESSAGE_MATCH, Match: update.Data}) != nil {
+					return
+				}
+			case <-ping.C:
+				ctx, cancel := context.WithTimeout(ctx, WRITE_TIMEOUT)
+				err := conn.Ping(ctx)
+				cancel()
+
+				if err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	// response of the last processed message, so that it can be sent again
+	// if it was lost. Sequence numbers only increase, so earlier responses
+	// are not needed anymore.
+	var response ScoringResponse
+	last := 0
+
+	for {
+		kind, data, err := conn.Read(ctx)
+		if err != nil {
+			return
+		}
+
+		if kind != websocket.MessageText || !utf8.Valid(data) {
+			conn.Close(websocket.StatusInvalidFramePayloadData, "messages must be UTF-8 text")
+			return
+		}
+
+		var message ScoringMessage
+
+		if err := json.Unmarshal(data, &message); err != nil {
+			send(ScoringResponse{Type: MESSAGE_ERROR, Problem: newProblem(http.StatusBadRequest, err)})
+			continue
+		}
+
+		switch {
+		case message.Seq == last && last > 0:
+			// already processed, the response was probably lost
+			send(response)
+		case message.Seq != last+1:
+			send(ScoringResponse{Type: MESSAGE_ERROR, Seq: message.Seq, Problem: newProblem(http.StatusBadRequest, errors.New("unexpected sequence number"))})
+		default:
+			last = message.Seq
+
+			response = ScoringResponse{Type: MESSAGE_ACK, Seq: message.Seq}
+			if err := handleScoringMessage(uuid, token, message); err != nil {
+				response = ScoringResponse{Type: MESSAGE_ERROR, Seq: message.Seq, Problem: newProblem(http.StatusBadRequest, err)}
+			}
+
+			send(response)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/coder/websocket"
+)
+
+// Sends a message and returns the response with the same sequence number,
+// skipping match updates.
+func sendScoring(t *testing.T, conn *websocket.Conn, message string) ScoringResponse {
+	if err := conn.Write(context.Background(), websocket.MessageText, []byte(message)); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	for {
+		_, data, err := conn.Read(context.Background())
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		var response ScoringResponse
+		if err := json.Unmarshal(data, &response); err != nil {
+			t.Fatal(err.Error())
+		}
+
+		if response.Type != MESSAGE_MATCH {
+			return response
+		}
+	}
+}
+
+func dialScoring(t *testing.T, server *httptest.Server, uuid string, token string) *websocket.Conn {
+	header := http.Header{}
+	header.Set("Cookie", COOKIE_NAME+"="+token)
+
+	conn, _, err := websocket.Dial(context.Background(), scoringURL(server, uuid), &websocket.DialOptions{HTTPHeader: header})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	return conn
+}
+
+func scoringURL(server *httptest.Server, uuid string) string {
+	return "ws" + strings.TrimPrefix(server.URL, "http") + PATH_API_MATCHES + "/" + uuid + "/ws"
+}
+
+func TestScoring(t *testing.T) {
+	setup(t)
+
+	server := httptest.NewServer(http.HandlerFunc(handleMatchesAPI))
+	defer server.Close()
+
+	token := strings.Repeat("a", TOKEN_LENGTH)
+	uuid, _ := createMatch(token)
+
+	conn := dialScoring(t, server, uuid, token)
+	defer conn.CloseNow()
+
+	tests := []struct {
+		message string
+		want    string
+		points  int
+	}{
+		{`{"seq": 1, "type": "rally", "winner": 1}`, MESSAGE_ERROR, 0},
+		{`{"seq": 2, "type": "match", "data": ` + testMatch + `}`, MESSAGE_ACK, 3},
+		{`{"seq": 3, "type": "rally", "winner": 2}`, MESSAGE_ACK, 4},
+		{`{"seq": 3, "type": "rally", "winner": 2}`, MESSAGE_ACK, 4},
+		{`{"seq": 1, "type": "rally", "winner": 1}`, MESSAGE_ERROR, 4},
+		{`{"seq": 5, "type": "undo"}`, MESSAGE_ERROR, 4},
+		{`{"seq": 4, "type": "rally", "winner": 3}`, MESSAGE_ERROR, 4},
+		{`{"seq": 5, "type": "undo"}`, MESSAGE_ACK, 3},
+		{`{"seq": 6, "type": "event", "event": {"type": "fault", "team": 1}}`, MESSAGE_ACK, 4},
+		{`{"seq": 7, "type": "info", "info": {"mode": 21, "team1": [], "team2": []}}`, MESSAGE_ERROR, 4},
+	}
+
+	for _, test := range tests {
+		response := sendScoring(t, conn, test.message)
+
+		if response.Type != test.want {
+			t.Errorf("%s: got %+v, want %s", test.message, response, test.want)
+		}
+
+		if match, err := getMatch(uuid); err == nil && match.PointsPlayed != test.points {
+			t.Errorf("%s: got %d points, want %d", test.message, match.PointsPlayed, test.points)
+		}
+	}
+
+	match, _ := getMatch(uuid)
+
+	// rallies are timestamped by the server
+	if rally := match.Games[0].Rallies[3]; rally.Winner != 2 || rally.Time == nil {
+		t.Errorf("Got %+v, want rally with time", rally)
+	}
+
+	if len(match.Events) != 1 || match.Events[0].Point != 3 {
+		t.Errorf("Got %+v, want service fault", match.Events)
+	}
+
+	// the match is still running
+	if record, _ := matches.Get(uuid); !strings.Contains(record.JSON, `"end":null`) {
+		t.Errorf("Got %s, want match without end", record.JSON)
+	}
+
+	// viewers without token receive updates, but cannot score
+	viewer := dialScoring(t, server, uuid, strings.Repeat("b", TOKEN_LENGTH))
+	defer viewer.CloseNow()
+
+	if response := sendScoring(t, viewer, `{"seq": 1, "type": "undo"}`); response.Type != MESSAGE_ERROR {
+		t.Errorf("Got %+v, want error", response)
+	}
+
+	sendScoring(t, conn, `{"seq": 8, "type": "rally", "winner": 1}`)
+
+	_, data, err := viewer.Read(context.Background())
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	var update ScoringResponse
+	json.Unmarshal(data, &update)
+
+	var updated APIMatch
+	json.Unmarshal(update.Match, &updated)
+
+	if update.Type != MESSAGE_MATCH || updated.PointsPlayed != 5 {
+		t.Errorf("Got %s with %d points, want match update", update.Type, updated.PointsPlayed)
+	}
+}
+
+func TestScoringOrigin(t *testing.T) {
+	setup(t)
+
+	server := httptest.NewServer(http.HandlerFunc(handleMatchesAPI))
+	defer server.Close()
+
+	token := strings.Repeat("a", TOKEN_LENGTH)
+	uuid, _ := createMatch(token)
+
+	// other pages must not score with the cookie of the scorer
+	header := http.Header{}
+	header.Set("Cookie", COOKIE_NAME+"="+token)
+	header.Set("Origin", "https://example.org")
+
+	if _, res, err := websocket.Dial(context.Background(), scoringURL(server, uuid), &websocket.DialOptions{HTTPHeader: header}); err == nil || res.StatusCode != http.StatusForbidden {
+		t.Errorf("Got %v, want connection from other origin to be rejected", err)
+	}
+
+	conn := dialScoring(t, server, uuid, token)
+	defer conn.CloseNow()
+
+	// text messages must be valid UTF-8
+	if err := conn.Write(context.Background(), websocket.MessageText, []byte{'{', 0xff, '}'}); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	for {
+		if _, _, err := conn.Read(context.Background()); err != nil {
+			if status := websocket.CloseStatus(err); status != websocket.StatusInvalidFramePayloadData {
+				t.Errorf("Got %v, want status %d", err, websocket.StatusInvalidFramePayloadData)
+			}
+
+			break
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const (
+	// iterations of PBKDF2-HMAC-SHA256 for new password hashes, as
+	// recommended by OWASP. Hashes store their iterations, so that it can be
+	// raised later.
+	ITERATIONS = 600000
+	// length of salts and derived keys in bytes
+	SALT_LENGTH = 16
+	KEY_LENGTH  = 32
+
+	MIN_PASSWORD_LENGTH = 8
+	MIN_USERNAME_LENGTH = 3
+	MAX_USERNAME_LENGTH = 32
+
+	// prefix of password hashes, followed by iterations, salt and key
+	HASH_PREFIX = "pbkdf2-sha256"
+
+	ERR_INVALID_ROLE     = "role must be one of admin, organiser, scorer or viewer"
+	ERR_INVALID_USERNAME = "username must be 3 to 32 lowercase letters, digits, dots, dashes or underscores"
+	ERR_INVALID_PASSWORD = "password must have at least 8 characters"
+	ERR_INVALID_HASH     = "password hash is invalid"
+)
+
+// Role is what a user may do. Each role may do everything the roles below
+// it may do.
+type Role string
+
+const (
+	// may follow matches if the server is private
+	Viewer Role = "viewer"
+	// may score matches
+	Scorer Role = "scorer"
+	// may create tournaments, ties, schedules and planned matches
+	Organiser Role = "organiser"
+	// may manage users
+	Admin Role = "admin"
+)
+
+var ranks = map[Role]int{
+	Viewer:    1,
+	Scorer:    2,
+	Organiser: 3,
+	Admin:     4,
+}
+
+func (r Role) IsValid() bool {
+	_, ok := ranks[r]
+	return ok
+}
+
+// Returns true if the role may do everything the given role may do.
+func (r Role) Allows(required Role) bool {
+	return r.IsValid() && ranks[r] >= ranks[required]
+}
+
+// Returns the username in its canonical form, or an error if it is invalid.
+func NormalizeUsername(username string) (string, error) {
+	username = strings.ToLower(strings.TrimSpace(username))
+
+	if len(username) < MIN_USERNAME_LENGTH || len(username) > MAX_USERNAME_LENGTH {
+		return "", errors.New(ERR_INVALID_USERNAME)
+	}
+
+	for _, c := range username {
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '.' || c == '-' || c == '_') {
+			return "", errors.New(ERR_INVALID_USERNAME)
+		}
+	}
+
+	return username, nil
+}
+
+// Returns the hash of a password with a random salt, e.g.
+// pbkdf2-sha256$600000${salt}${key}
+func HashPassword(password string) (string, error) {
+	if len(password) < MIN_PASSWORD_LENGTH {
+		return "", errors.New(ERR_INVALID_PASSWORD)
+	}
+
+	salt := make([]byte, SALT_LENGTH)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := pbkdf2.Key([]byte(password), salt, ITERATIONS, KEY_LENGTH, sha256.New)
+
+	return fmt.Sprintf("%s$%d$%s$%s", HASH_PREFIX, ITERATIONS,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// Returns true if the password matches the hash.
+func CheckPassword(hash string, password string) bool {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 4 || parts[0] != HASH_PREFIX {
+		return false
+	}
+
+	iterations, err := strconv.Atoi(parts[1])
+	if err != nil || iterations < 1 {
+		return false
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return false
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[3])
+	if err != nil || len(key) == 0 {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare(pbkdf2.Key([]byte(password), salt, iterations, len(key), sha256.New), key) == 1
+}
@@ -0,0 +1,79 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExistingHash(t *testing.T) {
+	// hashed before PBKDF2 was taken from golang.org/x/crypto
+	hash := "pbkdf2-sha256$1000$c2h1dHRsZWNvY2tzYWx0IQ$fb5QNShM+Xod6NXgMlvXHCwsOY6QSVCaZJ+wssJm030"
+
+	if !CheckPassword(hash, "shuttlecock") {
+		t.Error("Expected existing hash to match its password")
+	}
+
+	if CheckPassword(hash, "shuttlecocks") {
+		t.Error("Expected existing hash not to match another password")
+	}
+}
+
+func TestPassword(t *testing.T) {
+	if _, err := HashPassword("short"); err == nil {
+		t.Error("Expected error for short password")
+	}
+
+	hash, err := HashPassword("shuttlecock")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if !strings.HasPrefix(hash, HASH_PREFIX+"$") {
+		t.Errorf("Got %s, want %s hash", hash, HASH_PREFIX)
+	}
+
+	if other, _ := HashPassword("shuttlecock"); other == hash {
+		t.Error("Expected different salts")
+	}
+
+	if !CheckPassword(hash, "shuttlecock") {
+		t.Error("Expected password to match")
+	}
+
+	for _, hash := range []string{hash, "", "pbkdf2-sha256$0$c2FsdA$a2V5", "md5$1$c2FsdA$a2V5"} {
+		if CheckPassword(hash, "racket123") {
+			t.Errorf("Expected %q not to match", hash)
+		}
+	}
+}
+
+func TestRole(t *testing.T) {
+	for _, test := range []struct {
+		role     Role
+		required Role
+		want     bool
+	}{
+		{Admin, Viewer, true},
+		{Organiser, Organiser, true},
+		{Organiser, Admin, false},
+		{Scorer, Organiser, false},
+		{Viewer, Scorer, false},
+		{Role("root"), Viewer, false},
+	} {
+		if got := test.role.Allows(test.required); got != test.want {
+			t.Errorf("Got %t for %s allowing %s, want %t", got, test.role, test.required, test.want)
+		}
+	}
+}
+
+func TestNormalizeUsername(t *testing.T) {
+	if username, err := NormalizeUsername(" Desk.1 "); err != nil || username != "desk.1" {
+		t.Errorf("Got %s (%v), want desk.1", username, err)
+	}
+
+	for _, username := range []string{"", "ab", "desk 1", "désk", strings.Repeat("a", 33)} {
+		if _, err := NormalizeUsername(username); err == nil {
+			t.Errorf("Expected error for %q", username)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package hub
+
+import "sync"
+
+// number of messages buffered per subscriber before messages are dropped
+const BUFFER_SIZE = 16
+
+// Message is a change of a match, e.g. the JSON of the updated match.
+// Messages without a match are only sent to subscribers of all matches.
+type Message struct {
+	Match string
+	// type of the change, e.g. the name of a Server-Sent Event
+	Type string
+	Data []byte
+}
+
+// Hub is an in-process publish/subscribe hub for match changes. Publishing
+// never blocks: messages for subscribers that do not keep up are dropped.
+type Hub struct {
+	mutex       sync.RWMutex
+	subscribers map[*subscriber]struct{}
+}
+
+type subscriber struct {
+	// uuid of the match, or empty for all matches
+	match string
+	ch    chan Message
+}
+
+func New() *Hub {
+	return &Hub{
+		subscribers: make(map[*subscriber]struct{}),
+	}
+}
+
+// Subscribes to changes of the match with the given uuid, or to changes of
+// all matches if the uuid is empty. The returned function cancels the
+// subscription and closes the channel.
+func (h *Hub) Subscribe(match string) (<-chan Message, func()) {
+	s := &subscriber{
+		match: match,
+		ch:    make(chan Message, BUFFER_SIZE),
+	}
+
+	h.mutex.Lock()
+	h.subscribers[s] = struct{}{}
+	h.mutex.Unlock()
+
+	var once sync.Once
+
+	return s.ch, func() {
+		once.Do(func() {
+			h.mutex.Lock()
+			delete(h.subscribers, s)
+			h.mutex.Unlock()
+
+			close(s.ch)
+		})
+	}
+}
+
+// Publishes a message to all subscribers of its match.
+func (h *Hub) Publish(message Message) {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	for s := range h.subscribers {
+		if s.match != "" && s.match != message.Match {
+			continue
+		}
+
+		select {
+		case s.ch <- message:
+		default:
+		}
+	}
+}
+
+// Returns the number of subscribers.
+func (h *Hub) Len() int {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	return len(h.subscribers)
+}
@@ -0,0 +1,53 @@
+package hub
+
+import "testing"
+
+func TestHub(t *testing.T) {
+	h := New()
+
+	all, cancelAll := h.Subscribe("")
+	one, cancelOne := h.Subscribe("a")
+
+	h.Publish(Message{Match: "a", Data: []byte("1")})
+	h.Publish(Message{Match: "b", Data: []byte("2")})
+
+	if m := <-all; m.Match != "a" {
+		t.Errorf("Got %s, want a", m.Match)
+	}
+
+	if m := <-all; m.Match != "b" {
+		t.Errorf("Got %s, want b", m.Match)
+	}
+
+	if m := <-one; string(m.Data) != "1" {
+		t.Errorf("Got %s, want 1", m.Data)
+	}
+
+	select {
+	case m := <-one:
+		t.Errorf("Got unexpected message for %s", m.Match)
+	default:
+	}
+
+	cancelOne()
+	cancelOne()
+
+	if _, ok := <-one; ok {
+		t.Error("Expected closed channel")
+	}
+
+	// publishing must not block on slow subscribers
+	for i := 0; i < 2*BUFFER_SIZE; i++ {
+		h.Publish(Message{Match: "a"})
+	}
+
+	if len(all) != BUFFER_SIZE {
+		t.Errorf("Got %d buffered messages, want %d", len(all), BUFFER_SIZE)
+	}
+
+	cancelAll()
+
+	if h.Len() != 0 {
+		t.Errorf("Got %d subscribers, want 0", h.Len())
+	}
+}
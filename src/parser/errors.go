@@ -0,0 +1,90 @@
+package parser
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ValidationError is returned by Parse if a match is invalid. It describes
+// the reason and the location of the first error found. GameIndex and
+// PointIndex are -1 if the error does not refer to a game or point.
+type ValidationError struct {
+	Field      string `json:"field,omitempty"`
+	GameIndex  int    `json:"game"`
+	PointIndex int    `json:"point"`
+	Reason     string `json:"reason"`
+	// underlying error, e.g. of the JSON decoder
+	Err error `json:"-"`
+}
+
+func newValidationError(field string, reason string) *ValidationError {
+	return &ValidationError{
+		Field:      field,
+		GameIndex:  -1,
+		PointIndex: -1,
+		Reason:     reason,
+	}
+}
+
+func newGameError(field string, game int, point int, reason string) *ValidationError {
+	return &ValidationError{
+		Field:      field,
+		GameIndex:  game,
+		PointIndex: point,
+		Reason:     reason,
+	}
+}
+
+// Returns the location of the error in JSON path notation,
+// e.g. "games[1].points[4]".
+func (e *ValidationError) Location() string {
+	location := e.Field
+
+	if e.GameIndex >= 0 {
+		location = strings.Replace(location, "games", fmt.Sprintf("games[%d]", e.GameIndex), 1)
+	}
+
+	if e.PointIndex >= 0 {
+		location = strings.Replace(location, "points", fmt.Sprintf("points[%d]", e.PointIndex), 1)
+	}
+
+	return location
+}
+
+func (e *ValidationError) Error() string {
+	msg := e.Reason
+
+	if e.Reason != ERR_INVALID_JSON {
+		msg = fmt.Sprintf("%s: %s", ERR_INVALID_MATCH, e.Reason)
+	}
+
+	if location := e.Location(); len(location) != 0 {
+		msg = fmt.Sprintf("%s (%s)", msg, location)
+	}
+
+	if e.Err != nil {
+		msg = fmt.Sprintf("%s: %s", msg, e.Err.Error())
+	}
+
+	return msg
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// Converts an error of the JSON decoder into a ValidationError.
+func newJSONError(err error) *ValidationError {
+	e := newValidationError("", ERR_INVALID_JSON)
+	e.Err = err
+
+	var typeError *json.UnmarshalTypeError
+
+	if errors.As(err, &typeError) {
+		e.Field = typeError.Field
+	}
+
+	return e
+}
@@ -0,0 +1,92 @@
+package parser
+
+import "fmt"
+
+type EventType string
+
+const (
+	EventYellowCard   EventType = "yellow"
+	EventRedCard      EventType = "red"
+	EventBlackCard    EventType = "black"
+	EventServiceFault EventType = "fault"
+	EventInjury       EventType = "injury"
+	EventMedical      EventType = "medical"
+)
+
+// Event is an official event of a match, e.g. a misconduct card. Game and
+// Point reference the rally the event is tied to. For events that award a
+// rally to the opponent, i.e. red cards and service faults called by the
+// umpire, this is the awarded rally. For all other events, Point may also
+// be the index of the next rally that was not played yet.
+type Event struct {
+	Type   EventType `json:"type"`
+	Time   UnixTime  `json:"t"`
+	Team   TeamID    `json:"team"`
+	Player int       `json:"player"`
+	Game   int       `json:"game"`
+	Point  int       `json:"point"`
+	Note   string    `json:"note,omitempty"`
+}
+
+func (t EventType) isValid() bool {
+	switch t {
+	case EventYellowCard, EventRedCard, EventBlackCard, EventServiceFault, EventInjury, EventMedical:
+		return true
+	default:
+		return false
+	}
+}
+
+// Returns true if the event awards a rally to the opponent.
+func (t EventType) AwardsPoint() bool {
+	return t == EventRedCard || t == EventServiceFault
+}
+
+// Returns the events of the given team.
+func (m Match) EventsOf(team TeamID) []Event {
+	var events []Event
+
+	for _, event := range m.Events {
+		if event.Team == team {
+			events = append(events, event)
+		}
+	}
+
+	return events
+}
+
+// Validates the events of a match. Awarded rallies must be won by the
+// opponent and a black card disqualifies the team.
+func validateEvents(m *Match) error {
+	for i, event := range m.Events {
+		err := newGameError(fmt.Sprintf("events[%d]", i), event.Game, event.Point, ERR_INVALID_EVENT)
+
+		if !event.Type.isValid() || event.Time.IsZero() {
+			return err
+		}
+
+		if !(PlayerRef{Team: event.Team, Player: event.Player}).isValid(m.Info) {
+			return err
+		}
+
+		if event.Game < 0 || event.Game >= len(m.Games) {
+			return err
+		}
+
+		points := m.Games[event.Game].Points
+
+		if event.Point < 0 || event.Point > len(points) {
+			return err
+		}
+
+		if event.Type.AwardsPoint() && (event.Point == len(points) || points[event.Point] != opponent(event.Team)) {
+			return err
+		}
+
+		if event.Type == EventBlackCard && (m.Info.Result != ResultDisqualified || m.Info.ResultTeam != event.Team) {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,158 @@
+package parser
+
+import "testing"
+
+func parseEvents(events string, result string) (Match, error) {
+	return Parse(
+		`{
+			"info": {
+				"mode": 21,
+				"team1": [{ "country": "DK", "player": "A" }],
+				"team2": [{ "country": "TW", "player": "B" }],
+				"start": 1679684400,
+				"end": 1679686320
+				` + result + `
+			},
+			"games": [{ "points": [1, 2, 2] }],
+			"events": ` + events + `
+		}`)
+}
+
+func TestEvents(t *testing.T) {
+	match, err := parseEvents(
+		`[
+			{ "type": "yellow", "t": 1679684460, "team": 1, "player": 0, "game": 0, "point": 1 },
+			{ "type": "red", "t": 1679684520, "team": 1, "player": 0, "game": 0, "point": 2 },
+			{ "type": "medical", "t": 1679684580, "team": 2, "player": 0, "game": 0, "point": 3 }
+		]`, "")
+
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assertEqual(t, len(match.EventsOf(Team1)), 2)
+	assertEqual(t, match.EventsOf(Team2)[0].Type, EventMedical)
+}
+
+func TestEventsInvalid(t *testing.T) {
+	tests := []struct {
+		events string
+		result string
+	}{
+		// awarded rally was won by the carded team
+		{`[{ "type": "red", "t": 1679684520, "team": 2, "player": 0, "game": 0, "point": 2 }]`, ""},
+		// awarded rally was not played yet
+		{`[{ "type": "fault", "t": 1679684520, "team": 1, "player": 0, "game": 0, "point": 3 }]`, ""},
+		// unknown game
+		{`[{ "type": "yellow", "t": 1679684520, "team": 1, "player": 0, "game": 1, "point": 0 }]`, ""},
+		// unknown player
+		{`[{ "type": "yellow", "t": 1679684520, "team": 1, "player": 1, "game": 0, "point": 0 }]`, ""},
+		// black card without disqualification
+		{`[{ "type": "black", "t": 1679684520, "team": 1, "player": 0, "game": 0, "point": 3 }]`, ""},
+		// black card for the other team
+		{`[{ "type": "black", "t": 1679684520, "team": 1, "player": 0, "game": 0, "point": 3 }]`, `, "result": "disqualified", "resultTeam": 2`},
+	}
+
+	for _, test := range tests {
+		if _, err := parseEvents(test.events, test.result); err == nil {
+			t.Errorf("Expected error for events %s", test.events)
+		}
+	}
+
+	if _, err := parseEvents(`[{ "type": "black", "t": 1679684520, "team": 1, "player": 0, "game": 0, "point": 3 }]`, `, "result": "disqualified", "resultTeam": 1`); err != nil {
+		t.Error(err.Error())
+	}
+}
+
+func TestStateEvents(t *testing.T) {
+	match, err := parseEvents(`[]`, "")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// the parsed match is finished, continue the first game
+	match.Info.End = UnixTime{}
+
+	state, err := NewStateFromMatch(match)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assertEqual(t, state.AddEvent(Event{Type: EventYellowCard, Team: Team1}), nil)
+	assertEqual(t, state.AddEvent(Event{Type: EventServiceFault, Team: Team1}), nil)
+
+	score1, score2 := state.Score()
+	assertEqual(t, score1, 1)
+	assertEqual(t, score2, 3)
+
+	m, err := state.Match()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assertEqual(t, len(m.Events), 2)
+	assertEqual(t, m.Events[0].Point, 3)
+	assertEqual(t, m.Events[1].Point, 3)
+
+	// undoing the awarded rally removes the service fault
+	state.Undo()
+
+	m, err = state.Match()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assertEqual(t, len(m.Events), 1)
+	assertEqual(t, m.Events[0].Type, EventYellowCard)
+
+	state.Undo()
+
+	m, err = state.Match()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// the yellow card is moved to the next rally
+	assertEqual(t, m.Events[0].Point, 2)
+
+	assertEqual(t, state.AddEvent(Event{Type: EventBlackCard, Team: Team2}), nil)
+
+	m, err = state.Match()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assertEqual(t, m.Winner, Team1)
+	assertEqual(t, m.Info.Result, ResultDisqualified)
+
+	// undoing the black card continues the match
+	state.Undo()
+
+	m, err = state.Match()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assertEqual(t, m.Winner, Unknown)
+	assertEqual(t, m.Info.Result, Result(""))
+	assertEqual(t, len(m.Events), 1)
+
+	// no rally is removed
+	score1, score2 = state.Score()
+	assertEqual(t, score1, 1)
+	assertEqual(t, score2, 1)
+
+	// no rally is played after a disqualification
+	assertEqual(t, state.AddEvent(Event{Type: EventBlackCard, Team: Team1}), nil)
+	assertEqual(t, state.Winner(), Team2)
+	assertEqual(t, state.Finished(), true)
+	assertEqual(t, state.GamePoint(), Unknown)
+
+	if err := state.Apply(Team1); err == nil {
+		t.Error("Expected error for rally after disqualification")
+	}
+
+	score1, score2 = state.Score()
+	assertEqual(t, score1, 1)
+	assertEqual(t, score2, 1)
+}
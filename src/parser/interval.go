@@ -0,0 +1,63 @@
+package parser
+
+// Calculates the index of the rally after which the interval of a game is
+// taken, i.e. the rally in which the leading team reaches the interval score.
+// Returns -1 if the interval was not reached yet or the rules have none.
+func calculateIntervalPoint(points []TeamID, rules RuleSet) int {
+	if rules.IntervalPoints == 0 {
+		return -1
+	}
+
+	scoreTeam1 := 0
+	scoreTeam2 := 0
+
+	for j, point := range points {
+		if point == Team1 {
+			scoreTeam1++
+		} else if point == Team2 {
+			scoreTeam2++
+		}
+
+		if max(scoreTeam1, scoreTeam2) == rules.IntervalPoints {
+			return j
+		}
+	}
+
+	return -1
+}
+
+// Calculates the intervals of each game and whether the match is currently
+// in an interval. Teams change ends after each game and in the interval of
+// the deciding game.
+func calculateIntervals(m *Match, rules RuleSet) {
+	for i := range m.Games {
+		game := &m.Games[i]
+
+		game.IntervalPoint = calculateIntervalPoint(game.Points, rules)
+		game.ChangeEndsPoint = -1
+		game.GameInterval = game.Winner != Unknown && i != len(m.Games)-1
+
+		if i == rules.MaxGames()-1 {
+			game.ChangeEndsPoint = game.IntervalPoint
+		}
+	}
+
+	m.Interval = false
+	m.ChangeEnds = false
+
+	if m.Finished || len(m.Games) == 0 {
+		return
+	}
+
+	last := len(m.Games) - 1
+	game := m.Games[last]
+
+	if len(game.Points) == 0 {
+		// the previous game has ended and the next one did not start yet
+		m.Interval = last > 0
+		m.ChangeEnds = last > 0
+	} else if game.IntervalPoint == len(game.Points)-1 {
+		m.Interval = true
+		m.ChangeEnds = game.ChangeEndsPoint == game.IntervalPoint
+	}
+}
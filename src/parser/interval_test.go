@@ -0,0 +1,69 @@
+package parser
+
+import (
+	"fmt"
+	"testing"
+)
+
+func parseGames(mode int, games string) (Match, error) {
+	return Parse(
+		`{
+			"info": {
+				"mode": ` + fmt.Sprint(mode) + `,
+				"team1": [{ "country": "DK", "player": "A" }],
+				"team2": [{ "country": "TW", "player": "B" }],
+				"start": 1679684400,
+				"end": null
+			},
+			"games": ` + games + `
+		}`)
+}
+
+func TestIntervalMidGame(t *testing.T) {
+	match, err := parseGames(21, `[{ "points": [1, 1, 1, 1, 1, 2, 2, 1, 1, 1, 1, 1, 1] }]`)
+
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assertEqual(t, match.Games[0].IntervalPoint, 12)
+	assertEqual(t, match.Games[0].ChangeEndsPoint, -1)
+	assertEqual(t, match.Interval, true)
+	assertEqual(t, match.ChangeEnds, false)
+}
+
+func TestIntervalDecidingGame(t *testing.T) {
+	match, err := parseGames(11, `[
+		{ "points": [1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1] },
+		{ "points": [2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2] },
+		{ "points": [1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1] },
+		{ "points": [2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2] },
+		{ "points": [1, 2, 2, 2, 2, 2, 2] }
+	]`)
+
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assertEqual(t, match.Games[0].GameInterval, true)
+	assertEqual(t, match.Games[4].GameInterval, false)
+	assertEqual(t, match.Games[4].IntervalPoint, 6)
+	assertEqual(t, match.Games[4].ChangeEndsPoint, 6)
+	assertEqual(t, match.Interval, true)
+	assertEqual(t, match.ChangeEnds, true)
+}
+
+func TestIntervalBetweenGames(t *testing.T) {
+	match, err := parseGames(21, `[
+		{ "points": [1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1] },
+		{ "points": [] }
+	]`)
+
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assertEqual(t, match.Games[0].IntervalPoint, 10)
+	assertEqual(t, match.Interval, true)
+	assertEqual(t, match.ChangeEnds, true)
+}
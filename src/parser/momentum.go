@@ -0,0 +1,150 @@
+package parser
+
+// Score is the score of a game after a rally.
+type Score struct {
+	Team1 int `json:"team1"`
+	Team2 int `json:"team2"`
+}
+
+// Returns the team that leads, or Unknown if the score is tied.
+func (s Score) Leader() TeamID {
+	if s.Team1 > s.Team2 {
+		return Team1
+	} else if s.Team2 > s.Team1 {
+		return Team2
+	}
+
+	return Unknown
+}
+
+// Returns the lead of the given team, which is negative if the team is behind.
+func (s Score) Lead(team TeamID) int {
+	if team == Team1 {
+		return s.Team1 - s.Team2
+	}
+
+	return s.Team2 - s.Team1
+}
+
+// Calculates the score after every rally.
+func calculateScoresInGame(points []TeamID) []Score {
+	scores := make([]Score, 0, len(points))
+	score := Score{}
+
+	for _, point := range points {
+		if point == Team1 {
+			score.Team1++
+		} else if point == Team2 {
+			score.Team2++
+		}
+
+		scores = append(scores, score)
+	}
+
+	return scores
+}
+
+// Calculates how often the lead passed from one team to the other.
+// Ties in between do not count as a lead change on their own.
+func calculateLeadChangesInGame(scores []Score) int {
+	changes := 0
+	leader := Unknown
+
+	for _, score := range scores {
+		current := score.Leader()
+
+		if current == Unknown {
+			continue
+		}
+
+		if leader != Unknown && current != leader {
+			changes++
+		}
+
+		leader = current
+	}
+
+	return changes
+}
+
+// Calculates how often the score was tied, not counting 0:0.
+func calculateTiesInGame(scores []Score) int {
+	ties := 0
+
+	for _, score := range scores {
+		if score.Leader() == Unknown {
+			ties++
+		}
+	}
+
+	return ties
+}
+
+// Calculates the largest lead of the given team.
+func calculateMaxLeadInGame(scores []Score, team TeamID) int {
+	maxLead := 0
+
+	for _, score := range scores {
+		maxLead = max(maxLead, score.Lead(team))
+	}
+
+	return maxLead
+}
+
+// Calculates the largest deficit the given team came back from to win
+// the game. Returns 0 if the team did not win the game.
+func calculateComebackInGame(scores []Score, team TeamID, winner TeamID) int {
+	if team != winner {
+		return 0
+	}
+
+	return calculateMaxLeadInGame(scores, opponent(team))
+}
+
+func calculateLeadChangesInMatch(games []Game) int {
+	sum := 0
+
+	for _, game := range games {
+		sum += game.LeadChanges
+	}
+
+	return sum
+}
+
+func calculateTiesInMatch(games []Game) int {
+	sum := 0
+
+	for _, game := range games {
+		sum += game.Ties
+	}
+
+	return sum
+}
+
+func calculateMaxLeadInMatch(games []Game, team TeamID) int {
+	maxLead := 0
+
+	for _, game := range games {
+		if team == Team1 {
+			maxLead = max(maxLead, game.Team1MaxLead)
+		} else if team == Team2 {
+			maxLead = max(maxLead, game.Team2MaxLead)
+		}
+	}
+
+	return maxLead
+}
+
+func calculateComebackInMatch(games []Game, team TeamID) int {
+	comeback := 0
+
+	for _, game := range games {
+		if team == Team1 {
+			comeback = max(comeback, game.Team1Comeback)
+		} else if team == Team2 {
+			comeback = max(comeback, game.Team2Comeback)
+		}
+	}
+
+	return comeback
+}
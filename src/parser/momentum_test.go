@@ -0,0 +1,33 @@
+package parser
+
+import "testing"
+
+func TestMomentum(t *testing.T) {
+	// 0:1, 1:1, 2:1, 2:2, 2:3, 2:4, 2:5, 3:5, ...
+	match, err := parseGames(11, `[
+		{ "points": [2, 1, 1, 2, 2, 2, 2, 1, 1, 1, 1, 1, 1, 1, 1, 1] },
+		{ "points": [2, 2, 2] }
+	]`)
+
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	game := match.Games[0]
+
+	assertEqual(t, game.Winner, Team1)
+	assertEqual(t, len(game.Scores), 16)
+	assertEqual(t, game.Scores[6], Score{Team1: 2, Team2: 5})
+	assertEqual(t, game.LeadChanges, 3)
+	assertEqual(t, game.Ties, 3)
+	assertEqual(t, game.Team1MaxLead, 6)
+	assertEqual(t, game.Team2MaxLead, 3)
+	assertEqual(t, game.Team1Comeback, 3)
+	assertEqual(t, game.Team2Comeback, 0)
+
+	assertEqual(t, match.LeadChanges, 3)
+	assertEqual(t, match.Ties, 3)
+	assertEqual(t, match.Team1MaxLead, 6)
+	assertEqual(t, match.Team2MaxLead, 3)
+	assertEqual(t, match.Team1Comeback, 3)
+}
@@ -3,7 +3,6 @@ package parser
 import (
 	"encoding/json"
 	"errors"
-	"fmt"
 	"time"
 
 	"github.com/biter777/countries"
@@ -19,6 +18,10 @@ const (
 	ERR_INVALID_TIMES   = "times are invalid"
 	ERR_INVALID_POINT   = "point is invalid"
 	ERR_INVALID_GAME    = "game is invalid"
+	ERR_INVALID_SERVER  = "server is invalid"
+	ERR_INVALID_RULES   = "rules are invalid"
+	ERR_INVALID_RESULT  = "result is invalid"
+	ERR_INVALID_EVENT   = "event is invalid"
 )
 
 func max(a int, b int) int {
@@ -29,6 +32,14 @@ func max(a int, b int) int {
 	}
 }
 
+func min(a int, b int) int {
+	if a <= b {
+		return a
+	} else {
+		return b
+	}
+}
+
 type TeamID int
 
 const (
@@ -41,65 +52,30 @@ type Mode int
 
 const (
 	Mode11 Mode = 11
+	Mode15 Mode = 15
 	Mode21 Mode = 21
 )
 
 const (
-	Mode11WinPoints = 11
-	Mode11TiePoints = 15
-	Mode11WinGames  = 3
-	Mode11MaxGames  = 5
-
-	Mode21WinPoints = 21
-	Mode21TiePoints = 30
-	Mode21WinGames  = 2
-	Mode21MaxGames  = 3
+	Mode11WinPoints      = 11
+	Mode11TiePoints      = 15
+	Mode11WinGames       = 3
+	Mode11MaxGames       = 5
+	Mode11IntervalPoints = 6
+
+	Mode15WinPoints      = 15
+	Mode15TiePoints      = 21
+	Mode15WinGames       = 2
+	Mode15MaxGames       = 3
+	Mode15IntervalPoints = 8
+
+	Mode21WinPoints      = 21
+	Mode21TiePoints      = 30
+	Mode21WinGames       = 2
+	Mode21MaxGames       = 3
+	Mode21IntervalPoints = 11
 )
 
-func numWinPoints(mode Mode) int {
-	switch mode {
-	case Mode11:
-		return Mode11WinPoints
-	case Mode21:
-		return Mode21WinPoints
-	default:
-		return -1
-	}
-}
-
-func numTiePoints(mode Mode) int {
-	switch mode {
-	case Mode11:
-		return Mode11TiePoints
-	case Mode21:
-		return Mode21TiePoints
-	default:
-		return -1
-	}
-}
-
-func numWinGames(mode Mode) int {
-	switch mode {
-	case Mode11:
-		return Mode11WinGames
-	case Mode21:
-		return Mode21WinGames
-	default:
-		return -1
-	}
-}
-
-func numMaxGames(mode Mode) int {
-	switch mode {
-	case Mode11:
-		return Mode11MaxGames
-	case Mode21:
-		return Mode21MaxGames
-	default:
-		return -1
-	}
-}
-
 func count(slice []TeamID, team TeamID) int {
 	cnt := 0
 
@@ -128,32 +104,57 @@ type MatchInfo struct {
 	Team2 Team     `json:"team2"`
 	Start UnixTime `json:"start"`
 	End   UnixTime `json:"end"`
+	// how the match ended and the team that retired, gave a walkover
+	// or was disqualified
+	Result     Result `json:"result,omitempty"`
+	ResultTeam TeamID `json:"resultTeam,omitempty"`
+}
+
+// Stats are derived from the rallies of a game or of a whole match. They are
+// not part of the JSON of a match, but of the match returned by the API.
+type Stats struct {
+	Duration        int `json:"duration"`
+	PointsPlayed    int `json:"pointsPlayed"`
+	Team1PointsWon  int `json:"team1PointsWon"`
+	Team1ConsPoints int `json:"team1ConsPoints"`
+	Team1GamePoints int `json:"team1GamePoints"`
+	Team2PointsWon  int `json:"team2PointsWon"`
+	Team2ConsPoints int `json:"team2ConsPoints"`
+	Team2GamePoints int `json:"team2GamePoints"`
+	LeadChanges     int `json:"leadChanges"`
+	Ties            int `json:"ties"`
+	Team1MaxLead    int `json:"team1MaxLead"`
+	Team1Comeback   int `json:"team1Comeback"`
+	Team2MaxLead    int `json:"team2MaxLead"`
+	Team2Comeback   int `json:"team2Comeback"`
 }
 
 type Game struct {
-	Points          []TeamID `json:"points"`
-	Winner          TeamID   `json:"-"`
-	PointsPlayed    int      `json:"-"`
-	Team1PointsWon  int      `json:"-"`
-	Team1ConsPoints int      `json:"-"`
-	Team1GamePoints int      `json:"-"`
-	Team2PointsWon  int      `json:"-"`
-	Team2ConsPoints int      `json:"-"`
-	Team2GamePoints int      `json:"-"`
+	Points []TeamID `json:"points"`
+	// first server of the game and the receiver standing in the right court
+	Server          *PlayerRef `json:"server,omitempty"`
+	Receiver        *PlayerRef `json:"receiver,omitempty"`
+	Serves          []Serve    `json:"-"`
+	NextServe       *Serve     `json:"-"`
+	Rallies         []Rally    `json:"-"`
+	AvgRallyGap     int        `json:"-"`
+	IntervalPoint   int        `json:"-"`
+	ChangeEndsPoint int        `json:"-"`
+	GameInterval    bool       `json:"-"`
+	Winner          TeamID     `json:"-"`
+	Scores          []Score    `json:"-"`
+	Stats           `json:"-"`
 }
 
 type Match struct {
-	Info            MatchInfo `json:"info"`
-	Games           []Game    `json:"games"`
-	Winner          TeamID    `json:"-"`
-	Duration        int       `json:"-"`
-	PointsPlayed    int       `json:"-"`
-	Team1PointsWon  int       `json:"-"`
-	Team1ConsPoints int       `json:"-"`
-	Team1GamePoints int       `json:"-"`
-	Team2PointsWon  int       `json:"-"`
-	Team2ConsPoints int       `json:"-"`
-	Team2GamePoints int       `json:"-"`
+	Info       MatchInfo `json:"info"`
+	Games      []Game    `json:"games"`
+	Events     []Event   `json:"events,omitempty"`
+	Winner     TeamID    `json:"-"`
+	Finished   bool      `json:"-"`
+	Interval   bool      `json:"-"`
+	ChangeEnds bool      `json:"-"`
+	Stats      `json:"-"`
 }
 
 func (c Country) isValid() bool {
@@ -169,10 +170,12 @@ func (p Player) isValid() bool {
 }
 
 func (m Mode) isValid() bool {
-	return m == Mode11 || m == Mode21
+	_, ok := Rules(m)
+	return ok
 }
 
-func (t Team) isValid() bool {
+// Returns true if all players of the team have a name and a valid country.
+func (t Team) IsValid() bool {
 	for _, player := range t {
 		if !player.isValid() {
 			return false
@@ -184,39 +187,44 @@ func (t Team) isValid() bool {
 
 func (m MatchInfo) validate() error {
 	if !m.Mode.isValid() {
-		return errors.New(ERR_INVALID_MODE)
+		return newValidationError("info.mode", ERR_INVALID_MODE)
+	}
+
+	if !m.Team1.IsValid() {
+		return newValidationError("info.team1", ERR_INVALID_TEAMS)
 	}
 
-	if !m.Team1.isValid() || !m.Team2.isValid() {
-		return errors.New(ERR_INVALID_TEAMS)
+	if !m.Team2.IsValid() {
+		return newValidationError("info.team2", ERR_INVALID_TEAMS)
 	}
 
-	if len(m.Team1) != len(m.Team2) || (len(m.Team1) != 1 && len(m.Team1) != 2) {
-		return errors.New(ERR_INVALID_TEAMS)
+	if len(m.Team1) != 1 && len(m.Team1) != 2 {
+		return newValidationError("info.team1", ERR_INVALID_TEAMS)
+	}
+
+	if len(m.Team1) != len(m.Team2) {
+		return newValidationError("info.team2", ERR_INVALID_TEAMS)
 	}
 
 	if m.Start.IsZero() {
-		return errors.New(ERR_INVALID_TIMES)
+		return newValidationError("info.start", ERR_INVALID_TIMES)
 	}
 
 	// m.End is allowed to be zero for running matches
 	if !m.End.IsZero() && !m.Start.Time.Before(m.End.Time) {
-		return errors.New(ERR_INVALID_TIMES)
+		return newValidationError("info.end", ERR_INVALID_TIMES)
 	}
 
 	return nil
 }
 
-func (g *Game) validate(mode Mode, endTime UnixTime) error {
-	winPoints := numWinPoints(mode)
-	tiePoints := numTiePoints(mode)
-
+func (g *Game) validate(rules RuleSet, endTime UnixTime) error {
 	scoreTeam1 := 0
 	scoreTeam2 := 0
 
 	for j, point := range g.Points {
 		if !(point == Team1 || point == Team2) {
-			return errors.New(ERR_INVALID_POINT)
+			return newGameError("games.points", -1, j, ERR_INVALID_POINT)
 		}
 
 		if point == Team1 {
@@ -225,25 +233,12 @@ func (g *Game) validate(mode Mode, endTime UnixTime) error {
 			scoreTeam2++
 		}
 
-		// check if Team1 has won
-		if scoreTeam1 == tiePoints || (scoreTeam1 >= winPoints && scoreTeam1-scoreTeam2 >= 2) {
-			g.Winner = Team1
+		if winner := rules.gameWinner(scoreTeam1, scoreTeam2); winner != Unknown {
+			g.Winner = winner
 
 			// check if points were counted afterwards
 			if j != len(g.Points)-1 {
-				return errors.New(ERR_INVALID_POINT)
-			}
-
-			break
-		}
-
-		// check if Team2 has won
-		if scoreTeam2 == tiePoints || (scoreTeam2 >= winPoints && scoreTeam2-scoreTeam1 >= 2) {
-			g.Winner = Team2
-
-			// check if points were counted afterwards
-			if j != len(g.Points)-1 {
-				return errors.New(ERR_INVALID_POINT)
+				return newGameError("games.points", -1, j+1, ERR_INVALID_POINT)
 			}
 
 			break
@@ -258,23 +253,36 @@ func (g *Game) validate(mode Mode, endTime UnixTime) error {
 	g.Team1ConsPoints = calculateConsecutivePointsInGame(g.Points, Team1)
 	g.Team2ConsPoints = calculateConsecutivePointsInGame(g.Points, Team2)
 
-	g.Team1GamePoints = calculateGamePointsInGame(g.Points, Team1, mode)
-	g.Team2GamePoints = calculateGamePointsInGame(g.Points, Team2, mode)
+	g.Team1GamePoints = calculateGamePointsInGame(g.Points, Team1, rules)
+	g.Team2GamePoints = calculateGamePointsInGame(g.Points, Team2, rules)
+
+	g.Scores = calculateScoresInGame(g.Points)
+	g.LeadChanges = calculateLeadChangesInGame(g.Scores)
+	g.Ties = calculateTiesInGame(g.Scores)
+	g.Team1MaxLead = calculateMaxLeadInGame(g.Scores, Team1)
+	g.Team1Comeback = calculateComebackInGame(g.Scores, Team1, g.Winner)
+	g.Team2MaxLead = calculateMaxLeadInGame(g.Scores, Team2)
+	g.Team2Comeback = calculateComebackInGame(g.Scores, Team2, g.Winner)
 
 	return nil
 }
 
-func validateGames(match *Match, mode Mode, endTime UnixTime) error {
-	winGames := numWinGames(mode)
-	maxGames := numMaxGames(mode)
+func validateGames(match *Match, rules RuleSet, endTime UnixTime) error {
+	maxGames := rules.MaxGames()
 
 	if len(match.Games) > maxGames {
-		return errors.New(ERR_INVALID_GAME)
+		return newGameError("games", maxGames, -1, ERR_INVALID_GAME)
 	}
 
 	// Validate and calculate statistics for each game
 	for i := range match.Games {
-		if err := (&match.Games[i]).validate(mode, endTime); err != nil {
+		if err := (&match.Games[i]).validate(rules, endTime); err != nil {
+			var validationError *ValidationError
+
+			if errors.As(err, &validationError) {
+				validationError.GameIndex = i
+			}
+
 			return err
 		}
 	}
@@ -285,8 +293,8 @@ func validateGames(match *Match, mode Mode, endTime UnixTime) error {
 		winner = append(winner, game.Winner)
 
 		// If there is no winner for this game yet, then the game is still running and no later game must exist
-		if len(winner) < i+1 && len(match.Games) > i+1 {
-			return errors.New(ERR_INVALID_GAME)
+		if game.Winner == Unknown && len(match.Games) > i+1 {
+			return newGameError("games", i+1, -1, ERR_INVALID_GAME)
 		}
 
 		numWinsTeam1 := count(winner, Team1)
@@ -294,9 +302,14 @@ func validateGames(match *Match, mode Mode, endTime UnixTime) error {
 
 		// If there is a winner for this match, then no later games must exist.
 		// Also, the end time must be set.
-		if (numWinsTeam1 == winGames && numWinsTeam2 < winGames || numWinsTeam2 == winGames && numWinsTeam1 < winGames) &&
-			(len(match.Games) > i+1 || endTime.IsZero()) {
-			return errors.New(ERR_INVALID_GAME)
+		if rules.matchWinner(numWinsTeam1, numWinsTeam2) != Unknown {
+			if len(match.Games) > i+1 {
+				return newGameError("games", i+1, -1, ERR_INVALID_GAME)
+			}
+
+			if endTime.IsZero() {
+				return newValidationError("info.end", ERR_INVALID_TIMES)
+			}
 		}
 	}
 
@@ -308,10 +321,33 @@ func (m *Match) validate() error {
 		return err
 	}
 
-	if err := validateGames(m, m.Info.Mode, m.Info.End); err != nil {
+	// m.Info.validate ensures that the mode is registered
+	rules, _ := Rules(m.Info.Mode)
+
+	if err := validateGames(m, rules, m.Info.End); err != nil {
 		return err
 	}
 
+	if err := validateRallies(m.Games); err != nil {
+		return err
+	}
+
+	if err := validateServes(m); err != nil {
+		return err
+	}
+
+	m.Winner = rules.matchWinner(calculateGamesWonInMatch(m.Games, Team1), calculateGamesWonInMatch(m.Games, Team2))
+
+	if err := validateResult(m); err != nil {
+		return err
+	}
+
+	if err := validateEvents(m); err != nil {
+		return err
+	}
+
+	calculateIntervals(m, rules)
+
 	if m.Info.End.IsZero() {
 		m.Info.End.Time = time.Now()
 	}
@@ -330,14 +366,12 @@ func (m *Match) validate() error {
 	m.Team2PointsWon = calculatePointsWonInMatch(m.Games, Team2)
 	m.Team2ConsPoints = calculateConsecutivePointsInMatch(m.Games, Team2)
 	m.Team2GamePoints = calculateGamePointsInMatch(m.Games, Team2)
-
-	if calculateGamesWonInMatch(m.Games, Team1) == numWinGames(m.Info.Mode) {
-		m.Winner = Team1
-	} else if calculateGamesWonInMatch(m.Games, Team2) == numWinGames(m.Info.Mode) {
-		m.Winner = Team2
-	} else {
-		m.Winner = Unknown
-	}
+	m.LeadChanges = calculateLeadChangesInMatch(m.Games)
+	m.Ties = calculateTiesInMatch(m.Games)
+	m.Team1MaxLead = calculateMaxLeadInMatch(m.Games, Team1)
+	m.Team1Comeback = calculateComebackInMatch(m.Games, Team1)
+	m.Team2MaxLead = calculateMaxLeadInMatch(m.Games, Team2)
+	m.Team2Comeback = calculateComebackInMatch(m.Games, Team2)
 
 	return nil
 }
@@ -375,15 +409,12 @@ func calculateConsecutivePointsInGame(points []TeamID, team TeamID) int {
 //	21 : 21
 //	22 : 21  <- Game point for A
 //	23 : 21  <- A wins
-func calculateGamePointsInGame(points []TeamID, team TeamID, mode Mode) int {
+func calculateGamePointsInGame(points []TeamID, team TeamID, rules RuleSet) int {
 	gamePoints := 0
 
 	ownScore := 0
 	otherScore := 0
 
-	winPoints := numWinPoints(mode)
-	tiePoints := numTiePoints(mode)
-
 	for _, point := range points {
 		if point == team {
 			ownScore++
@@ -391,8 +422,7 @@ func calculateGamePointsInGame(points []TeamID, team TeamID, mode Mode) int {
 			otherScore++
 		}
 
-		if (ownScore == winPoints-1 && otherScore < ownScore) ||
-			(ownScore == tiePoints-1 && otherScore < tiePoints) {
+		if rules.gameWinner(ownScore, otherScore) == Unknown && rules.hasWon(ownScore+1, otherScore) {
 			gamePoints++
 		}
 	}
@@ -466,11 +496,11 @@ func Parse(data string) (Match, error) {
 	var match Match
 
 	if err := json.Unmarshal([]byte(data), &match); err != nil {
-		return match, fmt.Errorf("%s %s", ERR_INVALID_JSON, err.Error())
+		return match, newJSONError(err)
 	}
 
 	if err := match.validate(); err != nil {
-		return match, fmt.Errorf("%s: %s", ERR_INVALID_MATCH, err.Error())
+		return match, err
 	}
 
 	return match, nil
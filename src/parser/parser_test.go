@@ -1,7 +1,10 @@
 package parser
 
 import (
+	"encoding/json"
+	"errors"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -80,3 +83,394 @@ func TestAxelsenVsChou(t *testing.T) {
 	assertEqual(t, match.Team2ConsPoints, 5)
 	assertEqual(t, match.Team2GamePoints, 4)
 }
+
+func TestServesDoubles(t *testing.T) {
+	match, err := Parse(
+		`{
+			"info": {
+				"mode": 21,
+				"team1": [
+					{ "country": "ID", "player": "A" },
+					{ "country": "ID", "player": "B" }
+				],
+				"team2": [
+					{ "country": "CN", "player": "C" },
+					{ "country": "CN", "player": "D" }
+				],
+				"start": 1679684400,
+				"end": null
+			},
+			"games": [
+				{
+					"points": [1, 1, 2, 2, 1],
+					"server": { "team": 1, "player": 0 },
+					"receiver": { "team": 2, "player": 0 }
+				}
+			]
+		}`)
+
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	a, b := match.Info.Team1[0], match.Info.Team1[1]
+	c, d := match.Info.Team2[0], match.Info.Team2[1]
+
+	want := []Serve{
+		{Team: Team1, Server: a, Receiver: c, Court: RightCourt},
+		{Team: Team1, Server: a, Receiver: d, Court: LeftCourt},
+		{Team: Team1, Server: a, Receiver: c, Court: RightCourt},
+		{Team: Team2, Server: d, Receiver: b, Court: LeftCourt},
+		{Team: Team2, Server: d, Receiver: a, Court: RightCourt},
+	}
+
+	assertEqual(t, len(match.Games[0].Serves), len(want))
+
+	for i := range want {
+		assertEqual(t, match.Games[0].Serves[i], want[i])
+	}
+}
+
+func TestServesDoublesNextGame(t *testing.T) {
+	points := strings.TrimSuffix(strings.Repeat("1, ", 21), ", ")
+
+	match, err := Parse(
+		`{
+			"info": {
+				"mode": 21,
+				"team1": [
+					{ "country": "ID", "player": "A" },
+					{ "country": "ID", "player": "B" }
+				],
+				"team2": [
+					{ "country": "CN", "player": "C" },
+					{ "country": "CN", "player": "D" }
+				],
+				"start": 1679684400,
+				"end": null
+			},
+			"games": [
+				{
+					"points": [` + points + `],
+					"server": { "team": 1, "player": 0 },
+					"receiver": { "team": 2, "player": 0 }
+				},
+				{ "points": [1, 2] }
+			]
+		}`)
+
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// the players that serve and receive first are not guessed in doubles
+	assertEqual(t, len(match.Games[0].Serves), 21)
+	assertEqual(t, len(match.Games[1].Serves), 0)
+	assertEqual(t, match.NextServe() == nil, true)
+}
+
+func TestServesSinglesNextGame(t *testing.T) {
+	match, err := Parse(
+		`{
+			"info": {
+				"mode": 11,
+				"team1": [{ "country": "DK", "player": "A" }],
+				"team2": [{ "country": "TW", "player": "B" }],
+				"start": 1679684400,
+				"end": null
+			},
+			"games": [
+				{ "points": [2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2] },
+				{ "points": [1, 2, 2] }
+			]
+		}`)
+
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// first server of the first game is unknown
+	assertEqual(t, len(match.Games[0].Serves), 0)
+
+	// winner of the first game serves first
+	assertEqual(t, match.Games[1].Serves[0].Team, Team2)
+	assertEqual(t, match.Games[1].Serves[0].Court, RightCourt)
+	assertEqual(t, match.Games[1].Serves[1].Team, Team1)
+	assertEqual(t, match.Games[1].Serves[1].Court, LeftCourt)
+	assertEqual(t, match.Games[1].Serves[2].Team, Team2)
+	assertEqual(t, match.Games[1].Serves[2].Court, LeftCourt)
+
+	// the winner of the last rally serves next
+	assertEqual(t, match.NextServe().Team, Team2)
+	assertEqual(t, match.NextServe().Court, RightCourt)
+	assertEqual(t, match.Games[0].NextServe == nil, true)
+
+	// an explicit first server must be the winner of the previous game
+	_, err = Parse(
+		`{
+			"info": {
+				"mode": 11,
+				"team1": [{ "country": "DK", "player": "A" }],
+				"team2": [{ "country": "TW", "player": "B" }],
+				"start": 1679684400,
+				"end": null
+			},
+			"games": [
+				{ "points": [2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2] },
+				{ "points": [], "server": { "team": 1, "player": 0 } }
+			]
+		}`)
+
+	if err == nil {
+		t.Error("Expected error for invalid server")
+	}
+}
+
+func TestCustomRules(t *testing.T) {
+	if err := Register(RuleSet{Mode: 1021, Name: "21 Pts., 1 game", WinPoints: 21, TiePoints: 30, WinBy: 2, WinGames: 1, IntervalPoints: 11}); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// built-in rules cannot be replaced
+	if err := Register(RuleSet{Mode: Mode21, Name: "21", WinPoints: 21, WinBy: 2, WinGames: 1}); err == nil {
+		t.Error("Expected error for registering an existing mode")
+	}
+
+	match, err := Parse(
+		`{
+			"info": {
+				"mode": 1021,
+				"team1": [{ "country": "DK", "player": "A" }],
+				"team2": [{ "country": "TW", "player": "B" }],
+				"start": 1679684400,
+				"end": 1679686320
+			},
+			"games": [
+				{ "points": [1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1] }
+			]
+		}`)
+
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assertEqual(t, match.Winner, Team1)
+	assertEqual(t, match.Team1GamePoints, 1)
+}
+
+func TestRulesWithoutCap(t *testing.T) {
+	rules := RuleSet{Mode: 1011, Name: "11 Pts., no cap", WinPoints: 11, WinBy: 2, WinGames: 1}
+
+	if !rules.isValid() {
+		t.Fatal("Expected rules without cap to be valid")
+	}
+
+	assertEqual(t, rules.gameWinner(0, 0), Unknown)
+	assertEqual(t, rules.gameWinner(1, 0), Unknown)
+	assertEqual(t, rules.gameWinner(11, 9), Team1)
+	assertEqual(t, rules.gameWinner(30, 29), Unknown)
+	assertEqual(t, rules.gameWinner(30, 32), Team2)
+}
+
+func TestMode15(t *testing.T) {
+	match, err := Parse(
+		`{
+			"info": {
+				"mode": 15,
+				"team1": [{ "country": "DK", "player": "A" }],
+				"team2": [{ "country": "TW", "player": "B" }],
+				"start": 1679684400,
+				"end": null
+			},
+			"games": [
+				{ "points": [1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 1, 2, 1, 1] },
+				{ "points": [] }
+			]
+		}`)
+
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assertEqual(t, match.Games[0].Winner, Team1)
+	assertEqual(t, match.Games[0].Team1PointsWon, 17)
+	assertEqual(t, match.Games[0].Team1GamePoints, 16)
+	assertEqual(t, match.Games[0].Team2GamePoints, 0)
+	assertEqual(t, match.Winner, Unknown)
+}
+
+func TestRallyMetadata(t *testing.T) {
+	match, err := Parse(
+		`{
+			"info": {
+				"mode": 21,
+				"team1": [{ "country": "DK", "player": "A" }],
+				"team2": [{ "country": "TW", "player": "B" }],
+				"start": 1679684400,
+				"end": null
+			},
+			"games": [
+				{
+					"points": [
+						{ "t": 1679684430, "winner": 1, "shots": 14, "note": "net cord" },
+						2,
+						{ "t": 1679684490, "winner": 2 },
+						{ "t": 1679684520, "winner": 1, "shots": 3 }
+					]
+				}
+			]
+		}`)
+
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	game := match.Games[0]
+
+	assertEqual(t, len(game.Points), 4)
+	assertEqual(t, game.Points[1], Team2)
+	assertEqual(t, game.Team1PointsWon, 2)
+	assertEqual(t, game.Rallies[0].Shots, 14)
+	assertEqual(t, game.Rallies[0].Note, "net cord")
+	assertEqual(t, game.Rallies[0].Gap, -1)
+	assertEqual(t, game.Rallies[2].Gap, -1)
+	assertEqual(t, game.Rallies[3].Gap, 30)
+	assertEqual(t, game.AvgRallyGap, 30)
+	assertEqual(t, game.Duration, 2)
+
+	data, err := json.Marshal(game)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assertEqual(t, strings.HasPrefix(string(data), `{"points":[{"winner":1,"t":1679684430,"shots":14,"note":"net cord"},2,{"winner":2,"t":1679684490},`), true)
+
+	// timestamps must not decrease
+	_, err = Parse(
+		`{
+			"info": {
+				"mode": 21,
+				"team1": [{ "country": "DK", "player": "A" }],
+				"team2": [{ "country": "TW", "player": "B" }],
+				"start": 1679684400,
+				"end": null
+			},
+			"games": [
+				{ "points": [{ "t": 1679684490, "winner": 1 }, { "t": 1679684430, "winner": 2 }] }
+			]
+		}`)
+
+	if err == nil {
+		t.Error("Expected error for decreasing timestamps")
+	}
+}
+
+func TestValidationErrors(t *testing.T) {
+	tests := []struct {
+		games   string
+		country string
+		want    ValidationError
+	}{
+		{
+			games:   `[{ "points": [1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 2] }]`,
+			country: "DK",
+			want:    ValidationError{Field: "games.points", GameIndex: 0, PointIndex: 11, Reason: ERR_INVALID_POINT},
+		},
+		{
+			games:   `[{ "points": [1] }, { "points": [] }]`,
+			country: "DK",
+			want:    ValidationError{Field: "games", GameIndex: 1, PointIndex: -1, Reason: ERR_INVALID_GAME},
+		},
+		{
+			games:   `[{ "points": [] }]`,
+			country: "Nowhere",
+			want:    ValidationError{Field: "info.team1", GameIndex: -1, PointIndex: -1, Reason: ERR_INVALID_TEAMS},
+		},
+		{
+			games:   `[{ "points": ["1"] }]`,
+			country: "DK",
+			want:    ValidationError{Field: "", GameIndex: -1, PointIndex: -1, Reason: ERR_INVALID_JSON},
+		},
+	}
+
+	for _, test := range tests {
+		_, err := Parse(
+			`{
+				"info": {
+					"mode": 11,
+					"team1": [{ "country": "` + test.country + `", "player": "A" }],
+					"team2": [{ "country": "TW", "player": "B" }],
+					"start": 1679684400,
+					"end": null
+				},
+				"games": ` + test.games + `
+			}`)
+
+		var validationError *ValidationError
+
+		if !errors.As(err, &validationError) {
+			t.Fatalf("Got %v, want ValidationError", err)
+		}
+
+		validationError.Err = nil
+		assertEqual(t, *validationError, test.want)
+	}
+}
+
+func TestState(t *testing.T) {
+	state, err := NewState(MatchInfo{
+		Mode:  Mode11,
+		Team1: Team{{Country: "DK", Player: "A"}},
+		Team2: Team{{Country: "TW", Player: "B"}},
+		Start: UnixTime{Time: time.Unix(1679684400, 0)},
+	})
+
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	for i := 0; i < 10; i++ {
+		state.Apply(Team1)
+	}
+
+	assertEqual(t, state.GamePoint(), Team1)
+	assertEqual(t, state.MatchPoint(), Unknown)
+
+	state.Apply(Team1)
+
+	// a new game starts automatically
+	assertEqual(t, state.Game(), 1)
+	score1, score2 := state.Score()
+	assertEqual(t, score1, 0)
+	assertEqual(t, score2, 0)
+
+	// undo continues the previous game
+	state.Undo()
+	assertEqual(t, state.Game(), 0)
+	score1, _ = state.Score()
+	assertEqual(t, score1, 10)
+
+	for g := 0; g < 3; g++ {
+		for state.Game() == g && state.Winner() == Unknown {
+			state.Apply(Team2)
+		}
+	}
+
+	games1, games2 := state.Games()
+	assertEqual(t, games1, 0)
+	assertEqual(t, games2, 3)
+	assertEqual(t, state.Winner(), Team2)
+
+	if err := state.Apply(Team1); err == nil {
+		t.Error("Expected error for point after match end")
+	}
+
+	match, err := state.Match()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assertEqual(t, match.Winner, Team2)
+	assertEqual(t, match.Team1PointsWon, 10)
+	assertEqual(t, match.Team2PointsWon, 34)
+}
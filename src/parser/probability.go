@@ -0,0 +1,147 @@
+package parser
+
+import "math"
+
+// number of rallies assumed to be won by each team before the match starts
+const priorRallies = 10
+
+// Probability is the estimated probability of Team1 winning the current game
+// and the match after a rally. The probabilities of Team2 are 1 minus these.
+type Probability struct {
+	Game       int     `json:"game"`
+	Point      int     `json:"point"`
+	Team1Game  float64 `json:"team1Game"`
+	Team1Match float64 `json:"team1Match"`
+}
+
+// Calculates the probability of each team winning the current game and the
+// match after every rally. Rallies are assumed to be independent, with Team1
+// winning each rally with the rate it won rallies so far. This makes the
+// game a Markov chain over the score, which is solved exactly using the
+// rules of the match mode.
+func WinProbability(m Match) []Probability {
+	rules, ok := Rules(m.Info.Mode)
+	if !ok {
+		return nil
+	}
+
+	var probabilities []Probability
+
+	// rallies won by Team1 and rallies played so far
+	won := 0
+	played := 0
+
+	gamesTeam1 := 0
+	gamesTeam2 := 0
+
+	for i, game := range m.Games {
+		scoreTeam1 := 0
+		scoreTeam2 := 0
+
+		for j, point := range game.Points {
+			played++
+
+			if point == Team1 {
+				won++
+				scoreTeam1++
+			} else {
+				scoreTeam2++
+			}
+
+			// the rate starts at 0.5 and is smoothed with prior rallies,
+			// so that a few early rallies do not decide the estimate
+			p := float64(won+priorRallies) / float64(played+2*priorRallies)
+
+			chain := newMarkovChain(rules, p)
+			pGame := chain.game(scoreTeam1, scoreTeam2)
+
+			probabilities = append(probabilities, Probability{
+				Game:       i,
+				Point:      j,
+				Team1Game:  pGame,
+				Team1Match: pGame*chain.match(gamesTeam1+1, gamesTeam2) + (1-pGame)*chain.match(gamesTeam1, gamesTeam2+1),
+			})
+		}
+
+		if game.Winner == Team1 {
+			gamesTeam1++
+		} else if game.Winner == Team2 {
+			gamesTeam2++
+		}
+	}
+
+	return probabilities
+}
+
+type markovChain struct {
+	rules RuleSet
+	// probability of Team1 winning a rally
+	p     float64
+	games map[[2]int]float64
+}
+
+func newMarkovChain(rules RuleSet, p float64) *markovChain {
+	return &markovChain{
+		rules: rules,
+		p:     p,
+		games: make(map[[2]int]float64),
+	}
+}
+
+// Returns the probability of Team1 winning a game from the given score.
+func (c *markovChain) game(scoreTeam1 int, scoreTeam2 int) float64 {
+	switch c.rules.gameWinner(scoreTeam1, scoreTeam2) {
+	case Team1:
+		return 1
+	case Team2:
+		return 0
+	}
+
+	// Without a cap, a team wins as soon as it leads by WinBy points once both
+	// teams are close enough to WinPoints. The rest of the game is a random
+	// walk over the lead, which is the gambler's ruin problem.
+	if c.rules.TiePoints == 0 && min(scoreTeam1, scoreTeam2) >= c.rules.WinPoints-c.rules.WinBy {
+		return c.ruin(scoreTeam1 - scoreTeam2)
+	}
+
+	key := [2]int{scoreTeam1, scoreTeam2}
+
+	if probability, ok := c.games[key]; ok {
+		return probability
+	}
+
+	probability := c.p*c.game(scoreTeam1+1, scoreTeam2) + (1-c.p)*c.game(scoreTeam1, scoreTeam2+1)
+	c.games[key] = probability
+
+	return probability
+}
+
+// Returns the probability of Team1 reaching a lead of WinBy points before
+// Team2 does, given the current lead of Team1.
+func (c *markovChain) ruin(lead int) float64 {
+	w := float64(c.rules.WinBy)
+	d := float64(lead)
+
+	if c.p == 0.5 {
+		return (d + w) / (2 * w)
+	}
+
+	r := (1 - c.p) / c.p
+
+	return (1 - math.Pow(r, d+w)) / (1 - math.Pow(r, 2*w))
+}
+
+// Returns the probability of Team1 winning the match from the given number
+// of games won, before the next game started.
+func (c *markovChain) match(gamesTeam1 int, gamesTeam2 int) float64 {
+	switch c.rules.matchWinner(gamesTeam1, gamesTeam2) {
+	case Team1:
+		return 1
+	case Team2:
+		return 0
+	}
+
+	pGame := c.game(0, 0)
+
+	return pGame*c.match(gamesTeam1+1, gamesTeam2) + (1-pGame)*c.match(gamesTeam1, gamesTeam2+1)
+}
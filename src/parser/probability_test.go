@@ -0,0 +1,77 @@
+package parser
+
+import (
+	"math"
+	"testing"
+)
+
+func assertAlmostEqual(t *testing.T, got float64, want float64) {
+	if math.Abs(got-want) > 1e-9 {
+		t.Errorf("Got %v, want %v", got, want)
+	}
+}
+
+func TestMarkovChain(t *testing.T) {
+	rules, _ := Rules(Mode21)
+
+	// equally strong teams
+	chain := newMarkovChain(rules, 0.5)
+	assertAlmostEqual(t, chain.game(0, 0), 0.5)
+	assertAlmostEqual(t, chain.game(29, 29), 0.5)
+	assertAlmostEqual(t, chain.match(1, 1), 0.5)
+	assertAlmostEqual(t, chain.match(1, 0), 0.75)
+
+	// 20:19 is won directly with p, or at 20:20 with 0.5
+	assertAlmostEqual(t, chain.game(20, 19), 0.75)
+
+	// without a cap, deuce is won with p² / (p² + q²)
+	rules.TiePoints = 0
+	chain = newMarkovChain(rules, 0.6)
+	assertAlmostEqual(t, chain.game(25, 25), 0.36/(0.36+0.16))
+
+	// a game without a cap is not decided at 0:0
+	chain = newMarkovChain(rules, 0.5)
+	assertAlmostEqual(t, chain.game(0, 0), 0.5)
+}
+
+func TestWinProbability(t *testing.T) {
+	match, err := parseGames(21, `[
+		{ "points": [1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1] },
+		{ "points": [2] }
+	]`)
+
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	probabilities := WinProbability(match)
+
+	assertEqual(t, len(probabilities), 22)
+
+	// the first game is won
+	assertAlmostEqual(t, probabilities[20].Team1Game, 1)
+	assertEqual(t, probabilities[21].Game, 1)
+	assertEqual(t, probabilities[21].Point, 0)
+
+	for i := 1; i < 21; i++ {
+		if probabilities[i].Team1Match < probabilities[i-1].Team1Match {
+			t.Errorf("Expected non-decreasing probability after rally %d", i)
+		}
+	}
+
+	if probabilities[0].Team1Game <= 0.5 || probabilities[20].Team1Match < 0.99 {
+		t.Error("Expected Team1 to be favoured")
+	}
+
+	match, err = parseGames(21, `[{ "points": [1, 2] }]`)
+
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	probabilities = WinProbability(match)
+
+	// symmetric after the second rally
+	assertAlmostEqual(t, probabilities[1].Team1Game, 0.5)
+	assertAlmostEqual(t, probabilities[1].Team1Match, 0.5)
+}
@@ -0,0 +1,159 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+)
+
+// Rally holds the metadata of a single point. Points may either be given as
+// the plain ID of the team that won the rally, e.g. 1, or as an object, e.g.
+// {"t": 1679684460, "winner": 1, "shots": 14, "note": "net cord"}.
+type Rally struct {
+	Winner TeamID    `json:"winner"`
+	Time   *UnixTime `json:"t,omitempty"`
+	Shots  int       `json:"shots,omitempty"`
+	Note   string    `json:"note,omitempty"`
+	// seconds since the previous rally, -1 if unknown
+	Gap int `json:"-"`
+}
+
+func (r Rally) hasMetadata() bool {
+	return r.Time != nil || r.Shots != 0 || len(r.Note) != 0
+}
+
+func (r *Rally) UnmarshalJSON(b []byte) error {
+	b = bytes.TrimSpace(b)
+
+	if len(b) != 0 && b[0] == '{' {
+		type rally Rally
+
+		var v rally
+		if err := json.Unmarshal(b, &v); err != nil {
+			return err
+		}
+
+		*r = Rally(v)
+		return nil
+	}
+
+	*r = Rally{}
+	return json.Unmarshal(b, &r.Winner)
+}
+
+func (g *Game) UnmarshalJSON(b []byte) error {
+	type game Game
+
+	v := struct {
+		Points []Rally `json:"points"`
+		*game
+	}{
+		game: (*game)(g),
+	}
+
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+
+	g.Points = make([]TeamID, len(v.Points))
+	g.Rallies = v.Points
+
+	for i, rally := range v.Points {
+		g.Points[i] = rally.Winner
+	}
+
+	return nil
+}
+
+// Points are marshalled as plain team IDs unless a rally has metadata.
+func (g Game) MarshalJSON() ([]byte, error) {
+	type game Game
+
+	points := make([]any, len(g.Points))
+
+	for i, point := range g.Points {
+		if i < len(g.Rallies) && g.Rallies[i].hasMetadata() {
+			points[i] = g.Rallies[i]
+		} else {
+			points[i] = point
+		}
+	}
+
+	return json.Marshal(struct {
+		Points []any `json:"points"`
+		game
+	}{
+		Points: points,
+		game:   game(g),
+	})
+}
+
+// Validates the rally metadata of all games and calculates the time between
+// rallies and the duration of each game. Timestamps must not decrease over
+// the course of a match.
+func validateRallies(games []Game) error {
+	var last *UnixTime
+
+	for i := range games {
+		game := &games[i]
+
+		// games that were not unmarshalled from JSON have no rallies yet
+		if len(game.Rallies) != len(game.Points) {
+			game.Rallies = make([]Rally, len(game.Points))
+
+			for j, point := range game.Points {
+				game.Rallies[j].Winner = point
+			}
+		}
+
+		var first *UnixTime
+		gaps := 0
+		gapsSum := 0
+
+		for j := range game.Rallies {
+			rally := &game.Rallies[j]
+			rally.Gap = -1
+
+			if rally.Shots < 0 {
+				return newGameError("games.points", i, j, ERR_INVALID_POINT)
+			}
+
+			if rally.Time == nil {
+				continue
+			}
+
+			if last != nil {
+				if rally.Time.Before(last.Time) {
+					return newGameError("games.points", i, j, ERR_INVALID_TIMES)
+				}
+
+				// the first rally of a game follows the interval between games
+				if j != 0 && game.Rallies[j-1].Time != nil {
+					rally.Gap = int(rally.Time.Sub(last.Time) / time.Second)
+
+					gaps++
+					gapsSum += rally.Gap
+				}
+			}
+
+			if first == nil {
+				first = rally.Time
+			}
+
+			last = rally.Time
+		}
+
+		game.Duration = 0
+		game.AvgRallyGap = 0
+
+		if first != nil {
+			game.Duration = int(last.Sub(first.Time).Round(time.Minute).Minutes())
+		}
+
+		if gaps != 0 {
+			game.AvgRallyGap = gapsSum / gaps
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,71 @@
+package parser
+
+// Result describes how a match ended. An empty result is treated as
+// ResultCompleted once a team has won the required number of games.
+type Result string
+
+const (
+	ResultCompleted    Result = "completed"
+	ResultRetired      Result = "retired"
+	ResultWalkover     Result = "walkover"
+	ResultDisqualified Result = "disqualified"
+	ResultAbandoned    Result = "abandoned"
+)
+
+// Returns true if the match ended before a team won the required number
+// of games.
+func (r Result) IsEarly() bool {
+	return r == ResultRetired || r == ResultWalkover || r == ResultDisqualified || r == ResultAbandoned
+}
+
+// Validates the result of a match and determines its winner. Matches that
+// ended early must have an end time. Retirements, walkovers and
+// disqualifications apply to one team, whose opponent wins the match.
+// Abandoned matches have no winner.
+func validateResult(m *Match) error {
+	result := m.Info.Result
+	team := m.Info.ResultTeam
+
+	switch result {
+	case "", ResultCompleted:
+		if team != Unknown {
+			return newValidationError("info.resultTeam", ERR_INVALID_RESULT)
+		}
+
+		if result == ResultCompleted && m.Winner == Unknown {
+			return newValidationError("info.result", ERR_INVALID_RESULT)
+		}
+
+		m.Finished = m.Winner != Unknown
+		return nil
+	case ResultRetired, ResultWalkover, ResultDisqualified:
+		if team != Team1 && team != Team2 {
+			return newValidationError("info.resultTeam", ERR_INVALID_RESULT)
+		}
+	case ResultAbandoned:
+		if team != Unknown {
+			return newValidationError("info.resultTeam", ERR_INVALID_RESULT)
+		}
+	default:
+		return newValidationError("info.result", ERR_INVALID_RESULT)
+	}
+
+	// the match was already decided by games
+	if m.Winner != Unknown {
+		return newValidationError("info.result", ERR_INVALID_RESULT)
+	}
+
+	// no rally is played in a walkover
+	if result == ResultWalkover && calculatePointsPlayedInMatch(m.Games) != 0 {
+		return newValidationError("info.result", ERR_INVALID_RESULT)
+	}
+
+	if m.Info.End.IsZero() {
+		return newValidationError("info.end", ERR_INVALID_TIMES)
+	}
+
+	m.Winner = opponent(team)
+	m.Finished = true
+
+	return nil
+}
@@ -0,0 +1,62 @@
+package parser
+
+import "testing"
+
+func parseResult(result string, games string) (Match, error) {
+	return Parse(
+		`{
+			"info": {
+				"mode": 21,
+				"team1": [{ "country": "DK", "player": "A" }],
+				"team2": [{ "country": "TW", "player": "B" }],
+				"start": 1679684400,
+				"end": 1679686320,
+				` + result + `
+			},
+			"games": ` + games + `
+		}`)
+}
+
+func TestResultRetired(t *testing.T) {
+	match, err := parseResult(`"result": "retired", "resultTeam": 1`, `[{ "points": [1, 1, 2] }]`)
+
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assertEqual(t, match.Winner, Team2)
+	assertEqual(t, match.Finished, true)
+}
+
+func TestResultAbandoned(t *testing.T) {
+	match, err := parseResult(`"result": "abandoned"`, `[{ "points": [1, 1, 2] }]`)
+
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	assertEqual(t, match.Winner, Unknown)
+	assertEqual(t, match.Finished, true)
+}
+
+func TestResultInvalid(t *testing.T) {
+	tests := []struct {
+		result string
+		games  string
+	}{
+		// walkover after rallies were played
+		{`"result": "walkover", "resultTeam": 2`, `[{ "points": [1] }]`},
+		// retirement without team
+		{`"result": "retired"`, `[{ "points": [1] }]`},
+		// completed without winner
+		{`"result": "completed"`, `[{ "points": [1] }]`},
+		// unknown result
+		{`"result": "rain"`, `[{ "points": [1] }]`},
+	}
+
+	for _, test := range tests {
+		if _, err := parseResult(test.result, test.games); err == nil {
+			t.Errorf("Expected error for result %s", test.result)
+		}
+	}
+}
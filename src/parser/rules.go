@@ -0,0 +1,147 @@
+package parser
+
+import (
+	"errors"
+	"sort"
+	"sync"
+)
+
+// RuleSet describes the scoring rules of a mode. A game is won by the first
+// team to reach WinPoints with a lead of at least WinBy points, or by the
+// first team to reach TiePoints. A TiePoints of 0 means that there is no cap.
+// A match is won by the first team to win WinGames games.
+type RuleSet struct {
+	Mode           Mode   `json:"mode"`
+	Name           string `json:"name"`
+	WinPoints      int    `json:"winPoints"`
+	TiePoints      int    `json:"tiePoints"`
+	WinBy          int    `json:"winBy"`
+	WinGames       int    `json:"winGames"`
+	IntervalPoints int    `json:"intervalPoints"`
+}
+
+var (
+	rulesMutex sync.RWMutex
+	rules      = map[Mode]RuleSet{
+		Mode11: {
+			Mode:           Mode11,
+			Name:           "11 Pts., best of 5",
+			WinPoints:      Mode11WinPoints,
+			TiePoints:      Mode11TiePoints,
+			WinBy:          2,
+			WinGames:       Mode11WinGames,
+			IntervalPoints: Mode11IntervalPoints,
+		},
+		Mode15: {
+			Mode:           Mode15,
+			Name:           "15 Pts., best of 3",
+			WinPoints:      Mode15WinPoints,
+			TiePoints:      Mode15TiePoints,
+			WinBy:          2,
+			WinGames:       Mode15WinGames,
+			IntervalPoints: Mode15IntervalPoints,
+		},
+		Mode21: {
+			Mode:           Mode21,
+			Name:           "21 Pts., best of 3",
+			WinPoints:      Mode21WinPoints,
+			TiePoints:      Mode21TiePoints,
+			WinBy:          2,
+			WinGames:       Mode21WinGames,
+			IntervalPoints: Mode21IntervalPoints,
+		},
+	}
+)
+
+// Returns the maximum number of games that can be played in a match.
+func (r RuleSet) MaxGames() int {
+	return 2*r.WinGames - 1
+}
+
+func (r RuleSet) isValid() bool {
+	if r.Mode <= 0 || r.WinPoints <= 0 || r.WinBy <= 0 || r.WinGames <= 0 {
+		return false
+	}
+
+	if r.TiePoints != 0 && r.TiePoints < r.WinPoints {
+		return false
+	}
+
+	return r.IntervalPoints >= 0 && r.IntervalPoints < r.WinPoints
+}
+
+// Returns true if a team with the given score has won the game against
+// a team with the other score.
+func (r RuleSet) hasWon(score int, other int) bool {
+	return (r.TiePoints != 0 && score == r.TiePoints) || (score >= r.WinPoints && score-other >= r.WinBy)
+}
+
+// Returns the winner of a game with the given scores, or Unknown if the
+// game is still running.
+func (r RuleSet) gameWinner(scoreTeam1 int, scoreTeam2 int) TeamID {
+	if r.hasWon(scoreTeam1, scoreTeam2) {
+		return Team1
+	} else if r.hasWon(scoreTeam2, scoreTeam1) {
+		return Team2
+	}
+
+	return Unknown
+}
+
+// Returns the winner of a match with the given number of games won by each
+// team, or Unknown if the match is running.
+func (r RuleSet) matchWinner(gamesTeam1 int, gamesTeam2 int) TeamID {
+	if gamesTeam1 == r.WinGames {
+		return Team1
+	} else if gamesTeam2 == r.WinGames {
+		return Team2
+	}
+
+	return Unknown
+}
+
+// Registers a custom rule set. Built-in rule sets and rule sets that were
+// registered before cannot be replaced.
+func Register(r RuleSet) error {
+	if !r.isValid() {
+		return errors.New(ERR_INVALID_RULES)
+	}
+
+	rulesMutex.Lock()
+	defer rulesMutex.Unlock()
+
+	if _, ok := rules[r.Mode]; ok {
+		return errors.New(ERR_INVALID_RULES)
+	}
+
+	rules[r.Mode] = r
+
+	return nil
+}
+
+// Returns the rule set of the given mode.
+func Rules(mode Mode) (RuleSet, bool) {
+	rulesMutex.RLock()
+	defer rulesMutex.RUnlock()
+
+	r, ok := rules[mode]
+	return r, ok
+}
+
+// Returns all registered rule sets, ordered by mode.
+func AllRules() []RuleSet {
+	rulesMutex.RLock()
+	defer rulesMutex.RUnlock()
+
+	all := make([]RuleSet, 0, len(rules))
+
+	for _, r := range rules {
+		all = append(all, r)
+	}
+
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].Mode < all[j].Mode
+	})
+
+	return all
+}
@@ -0,0 +1,185 @@
+package parser
+
+// Court is the service court a rally is served from. The server serves
+// from the right court if the serving team's score is even and from the
+// left court if it is odd.
+type Court int
+
+const (
+	RightCourt Court = 0
+	LeftCourt  Court = 1
+)
+
+// PlayerRef references a player by its team and its index within that team.
+type PlayerRef struct {
+	Team   TeamID `json:"team"`
+	Player int    `json:"player"`
+}
+
+// Serve describes who served a rally, to whom and from which court.
+type Serve struct {
+	Team     TeamID `json:"team"`
+	Server   Player `json:"server"`
+	Receiver Player `json:"receiver"`
+	Court    Court  `json:"court"`
+}
+
+func opponent(team TeamID) TeamID {
+	switch team {
+	case Team1:
+		return Team2
+	case Team2:
+		return Team1
+	default:
+		return Unknown
+	}
+}
+
+func (r PlayerRef) isValid(info MatchInfo) bool {
+	switch r.Team {
+	case Team1:
+		return r.Player >= 0 && r.Player < len(info.Team1)
+	case Team2:
+		return r.Player >= 0 && r.Player < len(info.Team2)
+	default:
+		return false
+	}
+}
+
+func (c Court) String() string {
+	if c == LeftCourt {
+		return "left"
+	}
+
+	return "right"
+}
+
+// Calculates the server, receiver and service court of every rally in a game,
+// given the first server of the game and the player of the receiving team
+// standing in the right service court.
+//
+// In doubles, the players of the serving team switch service courts whenever
+// they win a rally. If the receiving team wins a rally, nobody switches courts
+// and the player of the receiving team standing in the court matching its new
+// score serves next. In singles, both players simply follow the server's score.
+func calculateServes(points []TeamID, info MatchInfo, server PlayerRef, receiver int) []Serve {
+	teams := map[TeamID]Team{
+		Team1: info.Team1,
+		Team2: info.Team2,
+	}
+
+	// index of the player standing in the right service court, per team
+	right := map[TeamID]int{
+		server.Team:           server.Player,
+		opponent(server.Team): receiver,
+	}
+
+	score := map[TeamID]int{}
+	serving := server.Team
+
+	serves := make([]Serve, 0, len(points))
+
+	// returns the player of the given team standing in the given court
+	playerIn := func(team TeamID, court Court) Player {
+		players := teams[team]
+
+		if court == LeftCourt && len(players) == 2 {
+			return players[1-right[team]]
+		}
+
+		return players[right[team]]
+	}
+
+	for _, point := range points {
+		court := RightCourt
+		if score[serving]%2 == 1 {
+			court = LeftCourt
+		}
+
+		serves = append(serves, Serve{
+			Team:     serving,
+			Server:   playerIn(serving, court),
+			Receiver: playerIn(opponent(serving), court),
+			Court:    court,
+		})
+
+		score[point]++
+
+		if point == serving {
+			if len(teams[serving]) == 2 {
+				right[serving] = 1 - right[serving]
+			}
+		} else {
+			serving = point
+		}
+	}
+
+	return serves
+}
+
+// Determines the first server and receiver of every game and calculates the
+// serves of each rally. The first server of a game may be given explicitly,
+// otherwise the winner of the previous game serves first in singles. In
+// doubles, the winning team chooses its server and the other team its
+// receiver, so both must be given for every game. If the first server or
+// receiver of a game is unknown, no serves are calculated for it.
+func validateServes(match *Match) error {
+	doubles := len(match.Info.Team1) == 2 || len(match.Info.Team2) == 2
+
+	for i := range match.Games {
+		game := &match.Games[i]
+
+		var server PlayerRef
+
+		if game.Server != nil {
+			server = *game.Server
+
+			if !server.isValid(match.Info) {
+				return newGameError("games.server", i, -1, ERR_INVALID_SERVER)
+			}
+
+			// the winner of a game serves first in the next game
+			if i > 0 && match.Games[i-1].Winner != Unknown && server.Team != match.Games[i-1].Winner {
+				return newGameError("games.server", i, -1, ERR_INVALID_SERVER)
+			}
+		} else if i > 0 && match.Games[i-1].Winner != Unknown && !doubles {
+			server = PlayerRef{Team: match.Games[i-1].Winner}
+		} else {
+			continue
+		}
+
+		receiver := PlayerRef{Team: opponent(server.Team)}
+
+		if game.Receiver != nil {
+			receiver = *game.Receiver
+
+			if !receiver.isValid(match.Info) || receiver.Team != opponent(server.Team) {
+				return newGameError("games.receiver", i, -1, ERR_INVALID_SERVER)
+			}
+		} else if doubles {
+			continue
+		}
+
+		// the serve of the next rally is calculated along with the others
+		points := append(append([]TeamID{}, game.Points...), Unknown)
+		serves := calculateServes(points, match.Info, server, receiver.Player)
+
+		game.Serves = serves[:len(game.Points)]
+
+		if game.Winner == Unknown {
+			game.NextServe = &serves[len(game.Points)]
+		}
+	}
+
+	return nil
+}
+
+// Returns the serve of the next rally, e.g. for a serving indicator. Returns
+// nil if the match is finished or the first server of the game is unknown.
+func (m Match) NextServe() *Serve {
+	if m.Finished || len(m.Games) == 0 {
+		return nil
+	}
+
+	return m.Games[len(m.Games)-1].NextServe
+}
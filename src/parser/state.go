@@ -0,0 +1,285 @@
+package parser
+
+import "time"
+
+// State tracks a running match rally by rally, without re-validating the
+// whole match after every point. It uses the same rules as Parse.
+type State struct {
+	info   MatchInfo
+	rules  RuleSet
+	games  []Game
+	events []Event
+}
+
+// Creates the state of a match that has not started yet.
+func NewState(info MatchInfo) (*State, error) {
+	if err := info.validate(); err != nil {
+		return nil, err
+	}
+
+	rules, _ := Rules(info.Mode)
+
+	return &State{
+		info:  info,
+		rules: rules,
+		games: []Game{{}},
+	}, nil
+}
+
+// Creates the state of a match that was parsed before.
+func NewStateFromMatch(m Match) (*State, error) {
+	s, err := NewState(m.Info)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(m.Games) != 0 {
+		s.games = make([]Game, len(m.Games))
+
+		for i, game := range m.Games {
+			s.games[i] = Game{
+				Points:   append([]TeamID{}, game.Points...),
+				Rallies:  append([]Rally{}, game.Rallies...),
+				Server:   game.Server,
+				Receiver: game.Receiver,
+			}
+		}
+	}
+
+	s.events = append([]Event{}, m.Events...)
+
+	// start the next game if the last game is finished
+	if !s.Finished() && s.gameWinner(len(s.games)-1) != Unknown {
+		s.games = append(s.games, Game{})
+	}
+
+	return s, nil
+}
+
+func (s *State) current() *Game {
+	return &s.games[len(s.games)-1]
+}
+
+func (s *State) gameWinner(i int) TeamID {
+	points := s.games[i].Points
+	return s.rules.gameWinner(count(points, Team1), count(points, Team2))
+}
+
+// Adds a rally won by the given team. A new game is started automatically
+// once a game is won, unless the match is finished.
+func (s *State) Apply(team TeamID) error {
+	return s.ApplyRally(Rally{Winner: team})
+}
+
+// Adds a rally with metadata, see Apply.
+func (s *State) ApplyRally(rally Rally) error {
+	if rally.Winner != Team1 && rally.Winner != Team2 {
+		return newGameError("games.points", s.Game(), len(s.current().Points), ERR_INVALID_POINT)
+	}
+
+	if s.Finished() {
+		return newGameError("games", s.Game()+1, -1, ERR_INVALID_GAME)
+	}
+
+	game := s.current()
+	game.Points = append(game.Points, rally.Winner)
+	game.Rallies = append(game.Rallies, rally)
+
+	if s.gameWinner(len(s.games)-1) == Unknown {
+		return nil
+	}
+
+	if s.Winner() != Unknown {
+		if s.info.End.IsZero() {
+			s.info.End.Time = time.Now()
+		}
+	} else {
+		s.games = append(s.games, Game{})
+	}
+
+	return nil
+}
+
+// Removes the last rally. If the current game has not started yet, the last
+// rally of the previous game is removed and that game continues. A black
+// card that ended the match is removed instead of a rally.
+func (s *State) Undo() {
+	if n := len(s.events); n > 0 && s.events[n-1].Type == EventBlackCard && s.info.Result == ResultDisqualified {
+		s.events = s.events[:n-1]
+		s.reopen()
+		return
+	}
+
+	if len(s.current().Points) == 0 && len(s.games) > 1 {
+		s.games = s.games[:len(s.games)-1]
+	}
+
+	game := s.current()
+
+	if len(game.Points) == 0 {
+		return
+	}
+
+	game.Points = game.Points[:len(game.Points)-1]
+	game.Rallies = game.Rallies[:len(game.Rallies)-1]
+
+	s.reopen()
+
+	// events that awarded the removed rally are removed as well, other events
+	// are moved to the next rally
+	var events []Event
+
+	for _, event := range s.events {
+		if event.Game > s.Game() || event.Game == s.Game() && event.Point >= len(game.Points) {
+			if event.Type.AwardsPoint() {
+				continue
+			}
+
+			event.Game = s.Game()
+			event.Point = len(game.Points)
+		}
+
+		events = append(events, event)
+	}
+
+	s.events = events
+}
+
+// Continues the match, which has no end and no result anymore.
+func (s *State) reopen() {
+	s.info.End = UnixTime{Time: time.Unix(0, 0)}
+	s.info.Result = ""
+	s.info.ResultTeam = Unknown
+}
+
+// Adds an official event of a team. Its position is set to the current
+// rally. Red cards and service faults award a rally to the opponent, which
+// is added as well. A black card disqualifies the team and ends the match.
+func (s *State) AddEvent(event Event) error {
+	if !event.Type.isValid() || !(PlayerRef{Team: event.Team, Player: event.Player}).isValid(s.info) {
+		return newValidationError("events", ERR_INVALID_EVENT)
+	}
+
+	if event.Time.IsZero() {
+		event.Time.Time = time.Now()
+	}
+
+	if event.Type.AwardsPoint() {
+		if err := s.ApplyRally(Rally{Winner: opponent(event.Team), Time: &UnixTime{Time: event.Time.Time}}); err != nil {
+			return err
+		}
+
+		// the awarded rally may have finished the game
+		for i := len(s.games) - 1; i >= 0; i-- {
+			if len(s.games[i].Points) != 0 {
+				event.Game = i
+				event.Point = len(s.games[i].Points) - 1
+				break
+			}
+		}
+	} else {
+		event.Game = s.Game()
+		event.Point = len(s.current().Points)
+	}
+
+	if event.Type == EventBlackCard {
+		s.info.Result = ResultDisqualified
+		s.info.ResultTeam = event.Team
+		s.info.End = event.Time
+	}
+
+	s.events = append(s.events, event)
+
+	return nil
+}
+
+// Returns the index of the current game.
+func (s *State) Game() int {
+	return len(s.games) - 1
+}
+
+// Returns the score of the current game.
+func (s *State) Score() (int, int) {
+	points := s.current().Points
+	return count(points, Team1), count(points, Team2)
+}
+
+// Returns the number of games won by each team.
+func (s *State) Games() (int, int) {
+	var winners []TeamID
+
+	for i := range s.games {
+		winners = append(winners, s.gameWinner(i))
+	}
+
+	return count(winners, Team1), count(winners, Team2)
+}
+
+// Returns the winner of the match, or Unknown if the match is running or
+// was abandoned. The opponent of a team that retired, did not show up or was
+// disqualified wins.
+func (s *State) Winner() TeamID {
+	switch s.info.Result {
+	case ResultRetired, ResultWalkover, ResultDisqualified:
+		return opponent(s.info.ResultTeam)
+	}
+
+	return s.rules.matchWinner(s.Games())
+}
+
+// Returns true once the match has a winner or has ended early.
+func (s *State) Finished() bool {
+	return s.Winner() != Unknown || s.info.Result.IsEarly()
+}
+
+// Returns the team that would win the current game with the next rally,
+// or Unknown if there is none.
+func (s *State) GamePoint() TeamID {
+	if s.Finished() {
+		return Unknown
+	}
+
+	scoreTeam1, scoreTeam2 := s.Score()
+
+	if s.rules.hasWon(scoreTeam1+1, scoreTeam2) {
+		return Team1
+	} else if s.rules.hasWon(scoreTeam2+1, scoreTeam1) {
+		return Team2
+	}
+
+	return Unknown
+}
+
+// Returns the team that would win the match with the next rally,
+// or Unknown if there is none.
+func (s *State) MatchPoint() TeamID {
+	team := s.GamePoint()
+	gamesTeam1, gamesTeam2 := s.Games()
+
+	if team == Team1 && gamesTeam1+1 == s.rules.WinGames || team == Team2 && gamesTeam2+1 == s.rules.WinGames {
+		return team
+	}
+
+	return Unknown
+}
+
+// Returns the match with all statistics calculated.
+func (s *State) Match() (Match, error) {
+	m := Match{
+		Info:   s.info,
+		Games:  make([]Game, len(s.games)),
+		Events: append([]Event(nil), s.events...),
+	}
+
+	for i, game := range s.games {
+		m.Games[i] = Game{
+			Points:   append([]TeamID{}, game.Points...),
+			Rallies:  append([]Rally{}, game.Rallies...),
+			Server:   game.Server,
+			Receiver: game.Receiver,
+		}
+	}
+
+	err := m.validate()
+	return m, err
+}
@@ -22,12 +22,17 @@ func (u *UnixTime) UnmarshalJSON(b []byte) error {
 	return nil
 }
 
+// Marshals the time as a Unix timestamp, or as null if it was never set.
 func (u UnixTime) MarshalJSON() ([]byte, error) {
+	if u.IsZero() {
+		return []byte("null"), nil
+	}
+
 	return []byte(fmt.Sprintf("%d", (u.Time.Unix()))), nil
 }
 
 // Returns true if UnixTime is 0, meaning a time.Time of
-// 1970-01-01 01:00:00 +0100 CET
+// 1970-01-01 01:00:00 +0100 CET, or if it was never set
 func (u UnixTime) IsZero() bool {
-	return u.Time.Equal(time.Unix(0, 0))
+	return u.Time.IsZero() || u.Time.Equal(time.Unix(0, 0))
 }
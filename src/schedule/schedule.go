@@ -0,0 +1,374 @@
+package schedule
+
+import (
+	"errors"
+	"score/src/parser"
+	"score/src/tournament"
+	"strings"
+	"time"
+)
+
+const (
+	// minutes a match is expected to take without finished matches of its
+	// mode to learn from
+	DEFAULT_ESTIMATE = 30
+	// minimum minutes between two matches of a player
+	DEFAULT_REST = 20
+
+	ERR_INVALID_COURT    = "court needs a name"
+	ERR_UNKNOWN_COURT    = "court does not exist"
+	ERR_INVALID_MATCH    = "scheduled match needs a category, a mode and both teams"
+	ERR_UNKNOWN_MATCH    = "scheduled match does not exist"
+	ERR_MATCH_NOT_CALLED = "scheduled match has not been called to a court"
+	ERR_MATCH_FINISHED   = "scheduled match has already finished"
+	ERR_MATCH_LINKED     = "scheduled match is already being scored"
+	ERR_MATCH_UNLINKED   = "scheduled match is not being scored"
+)
+
+// Court is a court of the venue. Courts are numbered in order of creation,
+// starting at 1.
+type Court struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	// scheduled match that is played on the court, 0 if the court is free
+	Item int `json:"item,omitempty"`
+}
+
+// Item is a scheduled match. Items are queued until they are called to a
+// free court, and numbered in order of creation, starting at 1.
+type Item struct {
+	ID       int                 `json:"id"`
+	Category tournament.Category `json:"category"`
+	Mode     parser.Mode         `json:"mode"`
+	Team1    parser.Team         `json:"team1"`
+	Team2    parser.Team         `json:"team2"`
+	// estimated duration in minutes
+	Estimate int `json:"estimate"`
+	// court the match was called to, 0 while it is queued
+	Court    int             `json:"court,omitempty"`
+	Called   parser.UnixTime `json:"called"`
+	Finished parser.UnixTime `json:"finished"`
+	// uuid of the match once it is scored
+	Match string `json:"match,omitempty"`
+}
+
+// Schedule is the order of play of a venue. Matches are called to courts in
+// the order they were added, unless one of their players needs to rest.
+type Schedule struct {
+	Courts []Court `json:"courts"`
+	Items  []Item  `json:"items"`
+	// minimum minutes between two matches of a player
+	Rest int `json:"rest"`
+}
+
+func New() Schedule {
+	return Schedule{
+		Courts: []Court{},
+		Items:  []Item{},
+		Rest:   DEFAULT_REST,
+	}
+}
+
+// Returns true if the match waits for a court.
+func (i Item) Queued() bool {
+	return i.Court == 0 && i.Finished.IsZero()
+}
+
+// Returns true if the match has been called to a court and is not finished.
+func (i Item) Playing() bool {
+	return i.Court != 0 && i.Finished.IsZero()
+}
+
+func (i Item) players() []parser.PlayerName {
+	var names []parser.PlayerName
+
+	for _, team := range []parser.Team{i.Team1, i.Team2} {
+		for _, player := range team {
+			names = append(names, player.Player)
+		}
+	}
+
+	return names
+}
+
+// Returns the average duration of finished matches in minutes, or
+// DEFAULT_ESTIMATE if there are none.
+func Estimate(average time.Duration) int {
+	if estimate := int(average.Round(time.Minute).Minutes()); estimate > 0 {
+		return estimate
+	}
+
+	return DEFAULT_ESTIMATE
+}
+
+func (s *Schedule) AddCourt(name string) (Court, error) {
+	name = strings.TrimSpace(name)
+
+	if len(name) == 0 {
+		return Court{}, errors.New(ERR_INVALID_COURT)
+	}
+
+	court := Court{
+		ID:   len(s.Courts) + 1,
+		Name: name,
+	}
+
+	s.Courts = append(s.Courts, court)
+
+	return court, nil
+}
+
+// Queues a match. Its id is set, as is its estimate if it has none.
+func (s *Schedule) Add(item Item) (Item, error) {
+	if !item.Category.IsValid() || len(item.Team1) != item.Category.Players() || len(item.Team2) != item.Category.Players() || !item.Team1.IsValid() || !item.Team2.IsValid() {
+		return Item{}, errors.New(ERR_INVALID_MATCH)
+	}
+
+	if _, ok := parser.Rules(item.Mode); !ok {
+		return Item{}, errors.New(ERR_INVALID_MATCH)
+	}
+
+	if item.Estimate <= 0 {
+		item.Estimate = DEFAULT_ESTIMATE
+	}
+
+	item.ID = len(s.Items) + 1
+	item.Court = 0
+	item.Called = parser.UnixTime{}
+	item.Finished = parser.UnixTime{}
+	item.Match = ""
+
+	s.Items = append(s.Items, item)
+
+	return item, nil
+}
+
+// Returns the court with the given id.
+func (s *Schedule) Court(id int) (*Court, error) {
+	if id < 1 || id > len(s.Courts) {
+		return nil, errors.New(ERR_UNKNOWN_COURT)
+	}
+
+	return &s.Courts[id-1], nil
+}
+
+// Returns the scheduled match with the given id.
+func (s *Schedule) Item(id int) (*Item, error) {
+	if id < 1 || id > len(s.Items) {
+		return nil, errors.New(ERR_UNKNOWN_MATCH)
+	}
+
+	return &s.Items[id-1], nil
+}
+
+// Returns the scheduled match that is scored as the match with the given
+// uuid.
+func (s *Schedule) ItemOf(match string) (*Item, bool) {
+	for i := range s.Items {
+		if s.Items[i].Match != "" && s.Items[i].Match == match {
+			return &s.Items[i], true
+		}
+	}
+
+	return nil, false
+}
+
+// Returns when each player may play again: after the rest following their
+// last finished match. Players that are on court are returned as well.
+func (s Schedule) available() (map[parser.PlayerName]time.Time, map[parser.PlayerName]bool) {
+	available := make(map[parser.PlayerName]time.Time)
+	playing := make(map[parser.PlayerName]bool)
+	rest := time.Duration(s.Rest) * time.Minute
+
+	for _, item := range s.Items {
+		for _, name := range item.players() {
+			if item.Playing() {
+				playing[name] = true
+			} else if !item.Finished.IsZero() && item.Finished.Add(rest).After(available[name]) {
+				available[name] = item.Finished.Add(rest)
+			}
+		}
+	}
+
+	return available, playing
+}
+
+// Calls queued matches to free courts, in order of the queue. Matches with
+// players who are on court or still need to rest are skipped. Returns the
+// matches that were called.
+func (s *Schedule) Assign(now time.Time) []Item {
+	available, playing := s.available()
+
+	var called []Item
+
+	for c := range s.Courts {
+		court := &s.Courts[c]
+
+		if court.Item != 0 {
+			continue
+		}
+
+		for i := range s.Items {
+			item := &s.Items[i]
+
+			if !item.Queued() || !ready(item.players(), available, playing, now) {
+				continue
+			}
+
+			item.Court = court.ID
+			item.Called = parser.UnixTime{Time: now}
+			court.Item = item.ID
+
+			for _, name := range item.players() {
+				playing[name] = true
+			}
+
+			called = append(called, *item)
+			break
+		}
+	}
+
+	return called
+}
+
+func ready(players []parser.PlayerName, available map[parser.PlayerName]time.Time, playing map[parser.PlayerName]bool, now time.Time) bool {
+	for _, name := range players {
+		if playing[name] || available[name].After(now) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Links the match that scores a called match.
+func (s *Schedule) Link(id int, match string) error {
+	item, err := s.Item(id)
+	if err != nil {
+		return err
+	}
+
+	if !item.Playing() {
+		return errors.New(ERR_MATCH_NOT_CALLED)
+	}
+
+	if item.Match != "" {
+		return errors.New(ERR_MATCH_LINKED)
+	}
+
+	item.Match = match
+
+	return nil
+}
+
+// Removes the link of a called match to the match that scores it and
+// returns the uuid of the match. The match stays on its court.
+func (s *Schedule) Unlink(id int) (string, error) {
+	item, err := s.Item(id)
+	if err != nil {
+		return "", err
+	}
+
+	if !item.Playing() || item.Match == "" {
+		return "", errors.New(ERR_MATCH_UNLINKED)
+	}
+
+	match := item.Match
+	item.Match = ""
+
+	return match, nil
+}
+
+// Marks a called match as finished and frees its court. Its players rest
+// from now on.
+func (s *Schedule) Finish(id int, now time.Time) error {
+	item, err := s.Item(id)
+	if err != nil {
+		return err
+	}
+
+	if !item.Finished.IsZero() {
+		return errors.New(ERR_MATCH_FINISHED)
+	}
+
+	if item.Court == 0 {
+		return errors.New(ERR_MATCH_NOT_CALLED)
+	}
+
+	item.Finished = parser.UnixTime{Time: now}
+
+	for c := range s.Courts {
+		if s.Courts[c].Item == id {
+			s.Courts[c].Item = 0
+		}
+	}
+
+	return nil
+}
+
+// Returns the expected start of each queued match by id. Running matches
+// are expected to take their estimate, and queued matches are expected to
+// be called in order to the court that becomes free first.
+func (s Schedule) Forecast(now time.Time) map[int]time.Time {
+	forecast := make(map[int]time.Time)
+
+	if len(s.Courts) == 0 {
+		return forecast
+	}
+
+	available, _ := s.available()
+	rest := time.Duration(s.Rest) * time.Minute
+	free := make([]time.Time, len(s.Courts))
+
+	for c, court := range s.Courts {
+		free[c] = now
+
+		if court.Item == 0 {
+			continue
+		}
+
+		item := s.Items[court.Item-1]
+		end := item.Called.Add(time.Duration(item.Estimate) * time.Minute)
+
+		if end.After(now) {
+			free[c] = end
+		}
+
+		for _, name := range item.players() {
+			available[name] = free[c].Add(rest)
+		}
+	}
+
+	for _, item := range s.Items {
+		if !item.Queued() {
+			continue
+		}
+
+		first := 0
+
+		for c := range free {
+			if free[c].Before(free[first]) {
+				first = c
+			}
+		}
+
+		start := free[first]
+
+		for _, name := range item.players() {
+			if available[name].After(start) {
+				start = available[name]
+			}
+		}
+
+		forecast[item.ID] = start
+
+		end := start.Add(time.Duration(item.Estimate) * time.Minute)
+		free[first] = end
+
+		for _, name := range item.players() {
+			available[name] = end.Add(rest)
+		}
+	}
+
+	return forecast
+}
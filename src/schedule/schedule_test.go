@@ -0,0 +1,117 @@
+package schedule
+
+import (
+	"score/src/parser"
+	"score/src/tournament"
+	"testing"
+	"time"
+)
+
+func singles(player1 string, player2 string) Item {
+	return Item{
+		Category: tournament.MenSingles,
+		Mode:     parser.Mode21,
+		Team1:    parser.Team{{Country: "DK", Player: parser.PlayerName(player1)}},
+		Team2:    parser.Team{{Country: "TW", Player: parser.PlayerName(player2)}},
+		Estimate: 40,
+	}
+}
+
+func TestEstimate(t *testing.T) {
+	if estimate := Estimate(0); estimate != DEFAULT_ESTIMATE {
+		t.Errorf("Got %d, want %d", estimate, DEFAULT_ESTIMATE)
+	}
+
+	if estimate := Estimate(45*time.Minute + 10*time.Second); estimate != 45 {
+		t.Errorf("Got %d, want 45", estimate)
+	}
+}
+
+func TestSchedule(t *testing.T) {
+	s := New()
+	now := time.Unix(1679684400, 0)
+
+	if _, err := s.AddCourt(" "); err == nil {
+		t.Error("Expected error for court without name")
+	}
+
+	s.AddCourt("Court 1")
+	s.AddCourt("Court 2")
+
+	if _, err := s.Add(Item{Category: tournament.MenDoubles, Mode: parser.Mode21, Team1: singles("A", "B").Team1}); err == nil {
+		t.Error("Expected error for invalid teams")
+	}
+
+	s.Add(singles("A", "B"))
+	s.Add(singles("C", "D"))
+	// A is on court, so E and F are called first
+	s.Add(singles("A", "C"))
+	s.Add(singles("E", "F"))
+
+	forecast := s.Forecast(now)
+	if !forecast[1].Equal(now) || !forecast[4].Equal(now.Add(40*time.Minute)) || !forecast[3].Equal(now.Add(60*time.Minute)) {
+		t.Errorf("Got forecast %v", forecast)
+	}
+
+	if called := s.Assign(now); len(called) != 2 || called[0].ID != 1 || called[1].ID != 2 || called[1].Court != 2 {
+		t.Fatalf("Got %+v, want matches 1 and 2 called", called)
+	}
+
+	if err := s.Link(3, "uuid"); err == nil {
+		t.Error("Expected error for queued match")
+	}
+
+	if _, err := s.Unlink(1); err == nil {
+		t.Error("Expected error for match without link")
+	}
+
+	if err := s.Link(1, "wrong"); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if match, err := s.Unlink(1); match != "wrong" || err != nil {
+		t.Errorf("Got %q, %v, want unlinked match", match, err)
+	}
+
+	if err := s.Link(1, "uuid"); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if item, ok := s.ItemOf("uuid"); !ok || item.ID != 1 {
+		t.Errorf("Got %+v, want match 1", item)
+	}
+
+	now = now.Add(40 * time.Minute)
+
+	if err := s.Finish(1, now); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if err := s.Finish(1, now); err == nil {
+		t.Error("Expected error for finished match")
+	}
+
+	// A needs to rest and C is still on court
+	if called := s.Assign(now); len(called) != 1 || called[0].ID != 4 || called[0].Court != 1 {
+		t.Fatalf("Got %+v, want match 4 called to court 1", called)
+	}
+
+	now = now.Add(5 * time.Minute)
+	s.Finish(2, now)
+
+	if called := s.Assign(now); len(called) != 0 {
+		t.Errorf("Got %+v, want no match called while A rests", called)
+	}
+
+	now = now.Add(15 * time.Minute)
+
+	if called := s.Assign(now); len(called) != 0 {
+		t.Errorf("Got %+v, want no match called while C rests", called)
+	}
+
+	now = now.Add(5 * time.Minute)
+
+	if called := s.Assign(now); len(called) != 1 || called[0].ID != 3 || called[0].Court != 2 {
+		t.Errorf("Got %+v, want match 3 called to court 2", called)
+	}
+}
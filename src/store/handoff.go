@@ -0,0 +1,142 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// handoff is a handoff code as it is kept by Memory.
+type handoff struct {
+	uuid      string
+	tokenHash string
+	expires   time.Time
+}
+
+func (m *Memory) CreateHandoff(code string, uuid string, expires time.Time) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	record, ok := m.records[uuid]
+	if !ok || len(record.Token) == 0 {
+		return ErrNotFound
+	}
+
+	if _, ok := m.handoffs[code]; ok {
+		return errors.New("cannot create handoff")
+	}
+
+	m.handoffs[code] = handoff{
+		uuid:      uuid,
+		tokenHash: HashToken(record.Token),
+		expires:   expires,
+	}
+
+	return nil
+}
+
+func (m *Memory) Handoff(code string, token string, now time.Time) (string, error) {
+	if len(token) == 0 {
+		return "", ErrInvalidToken
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	h, ok := m.handoffs[code]
+	if !ok || !h.expires.After(now) {
+		return "", ErrNotFound
+	}
+
+	delete(m.handoffs, code)
+
+	record, ok := m.records[h.uuid]
+	if !ok || len(record.Token) == 0 || HashToken(record.Token) != h.tokenHash {
+		return "", ErrInvalidToken
+	}
+
+	record.Token = token
+	m.records[h.uuid] = record
+
+	return h.uuid, nil
+}
+
+// Expired codes are deleted whenever a code is created.
+func (s *SQLite) CreateHandoff(code string, uuid string, expires time.Time) error {
+	if _, err := s.expireHandoffs.Exec(time.Now().Unix()); err != nil {
+		return errors.New("cannot create handoff")
+	}
+
+	record, err := s.Get(uuid)
+	if err != nil {
+		return err
+	}
+
+	if len(record.Token) == 0 {
+		return ErrNotFound
+	}
+
+	if _, err := s.createHandoff.Exec(code, uuid, HashToken(record.Token), expires.Unix()); err != nil {
+		return errors.New("cannot create handoff")
+	}
+
+	return nil
+}
+
+// The token is only moved if its hash still is the hash that was recorded for
+// the code, so that a match is never scored by two clients. Codes only record
+// the hash, so that the database does not hold another copy of the token.
+func (s *SQLite) Handoff(code string, token string, now time.Time) (string, error) {
+	if len(token) == 0 {
+		return "", ErrInvalidToken
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return "", errors.New("cannot hand off match")
+	}
+
+	defer tx.Rollback()
+
+	var uuid, tokenHash string
+
+	err = tx.Stmt(s.getHandoff).QueryRow(code, now.Unix()).Scan(&uuid, &tokenHash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrNotFound
+	} else if err != nil {
+		return "", errors.New("cannot hand off match")
+	}
+
+	if _, err := tx.Stmt(s.deleteHandoff).Exec(code); err != nil {
+		return "", errors.New("cannot hand off match")
+	}
+
+	record, err := scanRecord(tx.Stmt(s.get).QueryRow(uuid).Scan)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return "", errors.New("cannot hand off match")
+	}
+
+	var n int64
+
+	if len(record.Token) > 0 && HashToken(record.Token) == tokenHash {
+		result, err := tx.Stmt(s.transferToken).Exec(token, uuid, record.Token)
+		if err != nil {
+			return "", errors.New("cannot hand off match")
+		}
+
+		if n, err = result.RowsAffected(); err != nil {
+			return "", errors.New("cannot hand off match")
+		}
+	}
+
+	// the code is used up either way
+	if err := tx.Commit(); err != nil {
+		return "", errors.New("cannot hand off match")
+	}
+
+	if n == 0 {
+		return "", ErrInvalidToken
+	}
+
+	return uuid, nil
+}
@@ -0,0 +1,205 @@
+package store
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Memory implements all stores and keeps everything in memory, e.g. for
+// tests.
+type Memory struct {
+	mutex     sync.RWMutex
+	records   map[string]Record
+	revisions map[string][]Revision
+
+	tournaments map[string]TournamentRecord
+	// in order of creation
+	draws      []DrawRecord
+	matchDraws map[string]string
+
+	// in order of creation
+	ties      []TieRecord
+	matchTies map[string]string
+
+	schedule string
+
+	// plans of planned matches by uuid, and their uuids in order of creation
+	plans   map[string]string
+	planned []string
+
+	handoffs map[string]handoff
+
+	users    map[string]UserRecord
+	sessions map[string]SessionRecord
+	// in order of creation
+	audit []AuditRecord
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		records:   make(map[string]Record),
+		revisions: make(map[string][]Revision),
+
+		tournaments: make(map[string]TournamentRecord),
+		matchDraws:  make(map[string]string),
+
+		matchTies: make(map[string]string),
+
+		plans: make(map[string]string),
+
+		handoffs: make(map[string]handoff),
+
+		users:    make(map[string]UserRecord),
+		sessions: make(map[string]SessionRecord),
+	}
+}
+
+func (m *Memory) Create(token string) (string, error) {
+	if len(token) == 0 {
+		return "", ErrInvalidToken
+	}
+
+	uuid, err := uuid.NewRandom()
+	if err != nil {
+		return "", errors.New("cannot generate match uuid")
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.records[uuid.String()] = Record{
+		UUID:     uuid.String(),
+		Token:    token,
+		Modified: time.Now(),
+	}
+
+	return uuid.String(), nil
+}
+
+func (m *Memory) Update(uuid string, token string, json string, finished bool) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	record, ok := m.records[uuid]
+	if !ok || len(token) == 0 || record.Token != token {
+		return ErrNotFound
+	}
+
+	record.JSON = json
+	record.Modified = time.Now()
+
+	if finished {
+		record.Token = ""
+	}
+
+	m.records[uuid] = record
+	m.revisions[uuid] = append(m.revisions[uuid], Revision{
+		UUID:      uuid,
+		Revision:  len(m.revisions[uuid]) + 1,
+		Created:   record.Modified,
+		TokenHash: HashToken(token),
+		JSON:      json,
+	})
+
+	return nil
+}
+
+func (m *Memory) Get(uuid string) (Record, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	record, ok := m.records[uuid]
+	if !ok {
+		return Record{}, ErrNotFound
+	}
+
+	return record, nil
+}
+
+func (m *Memory) List(since time.Time) ([]Record, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	var records []Record
+
+	for _, record := range m.records {
+		if len(record.JSON) != 0 && !record.Modified.Before(since) {
+			records = append(records, record)
+		}
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Modified.After(records[j].Modified)
+	})
+
+	return records, nil
+}
+
+func (m *Memory) Revisions(uuid string) ([]Revision, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	if _, ok := m.records[uuid]; !ok {
+		return nil, ErrNotFound
+	}
+
+	return append([]Revision(nil), m.revisions[uuid]...), nil
+}
+
+func (m *Memory) Revision(uuid string, revision int) (Revision, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	revisions := m.revisions[uuid]
+
+	if revision < 1 || revision > len(revisions) {
+		return Revision{}, ErrNotFound
+	}
+
+	return revisions[revision-1], nil
+}
+
+func (m *Memory) Delete(uuid string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if _, ok := m.records[uuid]; !ok {
+		return ErrNotFound
+	}
+
+	delete(m.records, uuid)
+	delete(m.revisions, uuid)
+	delete(m.matchDraws, uuid)
+	delete(m.matchTies, uuid)
+	delete(m.plans, uuid)
+
+	return nil
+}
+
+func (m *Memory) GetSchedule() (string, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	if len(m.schedule) == 0 {
+		return "", ErrNotFound
+	}
+
+	return m.schedule, nil
+}
+
+func (m *Memory) SaveSchedule(json string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.schedule = json
+
+	return nil
+}
+
+func (m *Memory) Close() error {
+	return nil
+}
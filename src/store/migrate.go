@@ -0,0 +1,201 @@
+package store
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Migration is a single step of the database schema. Migrations are applied
+// in order of their version, which is the number prefix of the file name in
+// the migrations directory, e.g. 0002_add_revisions.sql.
+type Migration struct {
+	Version int
+	Name    string
+	SQL     string
+}
+
+// Migrator applies migrations to a SQLite database. It is used by the
+// migrate command to inspect the schema without serving matches.
+type Migrator struct {
+	db *sql.DB
+}
+
+// Returns all embedded migrations, ordered by version.
+func Migrations() ([]Migration, error) {
+	entries, err := migrationFiles.ReadDir("migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	var migrations []Migration
+
+	for _, entry := range entries {
+		name := strings.TrimSuffix(entry.Name(), ".sql")
+
+		prefix, _, _ := strings.Cut(name, "_")
+		version, err := strconv.Atoi(prefix)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("invalid migration name %s", entry.Name())
+		}
+
+		content, err := migrationFiles.ReadFile(path.Join("migrations", entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		migrations = append(migrations, Migration{
+			Version: version,
+			Name:    name,
+			SQL:     string(content),
+		})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].Version == migrations[i-1].Version {
+			return nil, fmt.Errorf("duplicate migration version %d", migrations[i].Version)
+		}
+	}
+
+	return migrations, nil
+}
+
+func NewMigrator(path string) (*Migrator, error) {
+	db, err := openSQLite(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{db: db}, nil
+}
+
+// Opens the database at the given path read-only, e.g. to print its status.
+// Neither the database nor its journal mode is changed, and the database is
+// not created if it does not exist. Up fails on read-only migrators.
+func NewReadOnlyMigrator(path string) (*Migrator, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open("sqlite3", "file:"+path+"?mode=ro&_busy_timeout="+fmt.Sprint(BUSY_TIMEOUT))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{db: db}, nil
+}
+
+func (m *Migrator) init() error {
+	_, err := m.db.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER NOT NULL PRIMARY KEY,
+			name    TEXT NOT NULL,
+			applied DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+	`)
+
+	return err
+}
+
+// Returns the schema version of the database, which is 0 if no migration
+// was applied yet. The database is not modified, so that status and dry-run
+// can be used on databases that should not be migrated yet.
+func (m *Migrator) Version() (int, error) {
+	var tables int
+
+	err := m.db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations'").Scan(&tables)
+	if err != nil || tables == 0 {
+		return 0, err
+	}
+
+	var version int
+
+	err = m.db.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&version)
+	return version, err
+}
+
+// Returns the migrations that were not applied yet. The database must not
+// be newer than the latest embedded migration, since it would then be
+// unknown whether this version can work with it.
+func (m *Migrator) Pending() ([]Migration, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	version, err := m.Version()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(migrations) > 0 && version > migrations[len(migrations)-1].Version {
+		return nil, fmt.Errorf("database schema version %d is newer than supported version %d", version, migrations[len(migrations)-1].Version)
+	}
+
+	var pending []Migration
+
+	for _, migration := range migrations {
+		if migration.Version > version {
+			pending = append(pending, migration)
+		}
+	}
+
+	return pending, nil
+}
+
+// Applies all pending migrations and returns them. Every migration runs in
+// its own transaction, so a failing migration leaves the database at the
+// version of the previous one.
+func (m *Migrator) Up() ([]Migration, error) {
+	if err := m.init(); err != nil {
+		return nil, err
+	}
+
+	pending, err := m.Pending()
+	if err != nil {
+		return nil, err
+	}
+
+	for i, migration := range pending {
+		if err := m.apply(migration); err != nil {
+			return pending[:i], fmt.Errorf("migration %s failed: %w", migration.Name, err)
+		}
+	}
+
+	return pending, nil
+}
+
+func (m *Migrator) apply(migration Migration) error {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(migration.SQL); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("INSERT INTO schema_migrations (version, name) VALUES (?, ?)", migration.Version, migration.Name); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (m *Migrator) Close() error {
+	return m.db.Close()
+}
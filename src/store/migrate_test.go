@@ -0,0 +1,152 @@
+package store
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMigrateLegacyDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "score.sqlite")
+
+	// schema of databases created before migrations were introduced
+	db, err := openSQLite(path)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE matches (
+			uuid     TEXT NOT NULL PRIMARY KEY,
+			token    TEXT,
+			json     TEXT,
+			modified DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+		INSERT INTO matches (uuid, token, json) VALUES ('legacy', NULL, '{}');
+	`)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	db.Close()
+
+	migrator, err := NewMigrator(path)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	migrations, err := Migrations()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	pending, err := migrator.Pending()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if len(pending) != len(migrations) {
+		t.Errorf("Got %d pending migrations, want %d", len(pending), len(migrations))
+	}
+
+	if _, err := migrator.Up(); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	version, err := migrator.Version()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if want := migrations[len(migrations)-1].Version; version != want {
+		t.Errorf("Got version %d, want %d", version, want)
+	}
+
+	// applying migrations again does nothing
+	if applied, err := migrator.Up(); err != nil || len(applied) != 0 {
+		t.Errorf("Got %d applied migrations (%v), want 0", len(applied), err)
+	}
+
+	migrator.Close()
+
+	s, err := NewSQLite(path)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	defer s.Close()
+
+	if record, err := s.Get("legacy"); err != nil || record.JSON != "{}" {
+		t.Errorf("Got %+v (%v), want legacy match", record, err)
+	}
+
+	if revision, err := s.Revision("legacy", 1); err != nil || revision.JSON != "{}" {
+		t.Errorf("Got %+v (%v), want first revision of legacy match", revision, err)
+	}
+}
+
+func TestMigrateNewerDatabase(t *testing.T) {
+	migrator, err := NewMigrator(filepath.Join(t.TempDir(), "score.sqlite"))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	defer migrator.Close()
+
+	if _, err := migrator.Up(); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if _, err := migrator.db.Exec("INSERT INTO schema_migrations (version, name) VALUES (9999, 'future')"); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if _, err := migrator.Up(); err == nil {
+		t.Error("Expected error for newer schema version")
+	}
+}
+
+func TestMigrateReadOnly(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "score.sqlite")
+
+	// a missing database is not created
+	if _, err := NewReadOnlyMigrator(path); err == nil {
+		t.Error("Expected error for missing database")
+	}
+
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatal("Expected database not to be created")
+	}
+
+	db, err := sql.Open("sqlite3", "file:"+path)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if _, err := db.Exec("CREATE TABLE matches (uuid TEXT NOT NULL PRIMARY KEY)"); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	db.Close()
+
+	migrator, err := NewReadOnlyMigrator(path)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	defer migrator.Close()
+
+	if pending, err := migrator.Pending(); err != nil || len(pending) == 0 {
+		t.Errorf("Got %d pending migrations (%v), want all", len(pending), err)
+	}
+
+	var mode string
+	if err := migrator.db.QueryRow("PRAGMA journal_mode").Scan(&mode); err != nil || mode != "delete" {
+		t.Errorf("Got journal mode %q (%v), want delete", mode, err)
+	}
+
+	if _, err := migrator.Up(); err == nil {
+		t.Error("Expected error for migrating read-only database")
+	}
+}
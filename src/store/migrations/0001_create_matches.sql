@@ -0,0 +1,13 @@
+-- Initial schema. Databases created before migrations were introduced
+-- already contain this table, so the statements must be idempotent.
+CREATE TABLE IF NOT EXISTS matches (
+	uuid     TEXT NOT NULL PRIMARY KEY,
+	token    TEXT,
+	json     TEXT,
+	modified DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TRIGGER IF NOT EXISTS update_modified AFTER UPDATE ON matches
+BEGIN
+	UPDATE matches SET modified = datetime('now') WHERE json = NEW.json;
+END;
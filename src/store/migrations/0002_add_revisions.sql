@@ -0,0 +1,23 @@
+-- Every accepted update is stored as a numbered revision of the match.
+CREATE TABLE match_revisions (
+	uuid       TEXT NOT NULL,
+	revision   INTEGER NOT NULL,
+	created    DATETIME DEFAULT CURRENT_TIMESTAMP,
+	token_hash TEXT NOT NULL,
+	json       TEXT NOT NULL,
+	PRIMARY KEY (uuid, revision)
+);
+
+-- The current state of existing matches becomes their first revision. The
+-- token of earlier updates is unknown.
+INSERT INTO match_revisions (uuid, revision, created, token_hash, json)
+	SELECT uuid, 1, modified, '', json FROM matches WHERE json IS NOT NULL;
+
+-- The trigger matched rows by their JSON, which also touched other matches
+-- with the same JSON, e.g. matches that were just started.
+DROP TRIGGER IF EXISTS update_modified;
+
+CREATE TRIGGER update_modified AFTER UPDATE ON matches
+BEGIN
+	UPDATE matches SET modified = datetime('now') WHERE uuid = NEW.uuid;
+END;
@@ -0,0 +1,18 @@
+CREATE TABLE tournaments (
+	id      TEXT NOT NULL PRIMARY KEY,
+	name    TEXT NOT NULL,
+	created DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Draws are stored as JSON, including their entries and positions.
+CREATE TABLE draws (
+	id            TEXT NOT NULL PRIMARY KEY,
+	tournament_id TEXT NOT NULL,
+	json          TEXT NOT NULL,
+	modified      DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX draws_tournament_id ON draws (tournament_id);
+
+-- draw of matches that are played at a draw position
+ALTER TABLE matches ADD COLUMN draw_id TEXT;
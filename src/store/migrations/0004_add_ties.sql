@@ -0,0 +1,7 @@
+-- Ties are stored as JSON, including their rubbers and linked matches.
+CREATE TABLE ties (
+	id       TEXT NOT NULL PRIMARY KEY,
+	json     TEXT NOT NULL,
+	created  DATETIME DEFAULT CURRENT_TIMESTAMP,
+	modified DATETIME DEFAULT CURRENT_TIMESTAMP
+);
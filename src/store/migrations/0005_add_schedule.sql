@@ -0,0 +1,6 @@
+-- The order of play of the venue is a single row, stored as JSON.
+CREATE TABLE schedule (
+	id       INTEGER NOT NULL PRIMARY KEY CHECK (id = 1),
+	json     TEXT NOT NULL,
+	modified DATETIME DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,2 @@
+-- plan of matches that are created before play starts, as JSON
+ALTER TABLE matches ADD COLUMN plan TEXT;
@@ -0,0 +1,9 @@
+-- Handoff codes move the token of a match to another client. The token of
+-- the match is recorded when the code is created, and codes expire at the
+-- given Unix time.
+CREATE TABLE handoffs (
+	code    TEXT NOT NULL PRIMARY KEY,
+	uuid    TEXT NOT NULL,
+	token   TEXT NOT NULL,
+	expires INTEGER NOT NULL
+);
@@ -0,0 +1,26 @@
+-- Local accounts. Passwords are only stored as a hash, see auth.HashPassword.
+CREATE TABLE users (
+	username      TEXT NOT NULL PRIMARY KEY,
+	password_hash TEXT NOT NULL,
+	role          TEXT NOT NULL,
+	created       DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Sessions are stored by the hash of their token and expire at the given
+-- Unix time.
+CREATE TABLE sessions (
+	token_hash TEXT NOT NULL PRIMARY KEY,
+	username   TEXT NOT NULL,
+	expires    INTEGER NOT NULL
+);
+
+CREATE INDEX sessions_username ON sessions (username);
+
+-- what users did, e.g. creating a tournament or scoring a match
+CREATE TABLE audit (
+	id       INTEGER PRIMARY KEY AUTOINCREMENT,
+	created  DATETIME DEFAULT CURRENT_TIMESTAMP,
+	username TEXT NOT NULL,
+	action   TEXT NOT NULL,
+	target   TEXT NOT NULL
+);
@@ -0,0 +1,13 @@
+-- Fields of the JSON of a match that matches are filtered and sorted by, see
+-- MatchStore.Query. They are derived from the JSON, so updates do not write
+-- them and existing matches do not need to be filled in.
+ALTER TABLE matches ADD COLUMN mode INTEGER
+	GENERATED ALWAYS AS (CASE WHEN json_valid(json) THEN json_extract(json, '$.info.mode') END) VIRTUAL;
+ALTER TABLE matches ADD COLUMN start_time INTEGER
+	GENERATED ALWAYS AS (CASE WHEN json_valid(json) THEN json_extract(json, '$.info.start') END) VIRTUAL;
+-- running matches have no end
+ALTER TABLE matches ADD COLUMN end_time INTEGER
+	GENERATED ALWAYS AS (CASE WHEN json_valid(json) THEN NULLIF(json_extract(json, '$.info.end'), 0) END) VIRTUAL;
+
+CREATE INDEX matches_start_time ON matches (start_time);
+CREATE INDEX matches_modified ON matches (modified);
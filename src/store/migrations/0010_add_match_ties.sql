@@ -0,0 +1,7 @@
+-- tie of matches that are played as a rubber
+ALTER TABLE matches ADD COLUMN tie_id TEXT;
+
+UPDATE matches SET tie_id = (
+	SELECT ties.id FROM ties, json_each(ties.json, '$.rubbers') AS rubber
+	WHERE json_extract(rubber.value, '$.match') = matches.uuid
+);
@@ -0,0 +1,5 @@
+-- Handoff codes only record the hash of the token of their match, see
+-- HashToken. Codes are short-lived, so existing codes are dropped instead of
+-- hashed.
+DELETE FROM handoffs;
+ALTER TABLE handoffs RENAME COLUMN token TO token_hash;
@@ -0,0 +1,9 @@
+-- courts of planned matches are the ids of the courts of the schedule
+-- instead of free names. Names that are not a court of the schedule are
+-- dropped.
+UPDATE matches SET plan = json_set(plan, '$.court', (
+	SELECT json_extract(court.value, '$.id') FROM schedule, json_each(schedule.json, '$.courts') AS court
+	WHERE json_extract(court.value, '$.name') = json_extract(matches.plan, '$.court')
+)) WHERE plan IS NOT NULL AND json_type(plan, '$.court') = 'text';
+
+UPDATE matches SET plan = json_remove(plan, '$.court') WHERE plan IS NOT NULL AND json_type(plan, '$.court') = 'null';
@@ -0,0 +1,202 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func (m *Memory) CreatePlanned(plan string) (string, error) {
+	uuid, err := uuid.NewRandom()
+	if err != nil {
+		return "", errors.New("cannot generate match uuid")
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.records[uuid.String()] = Record{
+		UUID:     uuid.String(),
+		Modified: time.Now(),
+	}
+	m.plans[uuid.String()] = plan
+	m.planned = append(m.planned, uuid.String())
+
+	return uuid.String(), nil
+}
+
+// must be called with the mutex held
+func (m *Memory) getPlanned(uuid string) (PlannedRecord, error) {
+	record, ok := m.records[uuid]
+	plan, planned := m.plans[uuid]
+
+	if !ok || !planned {
+		return PlannedRecord{}, ErrNotFound
+	}
+
+	return PlannedRecord{
+		UUID:     uuid,
+		Plan:     plan,
+		Claimed:  len(record.Token) != 0 || len(record.JSON) != 0,
+		Started:  len(record.JSON) != 0,
+		Modified: record.Modified,
+	}, nil
+}
+
+func (m *Memory) GetPlanned(uuid string) (PlannedRecord, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return m.getPlanned(uuid)
+}
+
+func (m *Memory) ListPlanned() ([]PlannedRecord, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	var planned []PlannedRecord
+
+	for _, uuid := range m.planned {
+		// deleted matches are skipped
+		if record, err := m.getPlanned(uuid); err == nil && !record.Started {
+			planned = append(planned, record)
+		}
+	}
+
+	return planned, nil
+}
+
+func (m *Memory) Claim(uuid string, token string) error {
+	if len(token) == 0 {
+		return ErrInvalidToken
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	planned, err := m.getPlanned(uuid)
+	if err != nil {
+		return err
+	}
+
+	if planned.Claimed {
+		return ErrClaimed
+	}
+
+	record := m.records[uuid]
+	record.Token = token
+	record.Modified = time.Now()
+	m.records[uuid] = record
+
+	return nil
+}
+
+func (m *Memory) Unclaim(uuid string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	planned, err := m.getPlanned(uuid)
+	if err != nil {
+		return err
+	}
+
+	if planned.Started {
+		return ErrNotFound
+	}
+
+	record := m.records[uuid]
+	record.Token = ""
+	record.Modified = time.Now()
+	m.records[uuid] = record
+
+	return nil
+}
+
+func (s *SQLite) CreatePlanned(plan string) (string, error) {
+	uuid, err := uuid.NewRandom()
+	if err != nil {
+		return "", errors.New("cannot generate match uuid")
+	}
+
+	if _, err := s.createPlanned.Exec(uuid.String(), plan); err != nil {
+		return "", errors.New("cannot create match")
+	}
+
+	return uuid.String(), nil
+}
+
+func scanPlanned(scan func(dest ...any) error) (PlannedRecord, error) {
+	var planned PlannedRecord
+
+	err := scan(&planned.UUID, &planned.Plan, &planned.Claimed, &planned.Started, &planned.Modified)
+	return planned, err
+}
+
+func (s *SQLite) GetPlanned(uuid string) (PlannedRecord, error) {
+	planned, err := scanPlanned(s.getPlanned.QueryRow(uuid).Scan)
+	if errors.Is(err, sql.ErrNoRows) {
+		return planned, ErrNotFound
+	}
+
+	return planned, err
+}
+
+func (s *SQLite) ListPlanned() ([]PlannedRecord, error) {
+	var planned []PlannedRecord
+
+	rows, err := s.listPlanned.Query()
+	if err != nil {
+		return planned, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		record, err := scanPlanned(rows.Scan)
+		if err != nil {
+			return planned, err
+		}
+
+		planned = append(planned, record)
+	}
+
+	return planned, rows.Err()
+}
+
+// The token is only set if the match has not been claimed, so that two
+// clients cannot claim the same match.
+func (s *SQLite) Claim(uuid string, token string) error {
+	if len(token) == 0 {
+		return ErrInvalidToken
+	}
+
+	result, err := s.claim.Exec(token, uuid)
+	if err != nil {
+		return errors.New("cannot claim match")
+	}
+
+	if n, err := result.RowsAffected(); err == nil && n == 1 {
+		return nil
+	}
+
+	if _, err := s.GetPlanned(uuid); err != nil {
+		return err
+	}
+
+	return ErrClaimed
+}
+
+func (s *SQLite) Unclaim(uuid string) error {
+	result, err := s.unclaim.Exec(uuid)
+	if err != nil {
+		return errors.New("cannot unclaim match")
+	}
+
+	if n, err := result.RowsAffected(); err != nil || n != 1 {
+		return ErrNotFound
+	}
+
+	return nil
+}
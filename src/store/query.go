@@ -0,0 +1,273 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Fields of the JSON of a match that queries select by.
+type matchInfo struct {
+	Info struct {
+		Mode  int    `json:"mode"`
+		Start *int64 `json:"start"`
+		End   *int64 `json:"end"`
+		Team1 []struct {
+			Player string `json:"player"`
+		} `json:"team1"`
+		Team2 []struct {
+			Player string `json:"player"`
+		} `json:"team2"`
+	} `json:"info"`
+}
+
+func (i matchInfo) start() int64 {
+	if i.Info.Start == nil {
+		return 0
+	}
+
+	return *i.Info.Start
+}
+
+// Returns the seconds between the start and the end of a match, or now if it
+// is running.
+func (i matchInfo) duration(now time.Time) int64 {
+	end := now.Unix()
+	if i.Info.End != nil && *i.Info.End != 0 {
+		end = *i.Info.End
+	}
+
+	if end < i.start() {
+		return 0
+	}
+
+	return end - i.start()
+}
+
+func (i matchInfo) hasPlayer(player string) bool {
+	player = strings.ToLower(player)
+
+	for _, p := range append(i.Info.Team1, i.Info.Team2...) {
+		if strings.Contains(strings.ToLower(p.Player), player) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (q MatchQuery) selects(record Record, info matchInfo) bool {
+	if q.Finished != nil && *q.Finished != (record.Token == "") {
+		return false
+	}
+
+	if !q.From.IsZero() && info.start() < q.From.Unix() {
+		return false
+	}
+
+	if !q.To.IsZero() && info.start() >= q.To.Unix() {
+		return false
+	}
+
+	if q.Mode != 0 && info.Info.Mode != q.Mode {
+		return false
+	}
+
+	return q.Player == "" || info.hasPlayer(q.Player)
+}
+
+func (m *Memory) Query(query MatchQuery) ([]Record, int, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	var records []Record
+	infos := make(map[string]matchInfo)
+
+	for _, record := range m.records {
+		var info matchInfo
+
+		if len(record.JSON) == 0 || json.Unmarshal([]byte(record.JSON), &info) != nil {
+			continue
+		}
+
+		if query.selects(record, info) {
+			records = append(records, record)
+			infos[record.UUID] = info
+		}
+	}
+
+	now := time.Now()
+
+	less := func(a Record, b Record) bool {
+		switch query.Sort {
+		case SORT_START:
+			return infos[a.UUID].start() < infos[b.UUID].start()
+		case SORT_DURATION:
+			return infos[a.UUID].duration(now) < infos[b.UUID].duration(now)
+		default:
+			return a.Modified.Before(b.Modified)
+		}
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		if query.Descending {
+			return less(records[j], records[i])
+		}
+
+		return less(records[i], records[j])
+	})
+
+	total := len(records)
+
+	if query.Offset >= len(records) {
+		return nil, total, nil
+	}
+
+	records = records[query.Offset:]
+
+	if query.Limit > 0 && len(records) > query.Limit {
+		records = records[:query.Limit]
+	}
+
+	return records, total, nil
+}
+
+func (m *Memory) AverageDuration(mode int) (time.Duration, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	var sum, n int64
+
+	for _, record := range m.records {
+		var info matchInfo
+
+		if record.Token != "" || len(record.JSON) == 0 || json.Unmarshal([]byte(record.JSON), &info) != nil {
+			continue
+		}
+
+		if info.Info.Mode != mode || info.Info.End == nil || *info.Info.End <= info.start() {
+			continue
+		}
+
+		sum += *info.Info.End - info.start()
+		n++
+	}
+
+	if n == 0 {
+		return 0, nil
+	}
+
+	return time.Duration(sum) * time.Second / time.Duration(n), nil
+}
+
+// Escapes the wildcards of LIKE, which is used with ESCAPE '\'.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// Queries are built from the fields of the query, so they are not prepared.
+// The columns they select by are generated from the JSON, see
+// 0009_add_match_info.sql. LIKE only ignores the case of ASCII letters.
+func (s *SQLite) Query(query MatchQuery) ([]Record, int, error) {
+	where := []string{"json IS NOT NULL"}
+	var args []any
+
+	if query.Finished != nil {
+		// the token of finished matches is deleted
+		if *query.Finished {
+			where = append(where, "token IS NULL")
+		} else {
+			where = append(where, "token IS NOT NULL")
+		}
+	}
+
+	if !query.From.IsZero() {
+		where = append(where, "start_time >= ?")
+		args = append(args, query.From.Unix())
+	}
+
+	if !query.To.IsZero() {
+		where = append(where, "start_time < ?")
+		args = append(args, query.To.Unix())
+	}
+
+	if query.Mode != 0 {
+		where = append(where, "mode = ?")
+		args = append(args, query.Mode)
+	}
+
+	if query.Player != "" {
+		where = append(where, `EXISTS (
+			SELECT 1 FROM (
+				SELECT value FROM json_each(matches.json, '$.info.team1')
+				UNION ALL
+				SELECT value FROM json_each(matches.json, '$.info.team2')
+			) WHERE json_extract(value, '$.player') LIKE ? ESCAPE '\'
+		)`)
+		args = append(args, "%"+escapeLike(query.Player)+"%")
+	}
+
+	condition := strings.Join(where, " AND ")
+
+	var total int
+
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM matches WHERE "+condition, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	order := "modified"
+
+	switch query.Sort {
+	case SORT_START:
+		order = "start_time"
+	case SORT_DURATION:
+		order = "MAX(COALESCE(end_time, CAST(strftime('%s', 'now') AS INTEGER)) - start_time, 0)"
+	}
+
+	direction := " ASC"
+	if query.Descending {
+		direction = " DESC"
+	}
+
+	limit := -1
+	if query.Limit > 0 {
+		limit = query.Limit
+	}
+
+	rows, err := s.db.Query(
+		"SELECT uuid, token, json, modified FROM matches WHERE "+condition+" ORDER BY "+order+direction+", rowid"+direction+" LIMIT ? OFFSET ?",
+		append(args, limit, query.Offset)...,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	defer rows.Close()
+
+	var records []Record
+
+	for rows.Next() {
+		record, err := scanRecord(rows.Scan)
+		if err != nil {
+			return records, 0, err
+		}
+
+		records = append(records, record)
+	}
+
+	return records, total, rows.Err()
+}
+
+// The average is computed from the columns generated from the JSON, so that
+// matches do not need to be parsed.
+func (s *SQLite) AverageDuration(mode int) (time.Duration, error) {
+	var average sql.NullFloat64
+
+	if err := s.averageDuration.QueryRow(mode).Scan(&average); err != nil {
+		return 0, err
+	}
+
+	return time.Duration(average.Float64 * float64(time.Second)), nil
+}
@@ -0,0 +1,394 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const (
+	// Writes are serialized by SQLite anyway. In WAL mode, readers do not
+	// block the writer, so a few connections are kept open for readers.
+	MAX_OPEN_CONNS = 4
+	// milliseconds to wait for a lock before SQLITE_BUSY is returned
+	BUSY_TIMEOUT = 5000
+
+	// format of SQLite's CURRENT_TIMESTAMP, always in UTC
+	TIMESTAMP_FORMAT = "2006-01-02 15:04:05"
+)
+
+// SQLite implements all stores backed by a SQLite database. It keeps its
+// connections and prepared statements open until Close is called.
+type SQLite struct {
+	db *sql.DB
+
+	create *sql.Stmt
+	update *sql.Stmt
+	get    *sql.Stmt
+	list   *sql.Stmt
+	delete *sql.Stmt
+
+	averageDuration *sql.Stmt
+
+	createRevision  *sql.Stmt
+	listRevisions   *sql.Stmt
+	getRevision     *sql.Stmt
+	deleteRevisions *sql.Stmt
+
+	createTournament *sql.Stmt
+	getTournament    *sql.Stmt
+	listTournaments  *sql.Stmt
+	createDraw       *sql.Stmt
+	updateDraw       *sql.Stmt
+	getDraw          *sql.Stmt
+	listDraws        *sql.Stmt
+	linkMatch        *sql.Stmt
+	drawOf           *sql.Stmt
+
+	createTie  *sql.Stmt
+	updateTie  *sql.Stmt
+	getTie     *sql.Stmt
+	listTies   *sql.Stmt
+	linkRubber *sql.Stmt
+	tieOf      *sql.Stmt
+
+	getSchedule  *sql.Stmt
+	saveSchedule *sql.Stmt
+
+	createPlanned *sql.Stmt
+	getPlanned    *sql.Stmt
+	listPlanned   *sql.Stmt
+	claim         *sql.Stmt
+	unclaim       *sql.Stmt
+
+	createHandoff  *sql.Stmt
+	getHandoff     *sql.Stmt
+	deleteHandoff  *sql.Stmt
+	expireHandoffs *sql.Stmt
+	transferToken  *sql.Stmt
+
+	createUser     *sql.Stmt
+	getUser        *sql.Stmt
+	listUsers      *sql.Stmt
+	updateUser     *sql.Stmt
+	deleteUser     *sql.Stmt
+	createSession  *sql.Stmt
+	getSession     *sql.Stmt
+	deleteSession  *sql.Stmt
+	deleteSessions *sql.Stmt
+	expireSessions *sql.Stmt
+	createAudit    *sql.Stmt
+	listAudit      *sql.Stmt
+}
+
+func openSQLite(path string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite3", "file:"+path+"?_journal_mode=WAL&_busy_timeout="+fmt.Sprint(BUSY_TIMEOUT))
+	if err != nil {
+		return nil, err
+	}
+
+	db.SetMaxOpenConns(MAX_OPEN_CONNS)
+	db.SetMaxIdleConns(MAX_OPEN_CONNS)
+	db.SetConnMaxLifetime(0)
+
+	return db, nil
+}
+
+// Opens the database at the given path and applies all pending migrations.
+func NewSQLite(path string) (*SQLite, error) {
+	db, err := openSQLite(path)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &SQLite{db: db}
+
+	if err := s.init(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *SQLite) init() error {
+	migrator := &Migrator{db: s.db}
+
+	if _, err := migrator.Up(); err != nil {
+		return err
+	}
+
+	statements := []struct {
+		stmt **sql.Stmt
+		sql  string
+	}{
+		{&s.create, "INSERT INTO matches (uuid, token) VALUES (?, ?)"},
+		{&s.update, "UPDATE matches SET json = ?, token = ? WHERE uuid = ? AND token = ?"},
+		{&s.get, "SELECT uuid, token, json, modified FROM matches WHERE uuid = ?"},
+		{&s.list, "SELECT uuid, token, json, modified FROM matches WHERE json IS NOT NULL AND modified >= ? ORDER BY modified DESC"},
+		{&s.delete, "DELETE FROM matches WHERE uuid = ?"},
+		{&s.averageDuration, "SELECT AVG(end_time - start_time) FROM matches WHERE token IS NULL AND mode = ? AND end_time > start_time"},
+		{&s.createRevision, "INSERT INTO match_revisions (uuid, revision, token_hash, json) SELECT ?, COALESCE(MAX(revision), 0) + 1, ?, ? FROM match_revisions WHERE uuid = ?"},
+		{&s.listRevisions, "SELECT uuid, revision, created, token_hash, json FROM match_revisions WHERE uuid = ? ORDER BY revision"},
+		{&s.getRevision, "SELECT uuid, revision, created, token_hash, json FROM match_revisions WHERE uuid = ? AND revision = ?"},
+		{&s.deleteRevisions, "DELETE FROM match_revisions WHERE uuid = ?"},
+		{&s.createTournament, "INSERT INTO tournaments (id, name) VALUES (?, ?)"},
+		{&s.getTournament, "SELECT id, name, created FROM tournaments WHERE id = ?"},
+		{&s.listTournaments, "SELECT id, name, created FROM tournaments ORDER BY created DESC, rowid DESC"},
+		{&s.createDraw, "INSERT INTO draws (id, tournament_id, json) VALUES (?, ?, ?)"},
+		{&s.updateDraw, "UPDATE draws SET json = ?, modified = CURRENT_TIMESTAMP WHERE id = ?"},
+		{&s.getDraw, "SELECT id, tournament_id, json, modified FROM draws WHERE id = ?"},
+		{&s.listDraws, "SELECT id, tournament_id, json, modified FROM draws WHERE tournament_id = ? ORDER BY rowid"},
+		{&s.linkMatch, "UPDATE matches SET draw_id = ? WHERE uuid = ?"},
+		{&s.drawOf, "SELECT draw_id FROM matches WHERE uuid = ?"},
+		{&s.createTie, "INSERT INTO ties (id, json) VALUES (?, ?)"},
+		{&s.updateTie, "UPDATE ties SET json = ?, modified = CURRENT_TIMESTAMP WHERE id = ?"},
+		{&s.getTie, "SELECT id, json, created, modified FROM ties WHERE id = ?"},
+		{&s.listTies, "SELECT id, json, created, modified FROM ties ORDER BY created DESC, rowid DESC"},
+		{&s.linkRubber, "UPDATE matches SET tie_id = ? WHERE uuid = ?"},
+		{&s.tieOf, "SELECT tie_id FROM matches WHERE uuid = ?"},
+		{&s.getSchedule, "SELECT json FROM schedule WHERE id = 1"},
+		{&s.saveSchedule, "INSERT INTO schedule (id, json) VALUES (1, ?) ON CONFLICT (id) DO UPDATE SET json = excluded.json, modified = CURRENT_TIMESTAMP"},
+		{&s.createPlanned, "INSERT INTO matches (uuid, plan) VALUES (?, ?)"},
+		{&s.getPlanned, "SELECT uuid, plan, token IS NOT NULL OR json IS NOT NULL, json IS NOT NULL, modified FROM matches WHERE uuid = ? AND plan IS NOT NULL"},
+		{&s.listPlanned, "SELECT uuid, plan, token IS NOT NULL, FALSE, modified FROM matches WHERE plan IS NOT NULL AND json IS NULL ORDER BY rowid"},
+		{&s.claim, "UPDATE matches SET token = ? WHERE uuid = ? AND plan IS NOT NULL AND token IS NULL AND json IS NULL"},
+		{&s.unclaim, "UPDATE matches SET token = NULL WHERE uuid = ? AND plan IS NOT NULL AND json IS NULL"},
+		{&s.createHandoff, "INSERT INTO handoffs (code, uuid, token_hash, expires) VALUES (?, ?, ?, ?)"},
+		{&s.getHandoff, "SELECT uuid, token_hash FROM handoffs WHERE code = ? AND expires > ?"},
+		{&s.deleteHandoff, "DELETE FROM handoffs WHERE code = ?"},
+		{&s.expireHandoffs, "DELETE FROM handoffs WHERE expires <= ?"},
+		{&s.transferToken, "UPDATE matches SET token = ? WHERE uuid = ? AND token = ?"},
+		{&s.createUser, "INSERT INTO users (username, password_hash, role) VALUES (?, ?, ?) ON CONFLICT (username) DO NOTHING"},
+		{&s.getUser, "SELECT username, password_hash, role, created FROM users WHERE username = ?"},
+		{&s.listUsers, "SELECT username, password_hash, role, created FROM users ORDER BY username"},
+		{&s.updateUser, "UPDATE users SET password_hash = ?, role = ? WHERE username = ?"},
+		{&s.deleteUser, "DELETE FROM users WHERE username = ?"},
+		{&s.createSession, "INSERT INTO sessions (token_hash, username, expires) VALUES (?, ?, ?)"},
+		{&s.getSession, "SELECT token_hash, username, expires FROM sessions WHERE token_hash = ? AND expires > ?"},
+		{&s.deleteSession, "DELETE FROM sessions WHERE token_hash = ?"},
+		{&s.deleteSessions, "DELETE FROM sessions WHERE username = ?"},
+		{&s.expireSessions, "DELETE FROM sessions WHERE expires <= ?"},
+		{&s.createAudit, "INSERT INTO audit (username, action, target) VALUES (?, ?, ?)"},
+		{&s.listAudit, "SELECT id, created, username, action, target FROM audit ORDER BY id DESC LIMIT ?"},
+	}
+
+	for _, statement := range statements {
+		stmt, err := s.db.Prepare(statement.sql)
+		if err != nil {
+			return err
+		}
+
+		*statement.stmt = stmt
+	}
+
+	return nil
+}
+
+func (s *SQLite) Create(token string) (string, error) {
+	if len(token) == 0 {
+		return "", ErrInvalidToken
+	}
+
+	uuid, err := uuid.NewRandom()
+	if err != nil {
+		return "", errors.New("cannot generate match uuid")
+	}
+
+	if _, err := s.create.Exec(uuid.String(), token); err != nil {
+		return "", errors.New("cannot create match")
+	}
+
+	return uuid.String(), nil
+}
+
+func (s *SQLite) Update(uuid string, token string, json string, finished bool) error {
+	newToken := sql.NullString{
+		String: token,
+		Valid:  !finished,
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return errors.New("cannot update match")
+	}
+
+	defer tx.Rollback()
+
+	result, err := tx.Stmt(s.update).Exec(json, newToken, uuid, token)
+	if err != nil {
+		return errors.New("cannot update match")
+	}
+
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return ErrNotFound
+	}
+
+	if _, err := tx.Stmt(s.createRevision).Exec(uuid, HashToken(token), json, uuid); err != nil {
+		return errors.New("cannot create revision")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.New("cannot update match")
+	}
+
+	return nil
+}
+
+func scanRecord(scan func(dest ...any) error) (Record, error) {
+	var record Record
+	var token, json sql.NullString
+
+	if err := scan(&record.UUID, &token, &json, &record.Modified); err != nil {
+		return record, err
+	}
+
+	record.Token = token.String
+	record.JSON = json.String
+
+	return record, nil
+}
+
+func (s *SQLite) Get(uuid string) (Record, error) {
+	record, err := scanRecord(s.get.QueryRow(uuid).Scan)
+	if errors.Is(err, sql.ErrNoRows) {
+		return record, ErrNotFound
+	}
+
+	return record, err
+}
+
+func (s *SQLite) List(since time.Time) ([]Record, error) {
+	var records []Record
+
+	rows, err := s.list.Query(since.UTC().Format(TIMESTAMP_FORMAT))
+	if err != nil {
+		return records, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		record, err := scanRecord(rows.Scan)
+		if err != nil {
+			return records, err
+		}
+
+		records = append(records, record)
+	}
+
+	return records, rows.Err()
+}
+
+func scanRevision(scan func(dest ...any) error) (Revision, error) {
+	var revision Revision
+
+	err := scan(&revision.UUID, &revision.Revision, &revision.Created, &revision.TokenHash, &revision.JSON)
+	return revision, err
+}
+
+func (s *SQLite) Revisions(uuid string) ([]Revision, error) {
+	if _, err := s.Get(uuid); err != nil {
+		return nil, err
+	}
+
+	var revisions []Revision
+
+	rows, err := s.listRevisions.Query(uuid)
+	if err != nil {
+		return revisions, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		revision, err := scanRevision(rows.Scan)
+		if err != nil {
+			return revisions, err
+		}
+
+		revisions = append(revisions, revision)
+	}
+
+	return revisions, rows.Err()
+}
+
+func (s *SQLite) Revision(uuid string, revision int) (Revision, error) {
+	r, err := scanRevision(s.getRevision.QueryRow(uuid, revision).Scan)
+	if errors.Is(err, sql.ErrNoRows) {
+		return r, ErrNotFound
+	}
+
+	return r, err
+}
+
+func (s *SQLite) Delete(uuid string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return errors.New("cannot delete match")
+	}
+
+	defer tx.Rollback()
+
+	result, err := tx.Stmt(s.delete).Exec(uuid)
+	if err != nil {
+		return errors.New("cannot delete match")
+	}
+
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return ErrNotFound
+	}
+
+	if _, err := tx.Stmt(s.deleteRevisions).Exec(uuid); err != nil {
+		return errors.New("cannot delete match")
+	}
+
+	return tx.Commit()
+}
+
+func (s *SQLite) GetSchedule() (string, error) {
+	var json string
+
+	err := s.getSchedule.QueryRow().Scan(&json)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrNotFound
+	}
+
+	return json, err
+}
+
+func (s *SQLite) SaveSchedule(json string) error {
+	if _, err := s.saveSchedule.Exec(json); err != nil {
+		return errors.New("cannot save schedule")
+	}
+
+	return nil
+}
+
+func (s *SQLite) Close() error {
+	statements := []*sql.Stmt{
+		s.create, s.update, s.get, s.list, s.delete, s.averageDuration,
+		s.createRevision, s.listRevisions, s.getRevision, s.deleteRevisions,
+		s.createTournament, s.getTournament, s.listTournaments,
+		s.createDraw, s.updateDraw, s.getDraw, s.listDraws, s.linkMatch, s.drawOf,
+		s.createTie, s.updateTie, s.getTie, s.listTies, s.linkRubber, s.tieOf,
+		s.getSchedule, s.saveSchedule,
+		s.createPlanned, s.getPlanned, s.listPlanned, s.claim, s.unclaim,
+		s.createHandoff, s.getHandoff, s.deleteHandoff, s.expireHandoffs, s.transferToken,
+		s.createUser, s.getUser, s.listUsers, s.updateUser, s.deleteUser,
+		s.createSession, s.getSession, s.deleteSession, s.deleteSessions, s.expireSessions,
+		s.createAudit, s.listAudit,
+	}
+
+	for _, stmt := range statements {
+		if stmt != nil {
+			stmt.Close()
+		}
+	}
+
+	return s.db.Close()
+}
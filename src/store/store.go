@@ -0,0 +1,254 @@
+package store
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+var (
+	ErrNotFound     = errors.New("match not found")
+	ErrInvalidToken = errors.New("token is invalid")
+	ErrClaimed      = errors.New("match has already been claimed")
+	ErrExists       = errors.New("already exists")
+)
+
+// Record is a match as it is stored. The JSON is stored as it was sent by
+// the client and is empty until the first update. The token is empty once
+// the match is finished.
+type Record struct {
+	UUID     string
+	Token    string
+	JSON     string
+	Modified time.Time
+}
+
+// Fields that matches can be sorted by, see MatchQuery.
+const (
+	SORT_MODIFIED = "modified"
+	SORT_START    = "start"
+	SORT_DURATION = "duration"
+)
+
+// MatchQuery selects matches by the info of their JSON. Zero values select
+// all matches.
+type MatchQuery struct {
+	// nil selects running and finished matches
+	Finished *bool
+	// matches started in [From, To)
+	From time.Time
+	To   time.Time
+	Mode int
+	// part of the name of a player, case-insensitive
+	Player string
+
+	// one of SORT_MODIFIED, SORT_START or SORT_DURATION
+	Sort       string
+	Descending bool
+	// 0 selects all matches
+	Limit  int
+	Offset int
+}
+
+// Revision is an accepted update of a match. Revisions are numbered per
+// match, starting at 1. The token is only stored as a hash, which is enough
+// to tell apart updates of different clients.
+type Revision struct {
+	UUID      string
+	Revision  int
+	Created   time.Time
+	TokenHash string
+	JSON      string
+}
+
+// MatchStore stores matches. Only the client holding the token of a match
+// may update it.
+type MatchStore interface {
+	// Creates a match for the given token and returns its uuid.
+	Create(token string) (string, error)
+	// Updates the JSON of a match and records it as a new revision. If the
+	// match is finished, the token is deleted so that the match cannot be
+	// updated anymore.
+	Update(uuid string, token string, json string, finished bool) error
+	Get(uuid string) (Record, error)
+	// Returns all matches modified since the given time, most recently
+	// modified first. Matches without JSON are skipped.
+	List(since time.Time) ([]Record, error)
+	// Returns the matches selected by the query and the number of all
+	// matches it selects, regardless of its limit and offset. Matches
+	// without JSON are skipped.
+	Query(query MatchQuery) ([]Record, int, error)
+	// Returns the average duration of finished matches of a mode, or 0 if
+	// there are none.
+	AverageDuration(mode int) (time.Duration, error)
+	// Returns all revisions of a match, oldest first.
+	Revisions(uuid string) ([]Revision, error)
+	// Returns the revision with the given number of a match.
+	Revision(uuid string, revision int) (Revision, error)
+	// Deletes a match and all of its revisions.
+	Delete(uuid string) error
+	Close() error
+}
+
+// TournamentRecord is a tournament, which groups the draws of its categories.
+type TournamentRecord struct {
+	ID      string
+	Name    string
+	Created time.Time
+}
+
+// DrawRecord is a draw of a tournament as it is stored, see tournament.Draw.
+type DrawRecord struct {
+	ID           string
+	TournamentID string
+	JSON         string
+	Modified     time.Time
+}
+
+// TournamentStore stores tournaments and their draws. Matches are linked to
+// the draw they are played in.
+type TournamentStore interface {
+	// Creates a tournament and returns its id.
+	CreateTournament(name string) (string, error)
+	GetTournament(id string) (TournamentRecord, error)
+	// Returns all tournaments, most recently created first.
+	ListTournaments() ([]TournamentRecord, error)
+	// Creates a draw of a tournament and returns its id.
+	CreateDraw(tournamentID string, json string) (string, error)
+	UpdateDraw(id string, json string) error
+	GetDraw(id string) (DrawRecord, error)
+	// Returns all draws of a tournament, in order of creation.
+	ListDraws(tournamentID string) ([]DrawRecord, error)
+	// Links a match to a draw, see DrawOf.
+	LinkMatch(uuid string, drawID string) error
+	// Returns the id of the draw a match is played in.
+	DrawOf(uuid string) (string, error)
+}
+
+// TieRecord is a team competition as it is stored, see tournament.Tie.
+type TieRecord struct {
+	ID       string
+	JSON     string
+	Created  time.Time
+	Modified time.Time
+}
+
+// TieStore stores team competitions, which link the matches of their rubbers.
+type TieStore interface {
+	// Creates a tie and returns its id.
+	CreateTie(json string) (string, error)
+	UpdateTie(id string, json string) error
+	GetTie(id string) (TieRecord, error)
+	// Returns all ties, most recently created first.
+	ListTies() ([]TieRecord, error)
+	// Links a match to a tie, see TieOf.
+	LinkRubber(uuid string, tieID string) error
+	// Returns the id of the tie a match is played in.
+	TieOf(uuid string) (string, error)
+}
+
+// ScheduleStore stores the order of play of the venue, see schedule.Schedule.
+type ScheduleStore interface {
+	// Returns ErrNotFound until the schedule is saved for the first time.
+	GetSchedule() (string, error)
+	SaveSchedule(json string) error
+}
+
+// PlannedRecord is a match that is created before play starts. Its plan is
+// stored as JSON. It has no token until it is claimed by the client that
+// scores it, and is started by the first update of that client.
+type PlannedRecord struct {
+	UUID     string
+	Plan     string
+	Claimed  bool
+	Started  bool
+	Modified time.Time
+}
+
+// PlannedStore stores matches that are created before play starts.
+type PlannedStore interface {
+	// Creates a match without a token and returns its uuid.
+	CreatePlanned(plan string) (string, error)
+	GetPlanned(uuid string) (PlannedRecord, error)
+	// Returns all planned matches that have not been started, in order of
+	// creation.
+	ListPlanned() ([]PlannedRecord, error)
+	// Sets the token of a planned match. Returns ErrClaimed if the match has
+	// already been claimed.
+	Claim(uuid string, token string) error
+	// Removes the token of a planned match that has not been started, so
+	// that it can be claimed again. Returns ErrNotFound otherwise.
+	Unclaim(uuid string) error
+}
+
+// HandoffStore stores short-lived codes that move the token of a match to
+// another client, e.g. if the device of the scorer fails.
+type HandoffStore interface {
+	// Creates a code for a match that is valid until the given time, and
+	// records the hash of the current token of the match, see HashToken. Returns ErrNotFound if the
+	// match has no token, e.g. because it is finished.
+	CreateHandoff(code string, uuid string, expires time.Time) error
+	// Moves the token of the match of a code to the given token and returns
+	// the uuid of the match. A code is used once. Returns ErrNotFound if the
+	// code does not exist or has expired, and ErrInvalidToken if the token of
+	// the match has changed since the code was created.
+	Handoff(code string, token string, now time.Time) (string, error)
+}
+
+// UserRecord is a local account. The role is one of auth.Role.
+type UserRecord struct {
+	Username     string
+	PasswordHash string
+	Role         string
+	Created      time.Time
+}
+
+// SessionRecord is a session of a user that has logged in.
+type SessionRecord struct {
+	TokenHash string
+	Username  string
+	Expires   time.Time
+}
+
+// UserStore stores local accounts and their sessions.
+type UserStore interface {
+	// Returns ErrExists if a user with the same name exists.
+	CreateUser(user UserRecord) error
+	GetUser(username string) (UserRecord, error)
+	// Returns all users, by name.
+	ListUsers() ([]UserRecord, error)
+	// Updates the password hash and role of a user. If the password hash
+	// changes, the sessions of the user are deleted.
+	UpdateUser(user UserRecord) error
+	// Deletes a user and their sessions.
+	DeleteUser(username string) error
+	// Sessions are stored by the hash of their token, see HashToken.
+	CreateSession(tokenHash string, username string, expires time.Time) error
+	// Returns ErrNotFound if the session does not exist or has expired.
+	GetSession(tokenHash string, now time.Time) (SessionRecord, error)
+	DeleteSession(tokenHash string) error
+}
+
+// AuditRecord is something a user did, e.g. an action on a target such as
+// "POST /api/tournaments" or "new" and the uuid of a match.
+type AuditRecord struct {
+	ID       int
+	Created  time.Time
+	Username string
+	Action   string
+	Target   string
+}
+
+// AuditStore stores what users did.
+type AuditStore interface {
+	Audit(username string, action string, target string) error
+	// Returns the given number of most recent records, most recent first.
+	ListAudit(limit int) ([]AuditRecord, error)
+}
+
+// Returns the hex encoded SHA-256 hash of a token.
+func HashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,646 @@
+package store
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func testStore(t *testing.T, s MatchStore) {
+	defer s.Close()
+
+	token := "token"
+
+	uuid, err := s.Create(token)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// matches without JSON are not listed
+	records, err := s.List(time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if len(records) != 0 {
+		t.Errorf("Got %d records, want 0", len(records))
+	}
+
+	if err := s.Update(uuid, "other", "{}", false); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Got %v, want %v", err, ErrNotFound)
+	}
+
+	if err := s.Update(uuid, token, `{}`, false); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if err := s.Update(uuid, token, `{"games":[]}`, true); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	revisions, err := s.Revisions(uuid)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if len(revisions) != 2 || revisions[0].JSON != `{}` || revisions[1].Revision != 2 {
+		t.Errorf("Got %+v, want 2 revisions", revisions)
+	}
+
+	revision, err := s.Revision(uuid, 1)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if revision.JSON != `{}` || revision.TokenHash != HashToken(token) {
+		t.Errorf("Got %+v", revision)
+	}
+
+	if _, err := s.Revision(uuid, 3); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Got %v, want %v", err, ErrNotFound)
+	}
+
+	record, err := s.Get(uuid)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if record.JSON != `{"games":[]}` || record.Token != "" {
+		t.Errorf("Got %+v", record)
+	}
+
+	// the token is deleted once the match is finished
+	if err := s.Update(uuid, token, "{}", false); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Got %v, want %v", err, ErrNotFound)
+	}
+
+	records, err = s.List(time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if len(records) != 1 || records[0].UUID != uuid {
+		t.Errorf("Got %+v, want match %s", records, uuid)
+	}
+
+	if err := s.Delete(uuid); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if _, err := s.Get(uuid); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Got %v, want %v", err, ErrNotFound)
+	}
+
+	if _, err := s.Revision(uuid, 1); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Got %v, want %v", err, ErrNotFound)
+	}
+}
+
+func testTournamentStore(t *testing.T, s interface {
+	MatchStore
+	TournamentStore
+}) {
+	defer s.Close()
+
+	id, err := s.CreateTournament("Club Championships")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if tournament, err := s.GetTournament(id); err != nil || tournament.Name != "Club Championships" {
+		t.Errorf("Got %+v (%v), want tournament %s", tournament, err, id)
+	}
+
+	if tournaments, err := s.ListTournaments(); err != nil || len(tournaments) != 1 {
+		t.Errorf("Got %d tournaments (%v), want 1", len(tournaments), err)
+	}
+
+	if _, err := s.CreateDraw("unknown", "{}"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Got %v, want %v", err, ErrNotFound)
+	}
+
+	drawID, err := s.CreateDraw(id, `{"category":"MS"}`)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if err := s.UpdateDraw(drawID, `{"category":"WS"}`); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if draw, err := s.GetDraw(drawID); err != nil || draw.TournamentID != id || draw.JSON != `{"category":"WS"}` {
+		t.Errorf("Got %+v (%v), want updated draw", draw, err)
+	}
+
+	if draws, err := s.ListDraws(id); err != nil || len(draws) != 1 || draws[0].ID != drawID {
+		t.Errorf("Got %+v (%v), want draw %s", draws, err, drawID)
+	}
+
+	uuid, err := s.Create("token")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if _, err := s.DrawOf(uuid); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Got %v, want %v", err, ErrNotFound)
+	}
+
+	if err := s.LinkMatch(uuid, drawID); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if linked, err := s.DrawOf(uuid); err != nil || linked != drawID {
+		t.Errorf("Got draw %s (%v), want %s", linked, err, drawID)
+	}
+
+	if err := s.LinkMatch("unknown", drawID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Got %v, want %v", err, ErrNotFound)
+	}
+}
+
+func testTieStore(t *testing.T, s interface {
+	MatchStore
+	TieStore
+}) {
+	defer s.Close()
+
+	if _, err := s.GetTie("unknown"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Got %v, want %v", err, ErrNotFound)
+	}
+
+	first, err := s.CreateTie(`{"team1":"BC Berlin"}`)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	second, err := s.CreateTie(`{"team1":"SG Hamburg"}`)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if err := s.UpdateTie(first, `{"team1":"BC Berlin 2"}`); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if err := s.UpdateTie("unknown", "{}"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Got %v, want %v", err, ErrNotFound)
+	}
+
+	if tie, err := s.GetTie(first); err != nil || tie.JSON != `{"team1":"BC Berlin 2"}` {
+		t.Errorf("Got %+v (%v), want updated tie", tie, err)
+	}
+
+	if ties, err := s.ListTies(); err != nil || len(ties) != 2 || ties[0].ID != second {
+		t.Errorf("Got %+v (%v), want tie %s first", ties, err, second)
+	}
+
+	uuid, err := s.Create("token")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if _, err := s.TieOf(uuid); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Got %v, want %v", err, ErrNotFound)
+	}
+
+	if err := s.LinkRubber(uuid, first); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if linked, err := s.TieOf(uuid); err != nil || linked != first {
+		t.Errorf("Got tie %s (%v), want %s", linked, err, first)
+	}
+
+	if err := s.LinkRubber("unknown", first); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Got %v, want %v", err, ErrNotFound)
+	}
+}
+
+func testScheduleStore(t *testing.T, s interface {
+	MatchStore
+	ScheduleStore
+}) {
+	defer s.Close()
+
+	if _, err := s.GetSchedule(); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Got %v, want %v", err, ErrNotFound)
+	}
+
+	for _, json := range []string{`{"rest":20}`, `{"rest":15}`} {
+		if err := s.SaveSchedule(json); err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+
+	if json, err := s.GetSchedule(); err != nil || json != `{"rest":15}` {
+		t.Errorf("Got %s (%v), want saved schedule", json, err)
+	}
+}
+
+func testPlannedStore(t *testing.T, s interface {
+	MatchStore
+	PlannedStore
+}) {
+	defer s.Close()
+
+	token := "token"
+
+	uuid, err := s.CreatePlanned(`{"court":"1"}`)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	other, err := s.CreatePlanned(`{"court":"2"}`)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// only claimed matches are updated
+	if err := s.Update(uuid, "", "{}", false); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Got %v, want %v", err, ErrNotFound)
+	}
+
+	if err := s.Claim(uuid, token); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if err := s.Claim(uuid, "other"); !errors.Is(err, ErrClaimed) {
+		t.Errorf("Got %v, want %v", err, ErrClaimed)
+	}
+
+	if err := s.Claim("unknown", token); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Got %v, want %v", err, ErrNotFound)
+	}
+
+	// an unclaimed match can be claimed by another client
+	if err := s.Unclaim(uuid); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if planned, err := s.GetPlanned(uuid); err != nil || planned.Claimed {
+		t.Errorf("Got %+v (%v), want unclaimed match", planned, err)
+	}
+
+	if err := s.Claim(uuid, token); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if planned, err := s.GetPlanned(uuid); err != nil || !planned.Claimed || planned.Started || planned.Plan != `{"court":"1"}` {
+		t.Errorf("Got %+v (%v), want claimed match", planned, err)
+	}
+
+	if list, err := s.ListPlanned(); err != nil || len(list) != 2 || list[0].UUID != uuid || list[1].Claimed {
+		t.Errorf("Got %+v (%v), want both matches in order", list, err)
+	}
+
+	if err := s.Update(uuid, token, `{"games":[]}`, true); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// a started match is not upcoming anymore, and cannot be claimed again
+	// even though its token is deleted
+	if list, err := s.ListPlanned(); err != nil || len(list) != 1 || list[0].UUID != other {
+		t.Errorf("Got %+v (%v), want only %s", list, err, other)
+	}
+
+	if err := s.Claim(uuid, "other"); !errors.Is(err, ErrClaimed) {
+		t.Errorf("Got %v, want %v", err, ErrClaimed)
+	}
+
+	if err := s.Unclaim(uuid); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Got %v, want %v", err, ErrNotFound)
+	}
+
+	// matches created for a client are not planned
+	created, err := s.Create(token)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if _, err := s.GetPlanned(created); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Got %v, want %v", err, ErrNotFound)
+	}
+}
+
+func testHandoffStore(t *testing.T, s interface {
+	MatchStore
+	HandoffStore
+}) {
+	defer s.Close()
+
+	now := time.Now()
+	token := "token"
+
+	uuid, err := s.Create(token)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if err := s.CreateHandoff("ABC123", "unknown", now.Add(time.Minute)); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Got %v, want %v", err, ErrNotFound)
+	}
+
+	for _, code := range []string{"ABC123", "DEF456", "EXPIRED"} {
+		expires := now.Add(time.Minute)
+		if code == "EXPIRED" {
+			expires = now.Add(-time.Minute)
+		}
+
+		if err := s.CreateHandoff(code, uuid, expires); err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+
+	if err := s.CreateHandoff("ABC123", uuid, now.Add(time.Minute)); err == nil {
+		t.Error("Expected error for duplicate code")
+	}
+
+	if _, err := s.Handoff("EXPIRED", "other", now); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Got %v, want %v", err, ErrNotFound)
+	}
+
+	if moved, err := s.Handoff("ABC123", "other", now); err != nil || moved != uuid {
+		t.Fatalf("Got %s (%v), want %s", moved, err, uuid)
+	}
+
+	// codes are used once
+	if _, err := s.Handoff("ABC123", "third", now); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Got %v, want %v", err, ErrNotFound)
+	}
+
+	if err := s.Update(uuid, token, "{}", false); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Got %v, want %v", err, ErrNotFound)
+	}
+
+	if err := s.Update(uuid, "other", "{}", false); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// the token has changed since the code was created
+	if _, err := s.Handoff("DEF456", "third", now); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Got %v, want %v", err, ErrInvalidToken)
+	}
+
+	if err := s.Update(uuid, "other", "{}", true); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// finished matches cannot be handed off
+	if err := s.CreateHandoff("GHI789", uuid, now.Add(time.Minute)); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Got %v, want %v", err, ErrNotFound)
+	}
+}
+
+func testUserStore(t *testing.T, s interface {
+	MatchStore
+	UserStore
+	AuditStore
+}) {
+	defer s.Close()
+
+	now := time.Now()
+
+	for _, user := range []UserRecord{
+		{Username: "scorer", PasswordHash: "hash", Role: "scorer"},
+		{Username: "desk", PasswordHash: "hash", Role: "organiser"},
+	} {
+		if err := s.CreateUser(user); err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+
+	if err := s.CreateUser(UserRecord{Username: "desk", PasswordHash: "other", Role: "admin"}); !errors.Is(err, ErrExists) {
+		t.Errorf("Got %v, want %v", err, ErrExists)
+	}
+
+	if err := s.UpdateUser(UserRecord{Username: "desk", PasswordHash: "new", Role: "admin"}); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if err := s.UpdateUser(UserRecord{Username: "unknown"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Got %v, want %v", err, ErrNotFound)
+	}
+
+	if user, err := s.GetUser("desk"); err != nil || user.PasswordHash != "new" || user.Role != "admin" {
+		t.Errorf("Got %+v (%v), want updated user", user, err)
+	}
+
+	if users, err := s.ListUsers(); err != nil || len(users) != 2 || users[0].Username != "desk" {
+		t.Errorf("Got %+v (%v), want users by name", users, err)
+	}
+
+	if err := s.CreateSession("current", "scorer", now.Add(time.Hour)); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if err := s.CreateSession("expired", "scorer", now.Add(-time.Hour)); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if session, err := s.GetSession("current", now); err != nil || session.Username != "scorer" {
+		t.Errorf("Got %+v (%v), want session of scorer", session, err)
+	}
+
+	if _, err := s.GetSession("expired", now); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Got %v, want %v", err, ErrNotFound)
+	}
+
+	if err := s.UpdateUser(UserRecord{Username: "scorer", PasswordHash: "hash", Role: "organiser"}); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if _, err := s.GetSession("current", now); err != nil {
+		t.Errorf("Got %v, want session to be kept when the role changes", err)
+	}
+
+	if err := s.CreateSession("other", "scorer", now.Add(time.Hour)); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if err := s.UpdateUser(UserRecord{Username: "scorer", PasswordHash: "new", Role: "organiser"}); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// sessions are deleted when the password changes
+	if _, err := s.GetSession("other", now); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Got %v, want %v", err, ErrNotFound)
+	}
+
+	if err := s.CreateSession("current", "scorer", now.Add(time.Hour)); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if err := s.DeleteUser("scorer"); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// sessions of deleted users are deleted as well
+	if _, err := s.GetSession("current", now); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Got %v, want %v", err, ErrNotFound)
+	}
+
+	if err := s.CreateSession("desk", "desk", now.Add(time.Hour)); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if err := s.DeleteSession("desk"); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if err := s.DeleteSession("desk"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Got %v, want %v", err, ErrNotFound)
+	}
+
+	for _, action := range []string{"first", "second", "third"} {
+		if err := s.Audit("desk", action, "target"); err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+
+	if records, err := s.ListAudit(2); err != nil || len(records) != 2 || records[0].Action != "third" || records[1].Username != "desk" {
+		t.Errorf("Got %+v (%v), want most recent records", records, err)
+	}
+}
+
+func testQuery(t *testing.T, s MatchStore) {
+	defer s.Close()
+
+	create := func(info string, finished bool) string {
+		uuid, err := s.Create("token")
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		if err := s.Update(uuid, "token", `{"info": `+info+`, "games": []}`, finished); err != nil {
+			t.Fatal(err.Error())
+		}
+
+		return uuid
+	}
+
+	short := create(`{"mode": 21, "start": 1000, "end": 1600, "team1": [{"player": "Viktor AXELSEN"}], "team2": [{"player": "CHOU Tien Chen"}]}`, true)
+	long := create(`{"mode": 21, "start": 2000, "end": 5000, "team1": [{"player": "LEE Zii Jia"}], "team2": [{"player": "Anders 100%"}]}`, true)
+	running := create(`{"mode": 15, "start": 3000, "end": null, "team1": [{"player": "A"}], "team2": [{"player": "B"}]}`, false)
+
+	if _, err := s.Create("token"); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	finished := true
+
+	for _, test := range []struct {
+		query MatchQuery
+		want  []string
+		total int
+	}{
+		{MatchQuery{Sort: SORT_START}, []string{short, long, running}, 3},
+		{MatchQuery{Sort: SORT_START, Descending: true, Limit: 2}, []string{running, long}, 3},
+		{MatchQuery{Sort: SORT_START, Limit: 2, Offset: 2}, []string{running}, 3},
+		{MatchQuery{Sort: SORT_START, Offset: 3}, nil, 3},
+		{MatchQuery{Finished: &finished, Sort: SORT_DURATION, Descending: true}, []string{long, short}, 2},
+		{MatchQuery{From: time.Unix(2000, 0), To: time.Unix(3000, 0)}, []string{long}, 1},
+		{MatchQuery{Mode: 15}, []string{running}, 1},
+		{MatchQuery{Player: "axel"}, []string{short}, 1},
+		{MatchQuery{Player: "100%"}, []string{long}, 1},
+		{MatchQuery{Player: "0%"}, []string{long}, 1},
+		{MatchQuery{Player: "_"}, nil, 0},
+	} {
+		records, total, err := s.Query(test.query)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		var got []string
+		for _, record := range records {
+			got = append(got, record.UUID)
+		}
+
+		if total != test.total || len(got) != len(test.want) {
+			t.Errorf("%+v: got %d of %d matches, want %d of %d", test.query, len(got), total, len(test.want), test.total)
+			continue
+		}
+
+		for i := range got {
+			if got[i] != test.want[i] {
+				t.Errorf("%+v: got match %d %s, want %s", test.query, i, got[i], test.want[i])
+			}
+		}
+	}
+
+	// only finished matches are averaged
+	for mode, want := range map[int]time.Duration{21: 30 * time.Minute, 15: 0, 11: 0} {
+		if average, err := s.AverageDuration(mode); err != nil || average != want {
+			t.Errorf("Got average %v (%v) of mode %d, want %v", average, err, mode, want)
+		}
+	}
+}
+
+func TestMemory(t *testing.T) {
+	testStore(t, NewMemory())
+	testQuery(t, NewMemory())
+	testTournamentStore(t, NewMemory())
+	testTieStore(t, NewMemory())
+	testScheduleStore(t, NewMemory())
+	testPlannedStore(t, NewMemory())
+	testHandoffStore(t, NewMemory())
+	testUserStore(t, NewMemory())
+}
+
+func TestSQLite(t *testing.T) {
+	s, err := NewSQLite(filepath.Join(t.TempDir(), "score.sqlite"))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	testStore(t, s)
+
+	s, err = NewSQLite(filepath.Join(t.TempDir(), "score.sqlite"))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	testQuery(t, s)
+
+	s, err = NewSQLite(filepath.Join(t.TempDir(), "score.sqlite"))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	testTournamentStore(t, s)
+
+	s, err = NewSQLite(filepath.Join(t.TempDir(), "score.sqlite"))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	testTieStore(t, s)
+
+	s, err = NewSQLite(filepath.Join(t.TempDir(), "score.sqlite"))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	testScheduleStore(t, s)
+
+	s, err = NewSQLite(filepath.Join(t.TempDir(), "score.sqlite"))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	testPlannedStore(t, s)
+
+	s, err = NewSQLite(filepath.Join(t.TempDir(), "score.sqlite"))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	testHandoffStore(t, s)
+
+	s, err = NewSQLite(filepath.Join(t.TempDir(), "score.sqlite"))
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	testUserStore(t, s)
+}
@@ -0,0 +1,182 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+func (m *Memory) CreateTie(json string) (string, error) {
+	id, err := newID("tie")
+	if err != nil {
+		return "", err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	now := time.Now()
+
+	m.ties = append(m.ties, TieRecord{
+		ID:       id,
+		JSON:     json,
+		Created:  now,
+		Modified: now,
+	})
+
+	return id, nil
+}
+
+func (m *Memory) UpdateTie(id string, json string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for i := range m.ties {
+		if m.ties[i].ID == id {
+			m.ties[i].JSON = json
+			m.ties[i].Modified = time.Now()
+			return nil
+		}
+	}
+
+	return ErrNotFound
+}
+
+func (m *Memory) GetTie(id string) (TieRecord, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	for _, tie := range m.ties {
+		if tie.ID == id {
+			return tie, nil
+		}
+	}
+
+	return TieRecord{}, ErrNotFound
+}
+
+func (m *Memory) ListTies() ([]TieRecord, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	var ties []TieRecord
+
+	for i := len(m.ties) - 1; i >= 0; i-- {
+		ties = append(ties, m.ties[i])
+	}
+
+	return ties, nil
+}
+
+func (s *SQLite) CreateTie(json string) (string, error) {
+	id, err := newID("tie")
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := s.createTie.Exec(id, json); err != nil {
+		return "", errors.New("cannot create tie")
+	}
+
+	return id, nil
+}
+
+func (s *SQLite) UpdateTie(id string, json string) error {
+	result, err := s.updateTie.Exec(json, id)
+	if err != nil {
+		return errors.New("cannot update tie")
+	}
+
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func scanTie(scan func(dest ...any) error) (TieRecord, error) {
+	var tie TieRecord
+
+	err := scan(&tie.ID, &tie.JSON, &tie.Created, &tie.Modified)
+	return tie, err
+}
+
+func (s *SQLite) GetTie(id string) (TieRecord, error) {
+	tie, err := scanTie(s.getTie.QueryRow(id).Scan)
+	if errors.Is(err, sql.ErrNoRows) {
+		return tie, ErrNotFound
+	}
+
+	return tie, err
+}
+
+func (s *SQLite) ListTies() ([]TieRecord, error) {
+	var ties []TieRecord
+
+	rows, err := s.listTies.Query()
+	if err != nil {
+		return ties, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		tie, err := scanTie(rows.Scan)
+		if err != nil {
+			return ties, err
+		}
+
+		ties = append(ties, tie)
+	}
+
+	return ties, rows.Err()
+}
+
+func (m *Memory) LinkRubber(uuid string, tieID string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if _, ok := m.records[uuid]; !ok {
+		return ErrNotFound
+	}
+
+	m.matchTies[uuid] = tieID
+
+	return nil
+}
+
+func (m *Memory) TieOf(uuid string) (string, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	tieID, ok := m.matchTies[uuid]
+	if !ok {
+		return "", ErrNotFound
+	}
+
+	return tieID, nil
+}
+
+func (s *SQLite) LinkRubber(uuid string, tieID string) error {
+	result, err := s.linkRubber.Exec(tieID, uuid)
+	if err != nil {
+		return errors.New("cannot link match")
+	}
+
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *SQLite) TieOf(uuid string) (string, error) {
+	var tieID sql.NullString
+
+	err := s.tieOf.QueryRow(uuid).Scan(&tieID)
+	if errors.Is(err, sql.ErrNoRows) || err == nil && !tieID.Valid {
+		return "", ErrNotFound
+	}
+
+	return tieID.String, err
+}
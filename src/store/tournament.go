@@ -0,0 +1,296 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func newID(kind string) (string, error) {
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return "", errors.New("cannot generate " + kind + " id")
+	}
+
+	return id.String(), nil
+}
+
+func (m *Memory) CreateTournament(name string) (string, error) {
+	id, err := newID("tournament")
+	if err != nil {
+		return "", err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.tournaments[id] = TournamentRecord{
+		ID:      id,
+		Name:    name,
+		Created: time.Now(),
+	}
+
+	return id, nil
+}
+
+func (m *Memory) GetTournament(id string) (TournamentRecord, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	tournament, ok := m.tournaments[id]
+	if !ok {
+		return TournamentRecord{}, ErrNotFound
+	}
+
+	return tournament, nil
+}
+
+func (m *Memory) ListTournaments() ([]TournamentRecord, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	var tournaments []TournamentRecord
+
+	for _, tournament := range m.tournaments {
+		tournaments = append(tournaments, tournament)
+	}
+
+	sort.Slice(tournaments, func(i, j int) bool {
+		return tournaments[i].Created.After(tournaments[j].Created)
+	})
+
+	return tournaments, nil
+}
+
+func (m *Memory) CreateDraw(tournamentID string, json string) (string, error) {
+	id, err := newID("draw")
+	if err != nil {
+		return "", err
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if _, ok := m.tournaments[tournamentID]; !ok {
+		return "", ErrNotFound
+	}
+
+	m.draws = append(m.draws, DrawRecord{
+		ID:           id,
+		TournamentID: tournamentID,
+		JSON:         json,
+		Modified:     time.Now(),
+	})
+
+	return id, nil
+}
+
+func (m *Memory) UpdateDraw(id string, json string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for i := range m.draws {
+		if m.draws[i].ID == id {
+			m.draws[i].JSON = json
+			m.draws[i].Modified = time.Now()
+			return nil
+		}
+	}
+
+	return ErrNotFound
+}
+
+func (m *Memory) GetDraw(id string) (DrawRecord, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	for _, draw := range m.draws {
+		if draw.ID == id {
+			return draw, nil
+		}
+	}
+
+	return DrawRecord{}, ErrNotFound
+}
+
+func (m *Memory) ListDraws(tournamentID string) ([]DrawRecord, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	var draws []DrawRecord
+
+	for _, draw := range m.draws {
+		if draw.TournamentID == tournamentID {
+			draws = append(draws, draw)
+		}
+	}
+
+	return draws, nil
+}
+
+func (m *Memory) LinkMatch(uuid string, drawID string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if _, ok := m.records[uuid]; !ok {
+		return ErrNotFound
+	}
+
+	m.matchDraws[uuid] = drawID
+
+	return nil
+}
+
+func (m *Memory) DrawOf(uuid string) (string, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	drawID, ok := m.matchDraws[uuid]
+	if !ok {
+		return "", ErrNotFound
+	}
+
+	return drawID, nil
+}
+
+func (s *SQLite) CreateTournament(name string) (string, error) {
+	id, err := newID("tournament")
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := s.createTournament.Exec(id, name); err != nil {
+		return "", errors.New("cannot create tournament")
+	}
+
+	return id, nil
+}
+
+func (s *SQLite) GetTournament(id string) (TournamentRecord, error) {
+	var tournament TournamentRecord
+
+	err := s.getTournament.QueryRow(id).Scan(&tournament.ID, &tournament.Name, &tournament.Created)
+	if errors.Is(err, sql.ErrNoRows) {
+		return tournament, ErrNotFound
+	}
+
+	return tournament, err
+}
+
+func (s *SQLite) ListTournaments() ([]TournamentRecord, error) {
+	var tournaments []TournamentRecord
+
+	rows, err := s.listTournaments.Query()
+	if err != nil {
+		return tournaments, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var tournament TournamentRecord
+
+		if err := rows.Scan(&tournament.ID, &tournament.Name, &tournament.Created); err != nil {
+			return tournaments, err
+		}
+
+		tournaments = append(tournaments, tournament)
+	}
+
+	return tournaments, rows.Err()
+}
+
+func (s *SQLite) CreateDraw(tournamentID string, json string) (string, error) {
+	if _, err := s.GetTournament(tournamentID); err != nil {
+		return "", err
+	}
+
+	id, err := newID("draw")
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := s.createDraw.Exec(id, tournamentID, json); err != nil {
+		return "", errors.New("cannot create draw")
+	}
+
+	return id, nil
+}
+
+func (s *SQLite) UpdateDraw(id string, json string) error {
+	result, err := s.updateDraw.Exec(json, id)
+	if err != nil {
+		return errors.New("cannot update draw")
+	}
+
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func scanDraw(scan func(dest ...any) error) (DrawRecord, error) {
+	var draw DrawRecord
+
+	err := scan(&draw.ID, &draw.TournamentID, &draw.JSON, &draw.Modified)
+	return draw, err
+}
+
+func (s *SQLite) GetDraw(id string) (DrawRecord, error) {
+	draw, err := scanDraw(s.getDraw.QueryRow(id).Scan)
+	if errors.Is(err, sql.ErrNoRows) {
+		return draw, ErrNotFound
+	}
+
+	return draw, err
+}
+
+func (s *SQLite) ListDraws(tournamentID string) ([]DrawRecord, error) {
+	var draws []DrawRecord
+
+	rows, err := s.listDraws.Query(tournamentID)
+	if err != nil {
+		return draws, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		draw, err := scanDraw(rows.Scan)
+		if err != nil {
+			return draws, err
+		}
+
+		draws = append(draws, draw)
+	}
+
+	return draws, rows.Err()
+}
+
+func (s *SQLite) LinkMatch(uuid string, drawID string) error {
+	result, err := s.linkMatch.Exec(drawID, uuid)
+	if err != nil {
+		return errors.New("cannot link match")
+	}
+
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *SQLite) DrawOf(uuid string) (string, error) {
+	var drawID sql.NullString
+
+	err := s.drawOf.QueryRow(uuid).Scan(&drawID)
+	if errors.Is(err, sql.ErrNoRows) || err == nil && !drawID.Valid {
+		return "", ErrNotFound
+	}
+
+	return drawID.String, err
+}
@@ -0,0 +1,340 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"sort"
+	"time"
+)
+
+func (m *Memory) CreateUser(user UserRecord) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if _, ok := m.users[user.Username]; ok {
+		return ErrExists
+	}
+
+	user.Created = time.Now()
+	m.users[user.Username] = user
+
+	return nil
+}
+
+func (m *Memory) GetUser(username string) (UserRecord, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	user, ok := m.users[username]
+	if !ok {
+		return UserRecord{}, ErrNotFound
+	}
+
+	return user, nil
+}
+
+func (m *Memory) ListUsers() ([]UserRecord, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	var users []UserRecord
+
+	for _, user := range m.users {
+		users = append(users, user)
+	}
+
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].Username < users[j].Username
+	})
+
+	return users, nil
+}
+
+func (m *Memory) UpdateUser(user UserRecord) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	existing, ok := m.users[user.Username]
+	if !ok {
+		return ErrNotFound
+	}
+
+	if existing.PasswordHash != user.PasswordHash {
+		for hash, session := range m.sessions {
+			if session.Username == user.Username {
+				delete(m.sessions, hash)
+			}
+		}
+	}
+
+	existing.PasswordHash = user.PasswordHash
+	existing.Role = user.Role
+	m.users[user.Username] = existing
+
+	return nil
+}
+
+func (m *Memory) DeleteUser(username string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if _, ok := m.users[username]; !ok {
+		return ErrNotFound
+	}
+
+	delete(m.users, username)
+
+	for hash, session := range m.sessions {
+		if session.Username == username {
+			delete(m.sessions, hash)
+		}
+	}
+
+	return nil
+}
+
+func (m *Memory) CreateSession(tokenHash string, username string, expires time.Time) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if _, ok := m.sessions[tokenHash]; ok {
+		return errors.New("cannot create session")
+	}
+
+	m.sessions[tokenHash] = SessionRecord{
+		TokenHash: tokenHash,
+		Username:  username,
+		Expires:   expires,
+	}
+
+	return nil
+}
+
+func (m *Memory) GetSession(tokenHash string, now time.Time) (SessionRecord, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	session, ok := m.sessions[tokenHash]
+	if !ok || !session.Expires.After(now) {
+		return SessionRecord{}, ErrNotFound
+	}
+
+	return session, nil
+}
+
+func (m *Memory) DeleteSession(tokenHash string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if _, ok := m.sessions[tokenHash]; !ok {
+		return ErrNotFound
+	}
+
+	delete(m.sessions, tokenHash)
+
+	return nil
+}
+
+func (m *Memory) Audit(username string, action string, target string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.audit = append(m.audit, AuditRecord{
+		ID:       len(m.audit) + 1,
+		Created:  time.Now(),
+		Username: username,
+		Action:   action,
+		Target:   target,
+	})
+
+	return nil
+}
+
+func (m *Memory) ListAudit(limit int) ([]AuditRecord, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	var records []AuditRecord
+
+	for i := len(m.audit) - 1; i >= 0 && len(records) < limit; i-- {
+		records = append(records, m.audit[i])
+	}
+
+	return records, nil
+}
+
+func (s *SQLite) CreateUser(user UserRecord) error {
+	result, err := s.createUser.Exec(user.Username, user.PasswordHash, user.Role)
+	if err != nil {
+		return errors.New("cannot create user")
+	}
+
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return ErrExists
+	}
+
+	return nil
+}
+
+func scanUser(scan func(dest ...any) error) (UserRecord, error) {
+	var user UserRecord
+
+	err := scan(&user.Username, &user.PasswordHash, &user.Role, &user.Created)
+	return user, err
+}
+
+func (s *SQLite) GetUser(username string) (UserRecord, error) {
+	user, err := scanUser(s.getUser.QueryRow(username).Scan)
+	if errors.Is(err, sql.ErrNoRows) {
+		return user, ErrNotFound
+	}
+
+	return user, err
+}
+
+func (s *SQLite) ListUsers() ([]UserRecord, error) {
+	var users []UserRecord
+
+	rows, err := s.listUsers.Query()
+	if err != nil {
+		return users, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		user, err := scanUser(rows.Scan)
+		if err != nil {
+			return users, err
+		}
+
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
+
+func (s *SQLite) UpdateUser(user UserRecord) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return errors.New("cannot update user")
+	}
+
+	defer tx.Rollback()
+
+	existing, err := scanUser(tx.Stmt(s.getUser).QueryRow(user.Username).Scan)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	} else if err != nil {
+		return errors.New("cannot update user")
+	}
+
+	if _, err := tx.Stmt(s.updateUser).Exec(user.PasswordHash, user.Role, user.Username); err != nil {
+		return errors.New("cannot update user")
+	}
+
+	if existing.PasswordHash != user.PasswordHash {
+		if _, err := tx.Stmt(s.deleteSessions).Exec(user.Username); err != nil {
+			return errors.New("cannot update user")
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (s *SQLite) DeleteUser(username string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return errors.New("cannot delete user")
+	}
+
+	defer tx.Rollback()
+
+	result, err := tx.Stmt(s.deleteUser).Exec(username)
+	if err != nil {
+		return errors.New("cannot delete user")
+	}
+
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return ErrNotFound
+	}
+
+	if _, err := tx.Stmt(s.deleteSessions).Exec(username); err != nil {
+		return errors.New("cannot delete user")
+	}
+
+	return tx.Commit()
+}
+
+// Expired sessions are deleted whenever a session is created.
+func (s *SQLite) CreateSession(tokenHash string, username string, expires time.Time) error {
+	if _, err := s.expireSessions.Exec(time.Now().Unix()); err != nil {
+		return errors.New("cannot create session")
+	}
+
+	if _, err := s.createSession.Exec(tokenHash, username, expires.Unix()); err != nil {
+		return errors.New("cannot create session")
+	}
+
+	return nil
+}
+
+func (s *SQLite) GetSession(tokenHash string, now time.Time) (SessionRecord, error) {
+	var session SessionRecord
+	var expires int64
+
+	err := s.getSession.QueryRow(tokenHash, now.Unix()).Scan(&session.TokenHash, &session.Username, &expires)
+	if errors.Is(err, sql.ErrNoRows) {
+		return session, ErrNotFound
+	} else if err != nil {
+		return session, err
+	}
+
+	session.Expires = time.Unix(expires, 0)
+
+	return session, nil
+}
+
+func (s *SQLite) DeleteSession(tokenHash string) error {
+	result, err := s.deleteSession.Exec(tokenHash)
+	if err != nil {
+		return errors.New("cannot delete session")
+	}
+
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *SQLite) Audit(username string, action string, target string) error {
+	if _, err := s.createAudit.Exec(username, action, target); err != nil {
+		return errors.New("cannot create audit record")
+	}
+
+	return nil
+}
+
+func (s *SQLite) ListAudit(limit int) ([]AuditRecord, error) {
+	var records []AuditRecord
+
+	rows, err := s.listAudit.Query(limit)
+	if err != nil {
+		return records, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var record AuditRecord
+
+		if err := rows.Scan(&record.ID, &record.Created, &record.Username, &record.Action, &record.Target); err != nil {
+			return records, err
+		}
+
+		records = append(records, record)
+	}
+
+	return records, rows.Err()
+}
@@ -0,0 +1,80 @@
+package throttle
+
+import (
+	"sync"
+	"time"
+)
+
+// Throttle limits the failed attempts of keys, e.g. of IP addresses guessing
+// codes. A key that has failed too often within a window is not allowed to
+// try again until the window has passed.
+type Throttle struct {
+	mutex  sync.Mutex
+	limit  int
+	window time.Duration
+	keys   map[string]*failures
+	// expired keys are pruned at most once per window
+	pruned time.Time
+}
+
+type failures struct {
+	start time.Time
+	count int
+}
+
+// Creates a throttle that allows limit failures per key within window.
+func New(limit int, window time.Duration) *Throttle {
+	return &Throttle{
+		limit:  limit,
+		window: window,
+		keys:   make(map[string]*failures),
+	}
+}
+
+func (t *Throttle) expired(f *failures, now time.Time) bool {
+	return !now.Before(f.start.Add(t.window))
+}
+
+// Returns false if the key has failed as often as allowed within the
+// current window.
+func (t *Throttle) Allow(key string, now time.Time) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	f, ok := t.keys[key]
+
+	return !ok || t.expired(f, now) || f.count < t.limit
+}
+
+// Records a failed attempt of the key. The window of a key starts with its
+// first failure.
+func (t *Throttle) Fail(key string, now time.Time) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if !now.Before(t.pruned.Add(t.window)) {
+		for k, f := range t.keys {
+			if t.expired(f, now) {
+				delete(t.keys, k)
+			}
+		}
+
+		t.pruned = now
+	}
+
+	f, ok := t.keys[key]
+	if !ok || t.expired(f, now) {
+		f = &failures{start: now}
+		t.keys[key] = f
+	}
+
+	f.count++
+}
+
+// Forgets the failures of the key, e.g. once it has logged in.
+func (t *Throttle) Reset(key string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	delete(t.keys, key)
+}
@@ -0,0 +1,57 @@
+package throttle
+
+import (
+	"testing"
+	"time"
+)
+
+func TestThrottle(t *testing.T) {
+	now := time.Unix(1679684400, 0)
+	throttle := New(2, time.Minute)
+
+	for i := 0; i < 2; i++ {
+		if !throttle.Allow("192.0.2.1", now) {
+			t.Fatalf("Got throttled after %d failures, want 2", i)
+		}
+
+		throttle.Fail("192.0.2.1", now)
+	}
+
+	if throttle.Allow("192.0.2.1", now.Add(59*time.Second)) {
+		t.Error("Expected throttled key within window")
+	}
+
+	if !throttle.Allow("192.0.2.2", now) {
+		t.Error("Expected other key to be allowed")
+	}
+
+	if !throttle.Allow("192.0.2.1", now.Add(time.Minute)) {
+		t.Error("Expected key to be allowed after window")
+	}
+
+	// failures of an expired window are forgotten
+	throttle.Fail("192.0.2.1", now.Add(time.Minute))
+
+	if !throttle.Allow("192.0.2.1", now.Add(time.Minute)) {
+		t.Error("Expected key to be allowed after 1 failure in new window")
+	}
+
+	throttle.Fail("192.0.2.1", now.Add(time.Minute))
+	throttle.Reset("192.0.2.1")
+
+	if !throttle.Allow("192.0.2.1", now.Add(time.Minute)) {
+		t.Error("Expected key to be allowed after reset")
+	}
+}
+
+func TestPrune(t *testing.T) {
+	now := time.Unix(1679684400, 0)
+	throttle := New(1, time.Minute)
+
+	throttle.Fail("192.0.2.1", now)
+	throttle.Fail("192.0.2.2", now.Add(2*time.Minute))
+
+	if _, ok := throttle.keys["192.0.2.1"]; ok {
+		t.Error("Expected expired key to be pruned")
+	}
+}
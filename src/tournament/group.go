@@ -0,0 +1,209 @@
+package tournament
+
+import (
+	"score/src/parser"
+	"sort"
+)
+
+// Standing is the record of an entry in a group. Ranks start at 1.
+type Standing struct {
+	Rank       int   `json:"rank"`
+	Entry      Entry `json:"entry"`
+	Played     int   `json:"played"`
+	Won        int   `json:"won"`
+	Lost       int   `json:"lost"`
+	GamesWon   int   `json:"gamesWon"`
+	GamesLost  int   `json:"gamesLost"`
+	PointsWon  int   `json:"pointsWon"`
+	PointsLost int   `json:"pointsLost"`
+}
+
+// result of a finished match between two entries
+type result struct {
+	entry1 int
+	entry2 int
+	winner int
+}
+
+// Generates the fixtures of a group with the circle method: one entry stays
+// in place while the others rotate, so that every entry plays every other
+// entry once. With an odd number of entries, one entry sits out each round.
+func (d *Draw) generateGroup() {
+	ids := make([]int, 0, len(d.Entries)+1)
+
+	for _, entry := range d.Entries {
+		ids = append(ids, entry.ID)
+	}
+
+	if len(ids)%2 == 1 {
+		ids = append(ids, Bye)
+	}
+
+	n := len(ids)
+	d.Positions = []Position{}
+
+	for round := 0; round < n-1; round++ {
+		position := 0
+
+		for i := 0; i < n/2; i++ {
+			entry1, entry2 := ids[i], ids[n-1-i]
+
+			if entry1 == Bye || entry2 == Bye {
+				continue
+			}
+
+			d.Positions = append(d.Positions, Position{
+				Round:    round,
+				Position: position,
+				Entry1:   entry1,
+				Entry2:   entry2,
+			})
+			position++
+		}
+
+		// the first entry stays, the last one moves to the second place
+		ids = append([]int{ids[0], ids[n-1]}, ids[1:n-1]...)
+	}
+}
+
+func (s Standing) gameDifference() int {
+	return s.GamesWon - s.GamesLost
+}
+
+func (s Standing) pointDifference() int {
+	return s.PointsWon - s.PointsLost
+}
+
+// Returns the standings of a group, given the linked matches by uuid. Only
+// finished matches are counted. Entries are ranked by matches won. Ties are
+// broken as in the BWF regulations: two tied entries are ranked by the match
+// between them, three or more by game difference and then by point
+// difference, until two entries remain tied.
+func (d Draw) Standings(matches map[string]parser.Match) []Standing {
+	standings := make([]Standing, len(d.Entries))
+	index := make(map[int]*Standing)
+
+	for i, entry := range d.Entries {
+		standings[i].Entry = entry
+		index[entry.ID] = &standings[i]
+	}
+
+	var results []result
+
+	for _, p := range d.Positions {
+		m, ok := matches[p.Match]
+		if p.Match == "" || !ok || !m.Finished {
+			continue
+		}
+
+		s1, ok1 := index[p.Entry1]
+		s2, ok2 := index[p.Entry2]
+
+		if !ok1 || !ok2 {
+			continue
+		}
+
+		games1, games2 := 0, 0
+
+		for _, game := range m.Games {
+			if game.Winner == parser.Team1 {
+				games1++
+			} else if game.Winner == parser.Team2 {
+				games2++
+			}
+		}
+
+		s1.Played++
+		s2.Played++
+		s1.GamesWon += games1
+		s1.GamesLost += games2
+		s2.GamesWon += games2
+		s2.GamesLost += games1
+		s1.PointsWon += m.Team1PointsWon
+		s1.PointsLost += m.Team2PointsWon
+		s2.PointsWon += m.Team2PointsWon
+		s2.PointsLost += m.Team1PointsWon
+
+		r := result{entry1: p.Entry1, entry2: p.Entry2}
+
+		if m.Winner == parser.Team1 {
+			s1.Won++
+			s2.Lost++
+			r.winner = p.Entry1
+		} else if m.Winner == parser.Team2 {
+			s2.Won++
+			s1.Lost++
+			r.winner = p.Entry2
+		}
+
+		results = append(results, r)
+	}
+
+	sort.SliceStable(standings, func(i, j int) bool {
+		return standings[i].Won > standings[j].Won
+	})
+
+	breakTies(standings, results, func(s Standing) int { return s.Won }, []func(Standing) int{
+		Standing.gameDifference,
+		Standing.pointDifference,
+	})
+
+	for i := range standings {
+		standings[i].Rank = i + 1
+	}
+
+	return standings
+}
+
+// Returns the winner of the match between two entries, or 0 if they have not
+// played each other yet.
+func headToHead(results []result, entry1 int, entry2 int) int {
+	for _, r := range results {
+		if r.entry1 == entry1 && r.entry2 == entry2 || r.entry1 == entry2 && r.entry2 == entry1 {
+			return r.winner
+		}
+	}
+
+	return 0
+}
+
+// Breaks ties of standings that are sorted by the given key, using the
+// remaining criteria in order. Entries that are still tied keep their order.
+func breakTies(standings []Standing, results []result, key func(Standing) int, criteria []func(Standing) int) {
+	for start := 0; start < len(standings); {
+		end := start + 1
+
+		for end < len(standings) && key(standings[end]) == key(standings[start]) {
+			end++
+		}
+
+		tied := standings[start:end]
+		start = end
+
+		if len(tied) == 1 {
+			continue
+		}
+
+		if len(tied) == 2 {
+			if winner := headToHead(results, tied[0].Entry.ID, tied[1].Entry.ID); winner != 0 {
+				if winner == tied[1].Entry.ID {
+					tied[0], tied[1] = tied[1], tied[0]
+				}
+
+				continue
+			}
+		}
+
+		if len(criteria) == 0 {
+			continue
+		}
+
+		next := criteria[0]
+
+		sort.SliceStable(tied, func(i, j int) bool {
+			return next(tied[i]) > next(tied[j])
+		})
+
+		breakTies(tied, results, next, criteria[1:])
+	}
+}
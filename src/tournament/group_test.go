@@ -0,0 +1,195 @@
+package tournament
+
+import (
+	"fmt"
+	"math/rand"
+	"score/src/parser"
+	"testing"
+)
+
+// Returns a finished match with the given games and points won.
+func finished(games1 int, games2 int, points1 int, points2 int) parser.Match {
+	m := parser.Match{
+		Finished: true,
+		Winner:   parser.Team1,
+	}
+
+	m.Team1PointsWon = points1
+	m.Team2PointsWon = points2
+
+	if games2 > games1 {
+		m.Winner = parser.Team2
+	}
+
+	for i := 0; i < games1; i++ {
+		m.Games = append(m.Games, parser.Game{Winner: parser.Team1})
+	}
+
+	for i := 0; i < games2; i++ {
+		m.Games = append(m.Games, parser.Game{Winner: parser.Team2})
+	}
+
+	return m
+}
+
+func newGroup(t *testing.T, entries int) Draw {
+	draw, err := NewDraw("g", Group, MenSingles, parser.Mode21)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	for i := 1; i <= entries; i++ {
+		draw.AddEntry(team(fmt.Sprint(i)), 0)
+	}
+
+	if err := draw.Generate(rand.New(rand.NewSource(1))); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	return draw
+}
+
+// Links all fixtures and returns the matches by uuid, given the results by
+// the entries that played.
+func play(t *testing.T, draw *Draw, results map[[2]int]parser.Match) map[string]parser.Match {
+	matches := make(map[string]parser.Match)
+
+	for _, p := range draw.Positions {
+		uuid := fmt.Sprintf("%d-%d", p.Entry1, p.Entry2)
+		draw.Link(p.Round, p.Position, uuid)
+
+		if m, ok := results[[2]int{p.Entry1, p.Entry2}]; ok {
+			matches[uuid] = m
+		} else if m, ok := results[[2]int{p.Entry2, p.Entry1}]; ok {
+			// the result is given from the view of the other entry
+			m.Winner = 3 - m.Winner
+			m.Team1PointsWon, m.Team2PointsWon = m.Team2PointsWon, m.Team1PointsWon
+			m.Games = append([]parser.Game(nil), m.Games...)
+
+			for i := range m.Games {
+				m.Games[i].Winner = 3 - m.Games[i].Winner
+			}
+
+			matches[uuid] = m
+		}
+	}
+
+	return matches
+}
+
+func ranking(standings []Standing) []int {
+	var ids []int
+
+	for _, s := range standings {
+		ids = append(ids, s.Entry.ID)
+	}
+
+	return ids
+}
+
+func TestGenerateGroup(t *testing.T) {
+	draw := newGroup(t, 5)
+
+	if len(draw.Positions) != 10 || draw.Rounds() != 5 || draw.RoundName(0) != "Round 1" {
+		t.Fatalf("Got %d positions and %d rounds", len(draw.Positions), draw.Rounds())
+	}
+
+	pairs := make(map[[2]int]bool)
+
+	for round := 0; round < draw.Rounds(); round++ {
+		playing := make(map[int]bool)
+
+		for _, p := range draw.Round(round) {
+			if playing[p.Entry1] || playing[p.Entry2] {
+				t.Errorf("Entry plays twice in round %d", round)
+			}
+
+			playing[p.Entry1] = true
+			playing[p.Entry2] = true
+
+			pair := [2]int{p.Entry1, p.Entry2}
+			if p.Entry1 > p.Entry2 {
+				pair = [2]int{p.Entry2, p.Entry1}
+			}
+
+			pairs[pair] = true
+		}
+	}
+
+	if len(pairs) != 10 {
+		t.Errorf("Got %d pairings, want 10", len(pairs))
+	}
+}
+
+func TestStandings(t *testing.T) {
+	tests := []struct {
+		name    string
+		results map[[2]int]parser.Match
+		want    []int
+	}{
+		{
+			// 1 and 2 won two matches each, 1 won the match between them
+			"head-to-head",
+			map[[2]int]parser.Match{
+				{1, 2}: finished(2, 1, 42, 40),
+				{2, 3}: finished(2, 0, 42, 10),
+				{2, 4}: finished(2, 0, 42, 10),
+				{3, 1}: finished(2, 0, 42, 30),
+				{1, 4}: finished(2, 0, 42, 30),
+			},
+			[]int{1, 2, 3, 4},
+		},
+		{
+			// 1, 2 and 3 won two matches each and have a game difference
+			// of +2, so the point difference decides
+			"point difference",
+			map[[2]int]parser.Match{
+				{1, 2}: finished(2, 1, 60, 55),
+				{2, 3}: finished(2, 1, 60, 50),
+				{3, 1}: finished(2, 1, 70, 50),
+				{1, 4}: finished(2, 0, 42, 10),
+				{2, 4}: finished(2, 0, 42, 10),
+				{3, 4}: finished(2, 0, 42, 10),
+			},
+			[]int{3, 2, 1, 4},
+		},
+		{
+			// 1, 2 and 3 won two matches each, 3 has the best game
+			// difference and 1 won the match against 2
+			"game difference",
+			map[[2]int]parser.Match{
+				{1, 2}: finished(2, 1, 42, 40),
+				{2, 3}: finished(2, 1, 42, 40),
+				{3, 1}: finished(2, 0, 42, 10),
+				{1, 4}: finished(2, 0, 42, 10),
+				{2, 4}: finished(2, 1, 42, 40),
+				{3, 4}: finished(2, 0, 42, 10),
+			},
+			[]int{3, 1, 2, 4},
+		},
+	}
+
+	for _, test := range tests {
+		draw := newGroup(t, 4)
+		standings := draw.Standings(play(t, &draw, test.results))
+
+		if got := ranking(standings); fmt.Sprint(got) != fmt.Sprint(test.want) {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+
+	draw := newGroup(t, 4)
+	standings := draw.Standings(play(t, &draw, tests[0].results))
+
+	if s := standings[0]; s.Rank != 1 || s.Played != 3 || s.Won != 2 || s.Lost != 1 || s.GamesWon != 4 || s.GamesLost != 3 || s.PointsWon != 114 || s.PointsLost != 112 {
+		t.Errorf("Got %+v", s)
+	}
+
+	// unfinished matches are not counted
+	running := finished(1, 0, 21, 10)
+	running.Finished = false
+
+	if standings := draw.Standings(map[string]parser.Match{"1-2": running}); standings[0].Played != 0 {
+		t.Errorf("Got %+v, want no played matches", standings[0])
+	}
+}
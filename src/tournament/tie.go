@@ -0,0 +1,154 @@
+package tournament
+
+import (
+	"errors"
+	"score/src/parser"
+	"strings"
+)
+
+const (
+	ERR_INVALID_TIE     = "tie needs the names of both teams and at least one rubber"
+	ERR_INVALID_RUBBER  = "rubber does not exist"
+	ERR_RUBBER_STARTED  = "rubber has already been started"
+	ERR_RUBBER_UNLINKED = "rubber has no linked match"
+)
+
+// rubbers of a tie unless given otherwise, as in many inter-club leagues
+var DefaultRubbers = []Category{
+	MenDoubles, MenDoubles, WomenDoubles, MenSingles, MenSingles, WomenSingles, MixedDoubles, MixedDoubles,
+}
+
+// Rubber is one of the matches of a tie.
+type Rubber struct {
+	Category Category `json:"category"`
+	Match    string   `json:"match,omitempty"`
+}
+
+// Tie is a team competition between two clubs, made of several rubbers. The
+// team that wins the most rubbers wins the tie.
+type Tie struct {
+	ID      string      `json:"id"`
+	Team1   string      `json:"team1"`
+	Team2   string      `json:"team2"`
+	Mode    parser.Mode `json:"mode"`
+	Rubbers []Rubber    `json:"rubbers"`
+}
+
+// Creates a tie with a rubber of each of the given categories, in order.
+func NewTie(id string, team1 string, team2 string, mode parser.Mode, rubbers []Category) (Tie, error) {
+	team1 = strings.TrimSpace(team1)
+	team2 = strings.TrimSpace(team2)
+
+	if len(team1) == 0 || len(team2) == 0 || len(rubbers) == 0 {
+		return Tie{}, errors.New(ERR_INVALID_TIE)
+	}
+
+	if _, ok := parser.Rules(mode); !ok {
+		return Tie{}, errors.New(parser.ERR_INVALID_MODE)
+	}
+
+	tie := Tie{
+		ID:    id,
+		Team1: team1,
+		Team2: team2,
+		Mode:  mode,
+	}
+
+	for _, category := range rubbers {
+		if !category.IsValid() {
+			return Tie{}, errors.New(ERR_INVALID_CATEGORY)
+		}
+
+		tie.Rubbers = append(tie.Rubbers, Rubber{Category: category})
+	}
+
+	return tie, nil
+}
+
+// Returns the rubber with the given index.
+func (t Tie) Rubber(rubber int) (Rubber, error) {
+	if rubber < 0 || rubber >= len(t.Rubbers) {
+		return Rubber{}, errors.New(ERR_INVALID_RUBBER)
+	}
+
+	return t.Rubbers[rubber], nil
+}
+
+// Links a match to a rubber that has not been started yet.
+func (t *Tie) Link(rubber int, match string) error {
+	r, err := t.Rubber(rubber)
+	if err != nil {
+		return err
+	}
+
+	if r.Match != "" {
+		return errors.New(ERR_RUBBER_STARTED)
+	}
+
+	t.Rubbers[rubber].Match = match
+
+	return nil
+}
+
+// Removes the link of a rubber to its match and returns the uuid of the
+// match.
+func (t *Tie) Unlink(rubber int) (string, error) {
+	r, err := t.Rubber(rubber)
+	if err != nil {
+		return "", err
+	}
+
+	if r.Match == "" {
+		return "", errors.New(ERR_RUBBER_UNLINKED)
+	}
+
+	t.Rubbers[rubber].Match = ""
+
+	return r.Match, nil
+}
+
+// Returns the rubber of the match with the given uuid.
+func (t Tie) RubberOf(match string) (Rubber, bool) {
+	for _, r := range t.Rubbers {
+		if r.Match != "" && r.Match == match {
+			return r, true
+		}
+	}
+
+	return Rubber{}, false
+}
+
+// Returns the number of rubbers won by each team, given the linked matches
+// by uuid.
+func (t Tie) Score(matches map[string]parser.Match) (int, int) {
+	score1, score2 := 0, 0
+
+	for _, rubber := range t.Rubbers {
+		m, ok := matches[rubber.Match]
+		if rubber.Match == "" || !ok {
+			continue
+		}
+
+		if m.Winner == parser.Team1 {
+			score1++
+		} else if m.Winner == parser.Team2 {
+			score2++
+		}
+	}
+
+	return score1, score2
+}
+
+// Returns the team that has won more than half of the rubbers, or Unknown
+// if the tie is not decided yet.
+func (t Tie) Winner(matches map[string]parser.Match) parser.TeamID {
+	score1, score2 := t.Score(matches)
+
+	if 2*score1 > len(t.Rubbers) {
+		return parser.Team1
+	} else if 2*score2 > len(t.Rubbers) {
+		return parser.Team2
+	}
+
+	return parser.Unknown
+}
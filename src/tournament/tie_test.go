@@ -0,0 +1,62 @@
+package tournament
+
+import (
+	"score/src/parser"
+	"testing"
+)
+
+func TestTie(t *testing.T) {
+	if _, err := NewTie("t", "BC Berlin", "", parser.Mode21, DefaultRubbers); err == nil {
+		t.Error("Expected error for missing team")
+	}
+
+	if _, err := NewTie("t", "BC Berlin", "SG Hamburg", parser.Mode21, []Category{"XX"}); err == nil {
+		t.Error("Expected error for invalid category")
+	}
+
+	tie, err := NewTie("t", "BC Berlin", "SG Hamburg", parser.Mode21, []Category{MenSingles, WomenSingles, MixedDoubles})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	for i, uuid := range []string{"ms", "ws", "xd"} {
+		if err := tie.Link(i, uuid); err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+
+	if err := tie.Link(0, "other"); err == nil {
+		t.Error("Expected error for started rubber")
+	}
+
+	if err := tie.Link(3, "other"); err == nil {
+		t.Error("Expected error for unknown rubber")
+	}
+
+	if match, err := tie.Unlink(2); match != "xd" || err != nil || tie.Rubbers[2].Match != "" {
+		t.Errorf("Got %q, %v, want unlinked rubber", match, err)
+	}
+
+	if _, err := tie.Unlink(2); err == nil {
+		t.Error("Expected error for rubber without match")
+	}
+
+	tie.Link(2, "xd")
+
+	matches := map[string]parser.Match{
+		"ms": finished(2, 0, 42, 20),
+		// running matches have no winner yet
+		"ws": {},
+	}
+
+	if score1, score2 := tie.Score(matches); score1 != 1 || score2 != 0 || tie.Winner(matches) != parser.Unknown {
+		t.Errorf("Got %d:%d, want 1:0 and no winner", score1, score2)
+	}
+
+	matches["ws"] = finished(0, 2, 20, 42)
+	matches["xd"] = finished(1, 2, 50, 55)
+
+	if score1, score2 := tie.Score(matches); score1 != 1 || score2 != 2 || tie.Winner(matches) != parser.Team2 {
+		t.Errorf("Got %d:%d, want 1:2 and team 2 as winner", score1, score2)
+	}
+}
@@ -0,0 +1,442 @@
+package tournament
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"score/src/parser"
+)
+
+const (
+	ERR_INVALID_CATEGORY   = "category must be one of MS, WS, MD, WD or XD"
+	ERR_INVALID_FORMAT     = "format must be knockout or group"
+	ERR_INVALID_ENTRY      = "entry is invalid for this draw"
+	ERR_INVALID_SEED       = "seed is invalid or already taken"
+	ERR_DRAW_GENERATED     = "draw has already been generated"
+	ERR_DRAW_TOO_SMALL     = "draw needs at least two entries"
+	ERR_INVALID_POSITION   = "draw position does not exist"
+	ERR_POSITION_NOT_READY = "draw position is not ready to be played"
+	ERR_POSITION_LOCKED    = "next match of the draw has already started"
+	ERR_POSITION_UNLINKED  = "draw position has no linked match"
+)
+
+type Category string
+
+const (
+	MenSingles   Category = "MS"
+	WomenSingles Category = "WS"
+	MenDoubles   Category = "MD"
+	WomenDoubles Category = "WD"
+	MixedDoubles Category = "XD"
+)
+
+// Format is how the entries of a draw play each other.
+type Format string
+
+const (
+	// single elimination, the winner of a match advances to the next round
+	Knockout Format = "knockout"
+	// round robin, every entry plays every other entry once
+	Group Format = "group"
+)
+
+// Entry ids are numbered per draw, starting at 1. Position entries use
+// these special values besides entry ids.
+const (
+	// the entry is not known yet, e.g. the winner of an earlier match
+	Open = 0
+	// there is no entry, the opponent advances without playing
+	Bye = -1
+)
+
+// Entry is a team registered for a draw. Seeds start at 1, unseeded entries
+// have a seed of 0.
+type Entry struct {
+	ID   int         `json:"id"`
+	Team parser.Team `json:"team"`
+	Seed int         `json:"seed,omitempty"`
+}
+
+// Position is a match of a draw. Matches are numbered by round, starting at
+// 0 for the first round, and by their position within the round. In knockout
+// draws, the winner of a position advances to position/2 of the next round.
+type Position struct {
+	Round    int    `json:"round"`
+	Position int    `json:"position"`
+	Entry1   int    `json:"entry1"`
+	Entry2   int    `json:"entry2"`
+	Match    string `json:"match,omitempty"`
+	Winner   int    `json:"winner,omitempty"`
+}
+
+// Draw is the knockout draw or group of a category. Positions are empty
+// until the draw is generated, after which no entries can be added.
+type Draw struct {
+	ID        string      `json:"id"`
+	Format    Format      `json:"format"`
+	Category  Category    `json:"category"`
+	Mode      parser.Mode `json:"mode"`
+	Entries   []Entry     `json:"entries"`
+	Positions []Position  `json:"positions"`
+}
+
+func (c Category) IsValid() bool {
+	switch c {
+	case MenSingles, WomenSingles, MenDoubles, WomenDoubles, MixedDoubles:
+		return true
+	default:
+		return false
+	}
+}
+
+// Returns the number of players of a team in this category.
+func (c Category) Players() int {
+	if c == MenSingles || c == WomenSingles {
+		return 1
+	}
+
+	return 2
+}
+
+func (f Format) IsValid() bool {
+	return f == Knockout || f == Group
+}
+
+func NewDraw(id string, format Format, category Category, mode parser.Mode) (Draw, error) {
+	if !format.IsValid() {
+		return Draw{}, errors.New(ERR_INVALID_FORMAT)
+	}
+
+	if !category.IsValid() {
+		return Draw{}, errors.New(ERR_INVALID_CATEGORY)
+	}
+
+	if _, ok := parser.Rules(mode); !ok {
+		return Draw{}, errors.New(parser.ERR_INVALID_MODE)
+	}
+
+	return Draw{
+		ID:        id,
+		Format:    format,
+		Category:  category,
+		Mode:      mode,
+		Entries:   []Entry{},
+		Positions: []Position{},
+	}, nil
+}
+
+// Returns true if the draw has been generated.
+func (d Draw) Generated() bool {
+	return len(d.Positions) != 0
+}
+
+// Returns the entry with the given id.
+func (d Draw) Entry(id int) (Entry, bool) {
+	if id < 1 || id > len(d.Entries) {
+		return Entry{}, false
+	}
+
+	return d.Entries[id-1], true
+}
+
+// Registers a team. Seeds must be unique and must not exceed the number of
+// entries once the draw is generated.
+func (d *Draw) AddEntry(team parser.Team, seed int) (Entry, error) {
+	if d.Generated() {
+		return Entry{}, errors.New(ERR_DRAW_GENERATED)
+	}
+
+	if len(team) != d.Category.Players() || !team.IsValid() {
+		return Entry{}, errors.New(ERR_INVALID_ENTRY)
+	}
+
+	if seed < 0 {
+		return Entry{}, errors.New(ERR_INVALID_SEED)
+	}
+
+	for _, entry := range d.Entries {
+		if seed != 0 && entry.Seed == seed {
+			return Entry{}, errors.New(ERR_INVALID_SEED)
+		}
+	}
+
+	entry := Entry{
+		ID:   len(d.Entries) + 1,
+		Team: team,
+		Seed: seed,
+	}
+
+	d.Entries = append(d.Entries, entry)
+
+	return entry, nil
+}
+
+// Returns the number of rounds of the generated draw.
+func (d Draw) Rounds() int {
+	rounds := 0
+
+	if d.Format == Group {
+		for _, p := range d.Positions {
+			if p.Round >= rounds {
+				rounds = p.Round + 1
+			}
+		}
+
+		return rounds
+	}
+
+	for n := len(d.Positions) + 1; n > 1; n /= 2 {
+		rounds++
+	}
+
+	return rounds
+}
+
+// Returns the positions of a round.
+func (d Draw) Round(round int) []Position {
+	var positions []Position
+
+	for _, p := range d.Positions {
+		if p.Round == round {
+			positions = append(positions, p)
+		}
+	}
+
+	return positions
+}
+
+// Returns the name of a round, e.g. "Final" or "Round of 16".
+func (d Draw) RoundName(round int) string {
+	if d.Format == Group {
+		return fmt.Sprintf("Round %d", round+1)
+	}
+
+	switch d.Rounds() - round {
+	case 1:
+		return "Final"
+	case 2:
+		return "Semi-finals"
+	case 3:
+		return "Quarter-finals"
+	default:
+		return fmt.Sprintf("Round of %d", 1<<(d.Rounds()-round))
+	}
+}
+
+func (d *Draw) position(round int, position int) (*Position, error) {
+	for i := range d.Positions {
+		if d.Positions[i].Round == round && d.Positions[i].Position == position {
+			return &d.Positions[i], nil
+		}
+	}
+
+	return nil, errors.New(ERR_INVALID_POSITION)
+}
+
+// Returns the position of the match with the given uuid.
+func (d Draw) PositionOf(match string) (Position, bool) {
+	for _, p := range d.Positions {
+		if p.Match != "" && p.Match == match {
+			return p, true
+		}
+	}
+
+	return Position{}, false
+}
+
+// Returns the order of seeds in a draw of the given size, e.g. [1 4 2 3]
+// for 4 entries: seed 1 plays seed 4 and seed 2 plays seed 3. Top seeds can
+// only meet in late rounds.
+func seedOrder(size int) []int {
+	order := []int{1}
+
+	for len(order) < size {
+		n := 2 * len(order)
+		next := make([]int, 0, n)
+
+		for _, seed := range order {
+			next = append(next, seed, n+1-seed)
+		}
+
+		order = next
+	}
+
+	return order
+}
+
+// Generates the draw. The size of a knockout draw is the next power of two
+// of the number of entries. Seeded entries are placed by seed, byes are
+// given to the best seeds, and unseeded entries are drawn into the remaining
+// places. Groups are generated by generateGroup.
+func (d *Draw) Generate(rng *rand.Rand) error {
+	if d.Generated() {
+		return errors.New(ERR_DRAW_GENERATED)
+	}
+
+	if len(d.Entries) < 2 {
+		return errors.New(ERR_DRAW_TOO_SMALL)
+	}
+
+	if d.Format == Group {
+		d.generateGroup()
+		return nil
+	}
+
+	size := 2
+	for size < len(d.Entries) {
+		size *= 2
+	}
+
+	// entries by rank, seeds first in order of their seed
+	var seeded, unseeded []Entry
+
+	for _, entry := range d.Entries {
+		if entry.Seed != 0 {
+			seeded = append(seeded, entry)
+		} else {
+			unseeded = append(unseeded, entry)
+		}
+	}
+
+	for _, entry := range seeded {
+		if entry.Seed > len(d.Entries) {
+			return errors.New(ERR_INVALID_SEED)
+		}
+	}
+
+	sortBySeed(seeded)
+	rng.Shuffle(len(unseeded), func(i, j int) {
+		unseeded[i], unseeded[j] = unseeded[j], unseeded[i]
+	})
+
+	ranked := append(seeded, unseeded...)
+
+	// byes take the places of the lowest ranks, which face the best seeds
+	slots := make([]int, size)
+
+	for i, rank := range seedOrder(size) {
+		if rank <= len(ranked) {
+			slots[i] = ranked[rank-1].ID
+		} else {
+			slots[i] = Bye
+		}
+	}
+
+	d.Positions = []Position{}
+
+	for round, n := 0, size/2; n >= 1; round, n = round+1, n/2 {
+		for i := 0; i < n; i++ {
+			d.Positions = append(d.Positions, Position{Round: round, Position: i})
+		}
+	}
+
+	for i := 0; i < size/2; i++ {
+		p, _ := d.position(0, i)
+		p.Entry1 = slots[2*i]
+		p.Entry2 = slots[2*i+1]
+
+		if p.Entry1 == Bye {
+			d.advance(p, p.Entry2)
+		} else if p.Entry2 == Bye {
+			d.advance(p, p.Entry1)
+		}
+	}
+
+	return nil
+}
+
+func sortBySeed(entries []Entry) {
+	for i := 1; i < len(entries); i++ {
+		for j := i; j > 0 && entries[j].Seed < entries[j-1].Seed; j-- {
+			entries[j], entries[j-1] = entries[j-1], entries[j]
+		}
+	}
+}
+
+// Sets the winner of a position and moves it to the next round.
+func (d *Draw) advance(p *Position, winner int) {
+	p.Winner = winner
+
+	next, err := d.position(p.Round+1, p.Position/2)
+	if err != nil {
+		// the final has no next round
+		return
+	}
+
+	if p.Position%2 == 0 {
+		next.Entry1 = winner
+	} else {
+		next.Entry2 = winner
+	}
+}
+
+// Links a match to a position whose entries are known.
+func (d *Draw) Link(round int, position int, match string) error {
+	p, err := d.position(round, position)
+	if err != nil {
+		return err
+	}
+
+	if p.Entry1 <= 0 || p.Entry2 <= 0 || p.Winner != 0 || p.Match != "" {
+		return errors.New(ERR_POSITION_NOT_READY)
+	}
+
+	p.Match = match
+
+	return nil
+}
+
+// Removes the link of a position to its match, e.g. if the match was
+// started for the wrong position, and returns the uuid of the match.
+func (d *Draw) Unlink(round int, position int) (string, error) {
+	p, err := d.position(round, position)
+	if err != nil {
+		return "", err
+	}
+
+	if p.Match == "" {
+		return "", errors.New(ERR_POSITION_UNLINKED)
+	}
+
+	match := p.Match
+	p.Match = ""
+
+	return match, nil
+}
+
+// Sets the winner of the linked match and advances the winning entry in
+// knockout draws. The winner may still change, e.g. after an undo, as long
+// as the next match has not been linked yet. Returns false if the match is
+// not part of the draw.
+func (d *Draw) SetWinner(match string, winner parser.TeamID) (bool, error) {
+	current, ok := d.PositionOf(match)
+	if !ok {
+		return false, nil
+	}
+
+	p, _ := d.position(current.Round, current.Position)
+
+	entry := Open
+	if winner == parser.Team1 {
+		entry = p.Entry1
+	} else if winner == parser.Team2 {
+		entry = p.Entry2
+	}
+
+	if entry == p.Winner {
+		return true, nil
+	}
+
+	if d.Format == Group {
+		p.Winner = entry
+		return true, nil
+	}
+
+	if next, err := d.position(p.Round+1, p.Position/2); err == nil && (next.Match != "" || next.Winner != 0) {
+		return true, errors.New(ERR_POSITION_LOCKED)
+	}
+
+	d.advance(p, entry)
+
+	return true, nil
+}
@@ -0,0 +1,123 @@
+package tournament
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"score/src/parser"
+	"testing"
+)
+
+func team(name string) parser.Team {
+	return parser.Team{{Country: "DK", Player: parser.PlayerName(name)}}
+}
+
+func TestSeedOrder(t *testing.T) {
+	if order := seedOrder(8); !reflect.DeepEqual(order, []int{1, 8, 4, 5, 2, 7, 3, 6}) {
+		t.Errorf("Got %v", order)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	draw, err := NewDraw("d", Knockout, MenSingles, parser.Mode21)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+
+	for i := 1; i <= 5; i++ {
+		seed := 0
+		if i <= 2 {
+			seed = 3 - i
+		}
+
+		if _, err := draw.AddEntry(team(fmt.Sprint(i)), seed); err != nil {
+			t.Fatal(err.Error())
+		}
+	}
+
+	if _, err := draw.AddEntry(team("6"), 1); err == nil {
+		t.Error("Expected error for duplicate seed")
+	}
+
+	if _, err := draw.AddEntry(parser.Team{}, 0); err == nil {
+		t.Error("Expected error for invalid team")
+	}
+
+	if err := draw.Generate(rand.New(rand.NewSource(1))); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if len(draw.Positions) != 7 || draw.Rounds() != 3 || draw.RoundName(0) != "Quarter-finals" {
+		t.Fatalf("Got %d positions and %d rounds", len(draw.Positions), draw.Rounds())
+	}
+
+	// entry 2 is seeded first and gets a bye
+	first := draw.Round(0)[0]
+	if first.Entry1 != 2 || first.Entry2 != Bye || first.Winner != 2 {
+		t.Errorf("Got %+v, want bye for seed 1", first)
+	}
+
+	// entry 1 is seeded second and placed in the other half
+	if third := draw.Round(0)[2]; third.Entry1 != 1 || third.Winner != 1 {
+		t.Errorf("Got %+v, want bye for seed 2", third)
+	}
+
+	// the only first round match is played by the two unseeded entries
+	// without bye
+	played := draw.Round(0)[1]
+	if played.Entry1 <= 2 || played.Entry2 <= 2 || played.Winner != 0 {
+		t.Errorf("Got %+v, want match of unseeded entries", played)
+	}
+
+	if err := draw.Link(1, 0, "semi"); err == nil {
+		t.Error("Expected error for position that is not ready")
+	}
+
+	if err := draw.Link(0, 1, "wrong"); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if match, err := draw.Unlink(0, 1); match != "wrong" || err != nil {
+		t.Fatalf("Got %q, %v, want unlinked match", match, err)
+	}
+
+	if _, err := draw.Unlink(0, 1); err == nil {
+		t.Error("Expected error for position without match")
+	}
+
+	if err := draw.Link(0, 1, "match"); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if ok, err := draw.SetWinner("match", parser.Team2); !ok || err != nil {
+		t.Fatalf("Got %v, %v", ok, err)
+	}
+
+	semi := draw.Round(1)[0]
+	if semi.Entry1 != 2 || semi.Entry2 != played.Entry2 {
+		t.Errorf("Got %+v, want winner in semi-final", semi)
+	}
+
+	// the winner may change until the next match is linked
+	draw.SetWinner("match", parser.Team1)
+
+	if err := draw.Link(1, 0, "semi"); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	if draw.Round(1)[0].Entry2 != played.Entry1 {
+		t.Errorf("Got %+v, want changed winner", draw.Round(1)[0])
+	}
+
+	if _, err := draw.SetWinner("match", parser.Team2); err == nil {
+		t.Error("Expected error for locked next match")
+	}
+
+	if ok, _ := draw.SetWinner("unknown", parser.Team1); ok {
+		t.Error("Expected unknown match")
+	}
+
+	if _, err := draw.AddEntry(team("7"), 0); err == nil {
+		t.Error("Expected error for generated draw")
+	}
+}
@@ -0,0 +1,69 @@
+// Keeps live pages up to date. Pages are only rendered by the server, so
+// changed parts of a page are fetched again and replaced.
+
+// Calls onMatch with every match published on the given event stream, e.g.
+// /api/events or /api/matches/{uuid}/events. The stream of all matches also
+// publishes links of matches to draws, ties and scheduled matches, which
+// onLink is called with if it is given.
+const subscribe = (path, onMatch, onLink) => {
+  const source = new EventSource(window.location.origin + path);
+
+  source.addEventListener("match", (e) => {
+    onMatch(JSON.parse(e.data));
+  });
+
+  if (onLink) {
+    source.addEventListener("link", (e) => {
+      onLink(JSON.parse(e.data));
+    });
+  }
+
+  return source;
+};
+
+// ids of elements that are being fetched, and the arguments of updates of
+// these elements that were requested meanwhile
+const fetching = new Set();
+const pending = new Map();
+
+// Fetches the element with the given id from the given url, which may return
+// a whole page or only the element, and replaces the element with the same id
+// on this page. If this page does not contain the element yet, insert is
+// called with it. An element is removed if the url does not return it.
+//
+// An element is never fetched twice at the same time. If it changes while it
+// is fetched, it is fetched once more afterwards.
+const update = (id, url, insert) => {
+  if (fetching.has(id)) {
+    pending.set(id, [url, insert]);
+    return;
+  }
+
+  fetching.add(id);
+
+  fetch(url)
+    .then(res => res.ok ? res.text() : Promise.reject(res.status))
+    .then(html => {
+      const updated = new DOMParser().parseFromString(html, "text/html").getElementById(id);
+      const current = document.getElementById(id);
+
+      if (updated === null) {
+        current?.remove();
+      } else if (current !== null) {
+        current.replaceWith(updated);
+      } else if (insert) {
+        insert(updated);
+      }
+    })
+    .catch(() => {})
+    .finally(() => {
+      fetching.delete(id);
+
+      if (pending.has(id)) {
+        const [url, insert] = pending.get(id);
+        pending.delete(id);
+
+        update(id, url, insert);
+      }
+    });
+};
@@ -0,0 +1,429 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"score/src/parser"
+	"score/src/store"
+	"score/src/tournament"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	PATH_API_TIES = "/api/ties"
+	PATH_TIE      = "/tie/"
+
+	ERR_PLAYERS_MISMATCH = "number of players does not match the category of the rubber"
+)
+
+var (
+	ties store.TieStore
+	// ties are read, changed and written as a whole
+	tieMutex sync.Mutex
+)
+
+// Tie as returned by the API, together with its score.
+type APITie struct {
+	tournament.Tie
+	Created int64         `json:"created"`
+	Score1  int           `json:"score1"`
+	Score2  int           `json:"score2"`
+	Winner  parser.TeamID `json:"winner"`
+}
+
+// TieLink links a new match to a rubber of a tie.
+type TieLink struct {
+	Tie    string `json:"tie"`
+	Rubber int    `json:"rubber"`
+}
+
+// Rubber of a tie as shown on the tie page.
+type TieRubber struct {
+	tournament.Rubber
+	// index of the rubber within the tie
+	Index int
+	// false until the first update of the linked match
+	Started bool
+	Live    parser.Match
+	// client that scores the rubber, empty once it has been started
+	ClientURL string
+}
+
+type TiePage struct {
+	APITie
+	Rubbers []TieRubber
+}
+
+func loadTie(id string) (tournament.Tie, store.TieRecord, error) {
+	var tie tournament.Tie
+
+	record, err := ties.GetTie(id)
+	if err != nil {
+		return tie, record, err
+	}
+
+	if err := json.Unmarshal([]byte(record.JSON), &tie); err != nil {
+		return tie, record, err
+	}
+
+	tie.ID = record.ID
+
+	return tie, record, nil
+}
+
+func saveTie(tie tournament.Tie) error {
+	data, err := json.Marshal(tie)
+	if err != nil {
+		return err
+	}
+
+	return ties.UpdateTie(tie.ID, string(data))
+}
+
+// Returns the linked matches of a tie that have been started, by uuid.
+func getTieMatches(tie tournament.Tie) map[string]parser.Match {
+	var uuids []string
+
+	for _, rubber := range tie.Rubbers {
+		uuids = append(uuids, rubber.Match)
+	}
+
+	return getStartedMatches(uuids)
+}
+
+func newAPITie(tie tournament.Tie, record store.TieRecord, linked map[string]parser.Match) APITie {
+	score1, score2 := tie.Score(linked)
+
+	return APITie{
+		Tie:     tie,
+		Created: record.Created.Unix(),
+		Score1:  score1,
+		Score2:  score2,
+		Winner:  tie.Winner(linked),
+	}
+}
+
+func getTie(id string) (APITie, error) {
+	tie, record, err := loadTie(id)
+	if err != nil {
+		return APITie{}, err
+	}
+
+	return newAPITie(tie, record, getTieMatches(tie)), nil
+}
+
+// Creates a match for a rubber of a tie, see createMatch.
+func createTieMatch(token string, link TieLink) (string, error) {
+	tieMutex.Lock()
+	defer tieMutex.Unlock()
+
+	tie, _, err := loadTie(link.Tie)
+	if err != nil {
+		return "", err
+	}
+
+	uuid, err := createMatch(token)
+	if err != nil {
+		return "", err
+	}
+
+	// the match is linked to the tie before the tie is saved, so that a
+	// rubber is never scored by a match that does not know its tie
+	if err := tie.Link(link.Rubber, uuid); err != nil {
+		matches.Delete(uuid)
+		return "", err
+	}
+
+	if err := ties.LinkRubber(uuid, tie.ID); err != nil {
+		matches.Delete(uuid)
+		return "", err
+	}
+
+	if err := saveTie(tie); err != nil {
+		matches.Delete(uuid)
+		return "", err
+	}
+
+	publishLink(APILink{Match: uuid, Tie: tie.ID, Linked: true})
+
+	return uuid, nil
+}
+
+// Unlinks the match of a rubber that has not been started, see unlinkMatch.
+func unlinkTieMatch(link TieLink) (APITie, error) {
+	drawMutex.Lock()
+	defer drawMutex.Unlock()
+
+	tieMutex.Lock()
+	defer tieMutex.Unlock()
+
+	tie, _, err := loadTie(link.Tie)
+	if err != nil {
+		return APITie{}, err
+	}
+
+	uuid, err := tie.Unlink(link.Rubber)
+	if err != nil {
+		return APITie{}, err
+	}
+
+	if err := unlinkMatch(uuid, func() error { return saveTie(tie) }); err != nil {
+		return APITie{}, err
+	}
+
+	publishLink(APILink{Match: uuid, Tie: tie.ID})
+
+	return getTie(tie.ID)
+}
+
+// Checks that a match linked to a rubber is played by as many players as the
+// category of the rubber needs. Returns nil if the match is not linked.
+func checkRubber(uuid string, m parser.Match) error {
+	tieID, err := ties.TieOf(uuid)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	tie, _, err := loadTie(tieID)
+	if err != nil {
+		return err
+	}
+
+	rubber, ok := tie.RubberOf(uuid)
+	if !ok {
+		return nil
+	}
+
+	players := rubber.Category.Players()
+
+	if len(m.Info.Team1) != players || len(m.Info.Team2) != players {
+		return errors.New(ERR_PLAYERS_MISMATCH)
+	}
+
+	return nil
+}
+
+func getTiePage(id string) (TiePage, error) {
+	tie, record, err := loadTie(id)
+	if err != nil {
+		return TiePage{}, err
+	}
+
+	linked := getTieMatches(tie)
+
+	page := TiePage{
+		APITie: newAPITie(tie, record, linked),
+	}
+
+	for i, rubber := range tie.Rubbers {
+		r := TieRubber{
+			Rubber: rubber,
+			Index:  i,
+		}
+
+		if m, ok := linked[rubber.Match]; ok {
+			r.Started = true
+			r.Live = m
+		}
+
+		if rubber.Match == "" {
+			query := url.Values{}
+			query.Set("tie", tie.ID)
+			query.Set("rubber", strconv.Itoa(i))
+
+			r.ClientURL = PATH_CLIENT + "?" + query.Encode()
+		}
+
+		page.Rubbers = append(page.Rubbers, r)
+	}
+
+	return page, nil
+}
+
+// Prepares the client for a rubber of a tie, e.g. /c/?tie={id}&rubber=0
+func prefillTieClient(data *ClientData, query url.Values) error {
+	index, err := strconv.Atoi(query.Get("rubber"))
+	if err != nil {
+		return errors.New(tournament.ERR_INVALID_RUBBER)
+	}
+
+	tie, _, err := loadTie(query.Get("tie"))
+	if err != nil {
+		return err
+	}
+
+	rubber, err := tie.Rubber(index)
+	if err != nil {
+		return err
+	}
+
+	data.Tie = &TieLink{
+		Tie:    tie.ID,
+		Rubber: index,
+	}
+	data.Doubles = rubber.Category.Players() == 2
+	data.DefaultMode = tie.Mode
+	data.Club1 = tie.Team1
+	data.Club2 = tie.Team2
+
+	return nil
+}
+
+// Handles the tie API:
+//
+//	GET  /api/ties
+//	POST /api/ties
+//	GET  /api/ties/{id}
+//	POST /api/ties/{id}/unlink
+func handleTiesAPI(w http.ResponseWriter, r *http.Request) {
+	var path []string
+	if rest := strings.Trim(strings.TrimPrefix(r.URL.Path, PATH_API_TIES), "/"); rest != "" {
+		path = strings.Split(rest, "/")
+	}
+
+	if len(path) > 2 || len(path) == 2 && path[1] != "unlink" {
+		writeProblem(w, http.StatusNotFound, nil)
+		return
+	}
+
+	// ties are created and unlinked by POST, everything else is read-only
+	method := http.MethodGet
+	if len(path) == 2 || len(path) == 0 && r.Method == http.MethodPost {
+		method = http.MethodPost
+	}
+
+	if r.Method != method {
+		writeProblem(w, http.StatusMethodNotAllowed, nil)
+		return
+	}
+
+	if !authorizeWrite(w, r) {
+		return
+	}
+
+	if len(path) == 2 {
+		var link TieLink
+
+		if err := readJSON(r, &link); err != nil {
+			writeProblem(w, http.StatusBadRequest, err)
+			return
+		}
+
+		link.Tie = path[0]
+
+		tie, err := unlinkTieMatch(link)
+		if errors.Is(err, store.ErrNotFound) {
+			writeProblem(w, http.StatusNotFound, err)
+			return
+		} else if err != nil {
+			writeProblem(w, http.StatusBadRequest, err)
+			return
+		}
+
+		writeJSON(w, tie)
+		return
+	}
+
+	if len(path) == 1 {
+		tie, err := getTie(path[0])
+		if err != nil {
+			writeStoreProblem(w, err)
+			return
+		}
+
+		writeJSON(w, tie)
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		var data struct {
+			Team1   string                `json:"team1"`
+			Team2   string                `json:"team2"`
+			Mode    parser.Mode           `json:"mode"`
+			Rubbers []tournament.Category `json:"rubbers"`
+		}
+
+		if err := readJSON(r, &data); err != nil {
+			writeProblem(w, http.StatusBadRequest, err)
+			return
+		}
+
+		if data.Rubbers == nil {
+			data.Rubbers = tournament.DefaultRubbers
+		}
+
+		tie, err := tournament.NewTie("", data.Team1, data.Team2, data.Mode, data.Rubbers)
+		if err != nil {
+			writeProblem(w, http.StatusBadRequest, err)
+			return
+		}
+
+		raw, _ := json.Marshal(tie)
+
+		id, err := ties.CreateTie(string(raw))
+		if err != nil {
+			writeProblem(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		created, err := getTie(id)
+		if err != nil {
+			writeStoreProblem(w, err)
+			return
+		}
+
+		writeCreated(w, created)
+		return
+	}
+
+	records, err := ties.ListTies()
+	if err != nil {
+		writeProblem(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	list := []APITie{}
+
+	for _, record := range records {
+		tie, err := getTie(record.ID)
+		if err != nil {
+			writeProblem(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		list = append(list, tie)
+	}
+
+	writeJSON(w, list)
+}
+
+func handleTie(w http.ResponseWriter, r *http.Request) {
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, PATH_TIE), "/")
+
+	t, ok := templates["tie.html"]
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	page, err := getTiePage(id)
+	if errors.Is(err, store.ErrNotFound) {
+		http.NotFound(w, r)
+		return
+	} else if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	if err := t.Execute(w, page); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestTie(t *testing.T) {
+	setup(t)
+
+	token := strings.Repeat("a", TOKEN_LENGTH)
+
+	var tie APITie
+	if code := jsonRequest(t, handleTiesAPI, http.MethodPost, PATH_API_TIES, `{"team1": "BC Berlin", "team2": "SG Hamburg", "mode": 21, "rubbers": ["MS", "WS", "XD"]}`, &tie); code != http.StatusCreated {
+		t.Fatalf("Got status %d, want %d", code, http.StatusCreated)
+	}
+
+	if code := jsonRequest(t, handleTiesAPI, http.MethodPost, PATH_API_TIES, `{"team1": "BC Berlin", "mode": 21}`, &tie); code != http.StatusBadRequest {
+		t.Errorf("Got status %d, want %d", code, http.StatusBadRequest)
+	}
+
+	w := apiRequest(token, `{"action": "new", "tie": {"tie": "`+tie.ID+`", "rubber": 0}}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Got status %d, want %d: %s", w.Code, http.StatusCreated, w.Body.String())
+	}
+
+	var response APIResponseData
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// a rubber is only played once
+	if w := apiRequest(token, `{"action": "new", "tie": {"tie": "`+tie.ID+`", "rubber": 0}}`); w.Code != http.StatusBadRequest {
+		t.Errorf("Got status %d, want %d", w.Code, http.StatusBadRequest)
+	}
+
+	w = apiRequest(token, `{"action": "update", "match": "`+response.Match+`", "data": `+apiTestMatch("Viktor AXELSEN", "CHOU Tien Chen", 1679684400, true)+`}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Got status %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+
+	if code := jsonRequest(t, handleTiesAPI, http.MethodPost, PATH_API_TIES+"/"+tie.ID+"/unlink", `{"rubber": 0}`, &tie); code != http.StatusBadRequest {
+		t.Errorf("Got status %d, want %d for started rubber", code, http.StatusBadRequest)
+	}
+
+	// a rubber that has not been started can be scored by another match
+	w = apiRequest(token, `{"action": "new", "tie": {"tie": "`+tie.ID+`", "rubber": 1}}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Got status %d, want %d: %s", w.Code, http.StatusCreated, w.Body.String())
+	}
+
+	if code := jsonRequest(t, handleTiesAPI, http.MethodPost, PATH_API_TIES+"/"+tie.ID+"/unlink", `{"rubber": 1}`, &tie); code != http.StatusOK || tie.Rubbers[1].Match != "" {
+		t.Errorf("Got status %d and %+v, want unlinked rubber", code, tie.Rubbers[1])
+	}
+
+	if code := jsonRequest(t, handleTiesAPI, http.MethodGet, PATH_API_TIES+"/"+tie.ID, "", &tie); code != http.StatusOK || tie.Score1 != 1 || tie.Score2 != 0 || tie.Rubbers[0].Match != response.Match {
+		t.Errorf("Got status %d and %+v, want score 1:0", code, tie)
+	}
+
+	var list []APITie
+	if code := jsonRequest(t, handleTiesAPI, http.MethodGet, PATH_API_TIES, "", &list); code != http.StatusOK || len(list) != 1 {
+		t.Errorf("Got status %d and %d ties, want 1", code, len(list))
+	}
+
+	w = httptest.NewRecorder()
+	handleTie(w, httptest.NewRequest(http.MethodGet, PATH_TIE+tie.ID, nil))
+
+	for _, want := range []string{"BC Berlin", "Viktor AXELSEN", "/m/" + response.Match, "/c/?rubber=2&amp;tie=" + tie.ID} {
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("Expected %q on tie page", want)
+		}
+	}
+
+	w = httptest.NewRecorder()
+	handleClient(w, httptest.NewRequest(http.MethodGet, PATH_CLIENT+"?tie="+tie.ID+"&rubber=2", nil))
+
+	if w.Code != http.StatusOK || !regexp.MustCompile(`const DOUBLES = \s*true`).MatchString(w.Body.String()) {
+		t.Errorf("Got status %d, want client for doubles", w.Code)
+	}
+
+	// the scorer must know which side plays for which club
+	for _, want := range []string{`<td class="club">BC Berlin</td>`, `<td class="club">SG Hamburg</td>`} {
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("Expected %q in client", want)
+		}
+	}
+
+	// a doubles rubber is not played as singles
+	w = apiRequest(token, `{"action": "new", "tie": {"tie": "`+tie.ID+`", "rubber": 2}}`)
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	w = apiRequest(token, `{"action": "update", "match": "`+response.Match+`", "data": `+apiTestMatch("Viktor AXELSEN", "CHOU Tien Chen", 1679684400, false)+`}`)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Got status %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,657 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"score/src/parser"
+	"score/src/store"
+	"score/src/tournament"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	PATH_API_TOURNAMENTS = "/api/tournaments"
+	PATH_TOURNAMENT      = "/t/"
+
+	ERR_TEAMS_MISMATCH = "teams do not match the entries of the draw position"
+)
+
+var (
+	tournaments store.TournamentStore
+	// draws are read, changed and written as a whole
+	drawMutex sync.Mutex
+)
+
+// Tournament as returned by the API. Draws are only included if a single
+// tournament is requested.
+type APITournament struct {
+	ID      string            `json:"id"`
+	Name    string            `json:"name"`
+	Created int64             `json:"created"`
+	Draws   []tournament.Draw `json:"draws,omitempty"`
+}
+
+// DrawLink links a new match to a position of a draw.
+type DrawLink struct {
+	Draw     string `json:"draw"`
+	Round    int    `json:"round"`
+	Position int    `json:"position"`
+}
+
+// Position of a draw as shown on the bracket page.
+type BracketMatch struct {
+	tournament.Position
+	// nil if the entry is not known yet or is a bye
+	Team1 parser.Team
+	Team2 parser.Team
+	// false until the first update of the linked match
+	Started bool
+	Live    parser.Match
+	// true if the match can be scored, i.e. both entries are known
+	Ready bool
+	// client that scores the match
+	ClientURL string
+}
+
+type BracketRound struct {
+	Name    string
+	Matches []BracketMatch
+}
+
+type BracketDraw struct {
+	tournament.Draw
+	Rounds []BracketRound
+	// nil unless the draw is a group
+	Standings []tournament.Standing
+}
+
+type TournamentPage struct {
+	ID    string
+	Name  string
+	Draws []BracketDraw
+}
+
+func newAPITournament(record store.TournamentRecord) APITournament {
+	return APITournament{
+		ID:      record.ID,
+		Name:    record.Name,
+		Created: record.Created.Unix(),
+	}
+}
+
+func loadDraw(id string) (tournament.Draw, store.DrawRecord, error) {
+	var draw tournament.Draw
+
+	record, err := tournaments.GetDraw(id)
+	if err != nil {
+		return draw, record, err
+	}
+
+	if err := json.Unmarshal([]byte(record.JSON), &draw); err != nil {
+		return draw, record, err
+	}
+
+	draw.ID = record.ID
+
+	return draw, record, nil
+}
+
+func saveDraw(draw tournament.Draw) error {
+	data, err := json.Marshal(draw)
+	if err != nil {
+		return err
+	}
+
+	return tournaments.UpdateDraw(draw.ID, string(data))
+}
+
+func getTournament(id string) (APITournament, error) {
+	record, err := tournaments.GetTournament(id)
+	if err != nil {
+		return APITournament{}, err
+	}
+
+	records, err := tournaments.ListDraws(id)
+	if err != nil {
+		return APITournament{}, err
+	}
+
+	t := newAPITournament(record)
+	t.Draws = []tournament.Draw{}
+
+	for _, record := range records {
+		draw, _, err := loadDraw(record.ID)
+		if err != nil {
+			return t, err
+		}
+
+		t.Draws = append(t.Draws, draw)
+	}
+
+	return t, nil
+}
+
+// Returns the draw of a tournament, or ErrNotFound if the draw belongs to
+// another tournament.
+func getTournamentDraw(tournamentID string, id string) (tournament.Draw, error) {
+	draw, record, err := loadDraw(id)
+	if err != nil {
+		return draw, err
+	}
+
+	if record.TournamentID != tournamentID {
+		return draw, store.ErrNotFound
+	}
+
+	return draw, nil
+}
+
+// Creates a match for a position of a draw, see createMatch.
+func createDrawMatch(token string, link DrawLink) (string, error) {
+	drawMutex.Lock()
+	defer drawMutex.Unlock()
+
+	draw, _, err := loadDraw(link.Draw)
+	if err != nil {
+		return "", err
+	}
+
+	uuid, err := createMatch(token)
+	if err != nil {
+		return "", err
+	}
+
+	if err := draw.Link(link.Round, link.Position, uuid); err != nil {
+		matches.Delete(uuid)
+		return "", err
+	}
+
+	if err := tournaments.LinkMatch(uuid, draw.ID); err != nil {
+		return "", err
+	}
+
+	if err := saveDraw(draw); err != nil {
+		return "", err
+	}
+
+	publishLink(APILink{Match: uuid, Draw: draw.ID, Linked: true})
+
+	return uuid, nil
+}
+
+// Unlinks the match of a position of a draw that has not been started, see
+// unlinkMatch.
+func unlinkDrawMatch(tournamentID string, link DrawLink) (tournament.Draw, error) {
+	drawMutex.Lock()
+	defer drawMutex.Unlock()
+
+	draw, err := getTournamentDraw(tournamentID, link.Draw)
+	if err != nil {
+		return draw, err
+	}
+
+	uuid, err := draw.Unlink(link.Round, link.Position)
+	if err != nil {
+		return draw, err
+	}
+
+	if err := unlinkMatch(uuid, func() error { return saveDraw(draw) }); err != nil {
+		return draw, err
+	}
+
+	publishLink(APILink{Match: uuid, Draw: draw.ID})
+
+	return draw, nil
+}
+
+func sameTeam(a parser.Team, b parser.Team) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i].Player != b[i].Player {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Checks that a match linked to a draw is played by the entries of its
+// position and advances the winner. Returns nil if the match is not linked.
+func advanceDraw(uuid string, m parser.Match) (*tournament.Draw, error) {
+	drawID, err := tournaments.DrawOf(uuid)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	draw, _, err := loadDraw(drawID)
+	if err != nil {
+		return nil, err
+	}
+
+	p, ok := draw.PositionOf(uuid)
+	if !ok {
+		return nil, nil
+	}
+
+	entry1, _ := draw.Entry(p.Entry1)
+	entry2, _ := draw.Entry(p.Entry2)
+
+	if !sameTeam(m.Info.Team1, entry1.Team) || !sameTeam(m.Info.Team2, entry2.Team) {
+		return nil, errors.New(ERR_TEAMS_MISMATCH)
+	}
+
+	if _, err := draw.SetWinner(uuid, m.Winner); err != nil {
+		return nil, err
+	}
+
+	return &draw, nil
+}
+
+// Returns the linked matches of a draw that have been started, by uuid.
+func getDrawMatches(draw tournament.Draw) map[string]parser.Match {
+	var uuids []string
+
+	for _, p := range draw.Positions {
+		uuids = append(uuids, p.Match)
+	}
+
+	return getStartedMatches(uuids)
+}
+
+// Returns the matches with the given uuids that have been started, by uuid.
+// Empty uuids are skipped.
+func getStartedMatches(uuids []string) map[string]parser.Match {
+	started := make(map[string]parser.Match)
+
+	for _, uuid := range uuids {
+		if uuid == "" {
+			continue
+		}
+
+		record, err := matches.Get(uuid)
+		if err != nil || len(record.JSON) == 0 {
+			continue
+		}
+
+		if m, err := parser.Parse(record.JSON); err == nil {
+			started[uuid] = m
+		}
+	}
+
+	return started
+}
+
+func getTournamentPage(id string) (TournamentPage, error) {
+	t, err := getTournament(id)
+	if err != nil {
+		return TournamentPage{}, err
+	}
+
+	page := TournamentPage{
+		ID:   t.ID,
+		Name: t.Name,
+	}
+
+	for _, draw := range t.Draws {
+		linked := getDrawMatches(draw)
+		bracket := BracketDraw{Draw: draw}
+
+		if draw.Format == tournament.Group && draw.Generated() {
+			bracket.Standings = draw.Standings(linked)
+		}
+
+		for round := 0; round < draw.Rounds(); round++ {
+			r := BracketRound{Name: draw.RoundName(round)}
+
+			for _, p := range draw.Round(round) {
+				match := BracketMatch{
+					Position: p,
+					Ready:    p.Entry1 > 0 && p.Entry2 > 0 && p.Winner == 0 && p.Match == "",
+				}
+
+				if match.Ready {
+					match.ClientURL = clientURL(draw.ID, p)
+				}
+
+				if entry, ok := draw.Entry(p.Entry1); ok {
+					match.Team1 = entry.Team
+				}
+
+				if entry, ok := draw.Entry(p.Entry2); ok {
+					match.Team2 = entry.Team
+				}
+
+				if m, ok := linked[p.Match]; ok {
+					match.Started = true
+					match.Live = m
+				}
+
+				r.Matches = append(r.Matches, match)
+			}
+
+			bracket.Rounds = append(bracket.Rounds, r)
+		}
+
+		page.Draws = append(page.Draws, bracket)
+	}
+
+	return page, nil
+}
+
+// Returns the URL of the client that scores the match of a draw position.
+func clientURL(draw string, p tournament.Position) string {
+	query := url.Values{}
+	query.Set("draw", draw)
+	query.Set("round", strconv.Itoa(p.Round))
+	query.Set("position", strconv.Itoa(p.Position))
+
+	return PATH_CLIENT + "?" + query.Encode()
+}
+
+// Fills in the teams of a draw position for the client, e.g.
+// /c/?draw={id}&round=0&position=1
+func prefillDrawClient(data *ClientData, query url.Values) error {
+	round, err := strconv.Atoi(query.Get("round"))
+	if err != nil {
+		return errors.New(tournament.ERR_INVALID_POSITION)
+	}
+
+	position, err := strconv.Atoi(query.Get("position"))
+	if err != nil {
+		return errors.New(tournament.ERR_INVALID_POSITION)
+	}
+
+	draw, _, err := loadDraw(query.Get("draw"))
+	if err != nil {
+		return err
+	}
+
+	for _, p := range draw.Round(round) {
+		if p.Position != position {
+			continue
+		}
+
+		entry1, ok1 := draw.Entry(p.Entry1)
+		entry2, ok2 := draw.Entry(p.Entry2)
+
+		if !ok1 || !ok2 {
+			return errors.New(tournament.ERR_POSITION_NOT_READY)
+		}
+
+		data.Draw = &DrawLink{
+			Draw:     draw.ID,
+			Round:    round,
+			Position: position,
+		}
+		data.Team1 = entry1.Team
+		data.Team2 = entry2.Team
+		data.Doubles = draw.Category.Players() == 2
+		data.DefaultMode = draw.Mode
+
+		return nil
+	}
+
+	return errors.New(tournament.ERR_INVALID_POSITION)
+}
+
+func readJSON(r *http.Request, v any) error {
+	return json.NewDecoder(r.Body).Decode(v)
+}
+
+func writeCreated(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeStoreProblem(w http.ResponseWriter, err error) {
+	if errors.Is(err, store.ErrNotFound) {
+		writeProblem(w, http.StatusNotFound, err)
+	} else {
+		writeProblem(w, http.StatusInternalServerError, err)
+	}
+}
+
+// Handles the tournament API:
+//
+//	GET  /api/tournaments
+//	POST /api/tournaments
+//	GET  /api/tournaments/{id}
+//	POST /api/tournaments/{id}/draws
+//	GET  /api/tournaments/{id}/draws/{draw}
+//	GET  /api/tournaments/{id}/draws/{draw}/standings
+//	POST /api/tournaments/{id}/draws/{draw}/entries
+//	POST /api/tournaments/{id}/draws/{draw}/generate
+//	POST /api/tournaments/{id}/draws/{draw}/unlink
+func handleTournamentsAPI(w http.ResponseWriter, r *http.Request) {
+	var path []string
+	if rest := strings.Trim(strings.TrimPrefix(r.URL.Path, PATH_API_TOURNAMENTS), "/"); rest != "" {
+		path = strings.Split(rest, "/")
+	}
+
+	switch {
+	case len(path) <= 1:
+	case len(path) <= 3 && path[1] == "draws":
+	case len(path) == 4 && path[1] == "draws" && (path[3] == "entries" || path[3] == "generate" || path[3] == "unlink" || path[3] == "standings"):
+	default:
+		writeProblem(w, http.StatusNotFound, nil)
+		return
+	}
+
+	// resources are created by POST, everything else is read-only
+	method := http.MethodGet
+	if len(path) == 2 || len(path) == 4 && path[3] != "standings" || len(path) == 0 && r.Method == http.MethodPost {
+		method = http.MethodPost
+	}
+
+	if r.Method != method {
+		writeProblem(w, http.StatusMethodNotAllowed, nil)
+		return
+	}
+
+	if !authorizeWrite(w, r) {
+		return
+	}
+
+	switch len(path) {
+	case 0:
+		if r.Method == http.MethodPost {
+			var data struct {
+				Name string `json:"name"`
+			}
+
+			if err := readJSON(r, &data); err != nil || strings.TrimSpace(data.Name) == "" {
+				writeProblem(w, http.StatusBadRequest, errors.New("tournament name is missing"))
+				return
+			}
+
+			id, err := tournaments.CreateTournament(strings.TrimSpace(data.Name))
+			if err != nil {
+				writeProblem(w, http.StatusInternalServerError, err)
+				return
+			}
+
+			t, err := getTournament(id)
+			if err != nil {
+				writeStoreProblem(w, err)
+				return
+			}
+
+			writeCreated(w, t)
+			return
+		}
+
+		records, err := tournaments.ListTournaments()
+		if err != nil {
+			writeProblem(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		list := []APITournament{}
+
+		for _, record := range records {
+			list = append(list, newAPITournament(record))
+		}
+
+		writeJSON(w, list)
+	case 1:
+		t, err := getTournament(path[0])
+		if err != nil {
+			writeStoreProblem(w, err)
+			return
+		}
+
+		writeJSON(w, t)
+	case 2:
+		var data struct {
+			Format   tournament.Format   `json:"format"`
+			Category tournament.Category `json:"category"`
+			Mode     parser.Mode         `json:"mode"`
+		}
+
+		if err := readJSON(r, &data); err != nil {
+			writeProblem(w, http.StatusBadRequest, err)
+			return
+		}
+
+		if data.Format == "" {
+			data.Format = tournament.Knockout
+		}
+
+		draw, err := tournament.NewDraw("", data.Format, data.Category, data.Mode)
+		if err != nil {
+			writeProblem(w, http.StatusBadRequest, err)
+			return
+		}
+
+		raw, _ := json.Marshal(draw)
+
+		draw.ID, err = tournaments.CreateDraw(path[0], string(raw))
+		if err != nil {
+			writeStoreProblem(w, err)
+			return
+		}
+
+		writeCreated(w, draw)
+	case 3:
+		draw, err := getTournamentDraw(path[0], path[2])
+		if err != nil {
+			writeStoreProblem(w, err)
+			return
+		}
+
+		writeJSON(w, draw)
+	case 4:
+		if path[3] == "unlink" {
+			var link DrawLink
+
+			if err := readJSON(r, &link); err != nil {
+				writeProblem(w, http.StatusBadRequest, err)
+				return
+			}
+
+			link.Draw = path[2]
+
+			draw, err := unlinkDrawMatch(path[0], link)
+			if errors.Is(err, store.ErrNotFound) {
+				writeProblem(w, http.StatusNotFound, err)
+				return
+			} else if err != nil {
+				writeProblem(w, http.StatusBadRequest, err)
+				return
+			}
+
+			writeJSON(w, draw)
+			return
+		}
+
+		drawMutex.Lock()
+		defer drawMutex.Unlock()
+
+		draw, err := getTournamentDraw(path[0], path[2])
+		if err != nil {
+			writeStoreProblem(w, err)
+			return
+		}
+
+		if path[3] == "standings" {
+			if draw.Format != tournament.Group {
+				writeProblem(w, http.StatusNotFound, errors.New("draw is not a group"))
+				return
+			}
+
+			writeJSON(w, draw.Standings(getDrawMatches(draw)))
+			return
+		}
+
+		var created any = draw
+
+		if path[3] == "entries" {
+			var data struct {
+				Team parser.Team `json:"team"`
+				Seed int         `json:"seed"`
+			}
+
+			if err := readJSON(r, &data); err != nil {
+				writeProblem(w, http.StatusBadRequest, err)
+				return
+			}
+
+			entry, err := draw.AddEntry(data.Team, data.Seed)
+			if err != nil {
+				writeProblem(w, http.StatusBadRequest, err)
+				return
+			}
+
+			created = entry
+		} else if err := draw.Generate(rand.New(rand.NewSource(time.Now().UnixNano()))); err != nil {
+			writeProblem(w, http.StatusBadRequest, err)
+			return
+		} else {
+			created = draw
+		}
+
+		if err := saveDraw(draw); err != nil {
+			writeStoreProblem(w, err)
+			return
+		}
+
+		writeCreated(w, created)
+	}
+}
+
+func handleTournament(w http.ResponseWriter, r *http.Request) {
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, PATH_TOURNAMENT), "/")
+
+	t, ok := templates["tournament.html"]
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	page, err := getTournamentPage(id)
+	if errors.Is(err, store.ErrNotFound) {
+		http.NotFound(w, r)
+		return
+	} else if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	if err := t.Execute(w, page); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"score/src/store"
+	"score/src/tournament"
+	"strings"
+	"testing"
+)
+
+func TestTournament(t *testing.T) {
+	setup(t)
+
+	token := strings.Repeat("a", TOKEN_LENGTH)
+
+	var created APITournament
+	if code := jsonRequest(t, handleTournamentsAPI, http.MethodPost, PATH_API_TOURNAMENTS, `{"name": "Club Championships"}`, &created); code != http.StatusCreated {
+		t.Fatalf("Got status %d, want %d", code, http.StatusCreated)
+	}
+
+	base := PATH_API_TOURNAMENTS + "/" + created.ID
+
+	var draw tournament.Draw
+	if code := jsonRequest(t, handleTournamentsAPI, http.MethodPost, base+"/draws", `{"category": "MS", "mode": 21}`, &draw); code != http.StatusCreated {
+		t.Fatalf("Got status %d, want %d", code, http.StatusCreated)
+	}
+
+	if code := jsonRequest(t, handleTournamentsAPI, http.MethodPost, base+"/draws", `{"category": "XX", "mode": 21}`, &draw); code != http.StatusBadRequest {
+		t.Errorf("Got status %d, want %d", code, http.StatusBadRequest)
+	}
+
+	players := []string{"Viktor AXELSEN", "CHOU Tien Chen", "Anders ANTONSEN"}
+
+	for i, player := range players {
+		var entry tournament.Entry
+		body := fmt.Sprintf(`{"team": [{"country": "DK", "player": %q}], "seed": %d}`, player, i+1)
+
+		if code := jsonRequest(t, handleTournamentsAPI, http.MethodPost, base+"/draws/"+draw.ID+"/entries", body, &entry); code != http.StatusCreated {
+			t.Fatalf("Got status %d, want %d", code, http.StatusCreated)
+		}
+	}
+
+	if code := jsonRequest(t, handleTournamentsAPI, http.MethodPost, base+"/draws/"+draw.ID+"/generate", "", &draw); code != http.StatusCreated {
+		t.Fatalf("Got status %d, want %d", code, http.StatusCreated)
+	}
+
+	// seed 1 has a bye, seed 2 plays seed 3
+	w := apiRequest(token, `{"action": "new", "draw": {"draw": "`+draw.ID+`", "round": 0, "position": 0}}`)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Got status %d, want %d", w.Code, http.StatusBadRequest)
+	}
+
+	w = apiRequest(token, `{"action": "new", "draw": {"draw": "`+draw.ID+`", "round": 0, "position": 1}}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Got status %d, want %d: %s", w.Code, http.StatusCreated, w.Body.String())
+	}
+
+	var response APIResponseData
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// a match that was started for the wrong position is unlinked and deleted
+	if code := jsonRequest(t, handleTournamentsAPI, http.MethodPost, base+"/draws/"+draw.ID+"/unlink", `{"round": 0, "position": 1}`, &draw); code != http.StatusOK || draw.Round(0)[1].Match != "" {
+		t.Fatalf("Got status %d and %+v, want unlinked position", code, draw.Round(0)[1])
+	}
+
+	if _, err := matches.Get(response.Match); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("Got %v, want unlinked match to be deleted", err)
+	}
+
+	w = apiRequest(token, `{"action": "new", "draw": {"draw": "`+draw.ID+`", "round": 0, "position": 1}}`)
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	// the teams of a linked match are given by the draw
+	w = apiRequest(token, `{"action": "update", "match": "`+response.Match+`", "data": `+apiTestMatch("LEE Zii Jia", "CHOU Tien Chen", 1679684400, false)+`}`)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Got status %d, want %d", w.Code, http.StatusBadRequest)
+	}
+
+	w = apiRequest(token, `{"action": "update", "match": "`+response.Match+`", "data": `+apiTestMatch("CHOU Tien Chen", "Anders ANTONSEN", 1679684400, true)+`}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Got status %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+
+	// started matches stay linked
+	if code := jsonRequest(t, handleTournamentsAPI, http.MethodPost, base+"/draws/"+draw.ID+"/unlink", `{"round": 0, "position": 1}`, &draw); code != http.StatusBadRequest {
+		t.Errorf("Got status %d, want %d", code, http.StatusBadRequest)
+	}
+
+	var got APITournament
+	if code := jsonRequest(t, handleTournamentsAPI, http.MethodGet, base, "", &got); code != http.StatusOK || len(got.Draws) != 1 {
+		t.Fatalf("Got status %d and %d draws, want %d and 1", code, len(got.Draws), http.StatusOK)
+	}
+
+	final := got.Draws[0].Round(1)[0]
+	if final.Entry1 != 1 || final.Entry2 != 2 {
+		t.Errorf("Got final %+v, want seed 1 against seed 2", final)
+	}
+
+	w = httptest.NewRecorder()
+	handleTournament(w, httptest.NewRequest(http.MethodGet, PATH_TOURNAMENT+created.ID, nil))
+
+	for _, want := range []string{"Club Championships", "Semi-finals", "Final", "CHOU Tien Chen", "/m/" + response.Match, "/c/?draw=" + draw.ID} {
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("Expected %q on bracket page", want)
+		}
+	}
+
+	w = httptest.NewRecorder()
+	handleClient(w, httptest.NewRequest(http.MethodGet, PATH_CLIENT+"?draw="+draw.ID+"&round=1&position=0", nil))
+
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "Viktor AXELSEN") {
+		t.Errorf("Got status %d, want client with the teams of the final", w.Code)
+	}
+}
+
+func TestTournamentGroup(t *testing.T) {
+	setup(t)
+
+	token := strings.Repeat("a", TOKEN_LENGTH)
+
+	var created APITournament
+	jsonRequest(t, handleTournamentsAPI, http.MethodPost, PATH_API_TOURNAMENTS, `{"name": "League Night"}`, &created)
+
+	base := PATH_API_TOURNAMENTS + "/" + created.ID
+
+	var draw tournament.Draw
+	if code := jsonRequest(t, handleTournamentsAPI, http.MethodPost, base+"/draws", `{"format": "group", "category": "MS", "mode": 21}`, &draw); code != http.StatusCreated {
+		t.Fatalf("Got status %d, want %d", code, http.StatusCreated)
+	}
+
+	for _, player := range []string{"Viktor AXELSEN", "CHOU Tien Chen", "Anders ANTONSEN"} {
+		var entry tournament.Entry
+		jsonRequest(t, handleTournamentsAPI, http.MethodPost, base+"/draws/"+draw.ID+"/entries", `{"team": [{"country": "DK", "player": "`+player+`"}]}`, &entry)
+	}
+
+	if code := jsonRequest(t, handleTournamentsAPI, http.MethodPost, base+"/draws/"+draw.ID+"/generate", "", &draw); code != http.StatusCreated || len(draw.Positions) != 3 {
+		t.Fatalf("Got status %d and %d fixtures, want %d and 3", code, len(draw.Positions), http.StatusCreated)
+	}
+
+	// entry 1 sits out the first round
+	w := apiRequest(token, `{"action": "new", "draw": {"draw": "`+draw.ID+`", "round": 0, "position": 0}}`)
+
+	var response APIResponseData
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	w = apiRequest(token, `{"action": "update", "match": "`+response.Match+`", "data": `+apiTestMatch("CHOU Tien Chen", "Anders ANTONSEN", 1679684400, true)+`}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Got status %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+
+	var standings []tournament.Standing
+	if code := jsonRequest(t, handleTournamentsAPI, http.MethodGet, base+"/draws/"+draw.ID+"/standings", "", &standings); code != http.StatusOK || len(standings) != 3 {
+		t.Fatalf("Got status %d and %d standings, want %d and 3", code, len(standings), http.StatusOK)
+	}
+
+	if s := standings[0]; s.Entry.ID != 2 || s.Won != 1 || s.GamesWon != 2 || s.PointsWon != 42 || s.PointsLost != 0 {
+		t.Errorf("Got %+v, want CHOU Tien Chen first", s)
+	}
+
+	w = httptest.NewRecorder()
+	handleTournament(w, httptest.NewRequest(http.MethodGet, PATH_TOURNAMENT+created.ID, nil))
+
+	for _, want := range []string{"MS · Group", "Round 3", "42:0"} {
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("Expected %q on tournament page", want)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"score/src/auth"
+	"score/src/store"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+const USER_USAGE = "usage: score user [list|add NAME ROLE|passwd NAME]"
+
+// Runs the user command on the database at the given path. Passwords are
+// read from r without echoing them if it is a terminal, or from its first
+// line otherwise, so that they do not end up in the shell history:
+//
+//	list               prints all users and their roles
+//	add NAME ROLE      creates a user with the given role
+//	passwd NAME        sets a new password for a user
+func runUser(r io.Reader, w io.Writer, path string, args []string) error {
+	command := "list"
+	if len(args) > 0 {
+		command = args[0]
+	}
+
+	if command == "list" && len(args) > 1 || command == "add" && len(args) != 3 || command == "passwd" && len(args) != 2 {
+		return errors.New(USER_USAGE)
+	}
+
+	s, err := store.NewSQLite(path)
+	if err != nil {
+		return err
+	}
+
+	defer s.Close()
+
+	users = s
+
+	readPassword := func() (string, error) {
+		fmt.Fprint(w, "password: ")
+
+		// not echoed if typed, piped passwords are read as a line
+		if f, ok := r.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+			password, err := term.ReadPassword(int(f.Fd()))
+			fmt.Fprintln(w)
+
+			return string(password), err
+		}
+
+		line, err := bufio.NewReader(r).ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return "", err
+		}
+
+		fmt.Fprintln(w)
+
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+
+	switch command {
+	case "list":
+		list, err := users.ListUsers()
+		if err != nil {
+			return err
+		}
+
+		for _, user := range list {
+			fmt.Fprintf(w, "%s\t%s\n", user.Username, user.Role)
+		}
+
+	case "add":
+		password, err := readPassword()
+		if err != nil {
+			return err
+		}
+
+		user, err := createUser(args[1], password, auth.Role(args[2]))
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(w, "created %s with role %s\n", user.Username, user.Role)
+
+	case "passwd":
+		password, err := readPassword()
+		if err != nil {
+			return err
+		}
+
+		if password == "" {
+			return errors.New(auth.ERR_INVALID_PASSWORD)
+		}
+
+		user, err := updateUser(args[1], password, "")
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(w, "changed password of %s\n", user.Username)
+
+	default:
+		return errors.New(USER_USAGE)
+	}
+
+	return nil
+}